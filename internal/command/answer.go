@@ -0,0 +1,24 @@
+package command
+
+import (
+	"fmt"
+
+	"QA-System/internal/service"
+)
+
+func init() {
+	Register(Command{
+		Name: "answers-migrate-time",
+		Desc: "根据答卷时间字符串为旧答卷补充可按时间范围查询的答卷时间",
+		Run:  migrateAnswerTimes,
+	})
+}
+
+func migrateAnswerTimes(_ []string) error {
+	migrated, err := service.MigrateAnswerTimes()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已迁移%d份答卷\n", migrated)
+	return nil
+}
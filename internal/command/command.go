@@ -0,0 +1,54 @@
+// Package command 实现运维使用的命令行子命令
+//
+// 以 `QA-System <子命令> [参数]` 运行时，初始化数据库后执行子命令并退出，不启动服务。
+package command
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Command 命令行子命令
+type Command struct {
+	Name  string
+	Usage string // 参数说明
+	Desc  string // 功能说明
+	Run   func(args []string) error
+}
+
+var commands = make(map[string]Command)
+
+// Register 注册子命令
+func Register(cmd Command) {
+	commands[cmd.Name] = cmd
+}
+
+// Run 执行参数中的子命令，没有子命令时返回 false
+func Run(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.Run(args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return true
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "可用的子命令:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s %s\n\t%s\n", name, commands[name].Usage, commands[name].Desc)
+	}
+}
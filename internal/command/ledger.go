@@ -0,0 +1,50 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"QA-System/internal/service"
+)
+
+func init() {
+	Register(Command{
+		Name:  "ledger-verify",
+		Usage: "<问卷ID>",
+		Desc:  "重新计算问卷答卷的哈希链，与答卷和签名检查点比对",
+		Run:   verifyLedger,
+	})
+	Register(Command{
+		Name: "ledger-checkpoint",
+		Desc: "立即为有新条目的哈希链生成签名检查点",
+		Run: func([]string) error {
+			return service.CreateCheckpoints()
+		},
+	})
+}
+
+func verifyLedger(args []string) error {
+	if len(args) != 1 {
+		return errors.New("用法: ledger-verify <问卷ID>")
+	}
+	sid, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("问卷ID不合法: %w", err)
+	}
+	report, err := service.VerifyLedger(sid)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if !report.Valid {
+		return fmt.Errorf("问卷%d的哈希链校验未通过，发现%d处问题", sid, len(report.Divergences))
+	}
+	return nil
+}
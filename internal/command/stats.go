@@ -0,0 +1,74 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"QA-System/internal/service"
+)
+
+func init() {
+	Register(Command{
+		Name:  "stats-verify",
+		Usage: "<问卷ID>",
+		Desc:  "比对问卷的内存统计和 MongoDB 聚合统计结果",
+		Run:   verifyStats,
+	})
+	Register(Command{
+		Name:  "stats-rebuild",
+		Usage: "[问卷ID...]",
+		Desc:  "从答卷重建投票统计计数器并输出与答卷不一致的项，不指定问卷时重建所有已有计数器的问卷",
+		Run:   rebuildStatsCounters,
+	})
+}
+
+func verifyStats(args []string) error {
+	if len(args) != 1 {
+		return errors.New("用法: stats-verify <问卷ID>")
+	}
+	sid, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("问卷ID不合法: %w", err)
+	}
+	report, err := service.VerifyStats(sid)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if !report.Valid {
+		return fmt.Errorf("问卷%d的统计结果不一致", sid)
+	}
+	return nil
+}
+
+func rebuildStatsCounters(args []string) error {
+	reports := make([]service.CounterReport, 0, len(args))
+	if len(args) == 0 {
+		var err error
+		reports, err = service.RebuildAllStatsCounters()
+		if err != nil {
+			return err
+		}
+	}
+	for _, arg := range args {
+		sid, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("问卷ID不合法: %w", err)
+		}
+		report, err := service.RebuildStatsCounters(sid)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, *report)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(reports)
+}
@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"errors"
+	"time"
 
 	database "QA-System/internal/pkg/database/mongodb"
 
@@ -15,10 +16,14 @@ import (
 
 // Answer 各问题答卷模型
 type Answer struct {
-	QuestionID int    `json:"question_id" bson:"questionid"` // 问题ID
-	SerialNum  int    `json:"serial_num" bson:"serialnum"`   // 问题序号
-	Subject    string `json:"subject" bson:"subject"`        // 问题标题
-	Content    string `json:"content" bson:"content"`        // 答案内容
+	QuestionID  int    `json:"question_id" bson:"questionid"`                       // 问题ID
+	SerialNum   int    `json:"serial_num" bson:"serialnum"`                         // 问题序号
+	Subject     string `json:"subject" bson:"subject"`                              // 问题标题
+	Content     string `json:"content" bson:"content"`                              // 答案内容
+	Position    int    `json:"position,omitempty" bson:"position,omitempty"`        // 答题者看到的题目位置
+	OptionOrder []int  `json:"option_order,omitempty" bson:"optionorder,omitempty"` // 答题者看到的选项序号顺序
+
+	OptionSerials []int `json:"option_serials,omitempty" bson:"optionserials,omitempty"` // 所选选项的序号，与语言无关
 }
 
 // AnswerSheet mongodb答卷表模型
@@ -28,6 +33,14 @@ type AnswerSheet struct {
 	Time     string             `json:"time" bson:"time"`          // 答卷时间
 	Unique   bool               `json:"unique" bson:"unique"`      // 是否唯一
 	Answers  []Answer           `json:"answers" bson:"answers"`    // 答案列表
+
+	HiddenFields map[string]string `json:"hidden_fields,omitempty" bson:"hiddenfields,omitempty"` // 隐藏字段
+	Lang         string            `json:"lang,omitempty" bson:"lang,omitempty"`                  // 答题者使用的语言
+
+	Receipt    string `json:"-" bson:"receipt,omitempty"`    // 匿名投票回执码的摘要
+	StudentKey string `json:"-" bson:"studentkey,omitempty"` // 统一验证学号的 HMAC 用于关联统一验证记录 匿名投票不记录
+
+	SubmittedAt time.Time `json:"-" bson:"submittedat,omitempty"` // 与 Time 相同的答卷时间，用于按时间范围查询
 }
 
 // QuestionAnswers 问题答案模型
@@ -42,10 +55,11 @@ type AnswersResonse struct {
 	QuestionAnswers []QuestionAnswers    `json:"question_answers"`
 	AnswerIDs       []primitive.ObjectID `json:"answer_ids"`
 	Time            []string             `json:"time"`
+	HiddenFields    []map[string]string  `json:"hidden_fields"` // 每份答卷的隐藏字段
 }
 
-// SaveAnswerSheet 将答卷直接保存到 MongoDB 集合中
-func (d *Dao) SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids []int) error {
+// SaveAnswerSheet 将答卷直接保存到 MongoDB 集合中，返回因唯一问题重复而不再有效的答卷
+func (d *Dao) SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids []int) (*AnswerSheet, error) {
 	// 构建查询条件
 	matchConditions := make([]bson.M, 0) // 初始化为空切片
 	for _, answer := range answerSheet.Answers {
@@ -64,10 +78,7 @@ func (d *Dao) SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids
 	if len(matchConditions) == 0 {
 		// 没有符合条件的记录，直接插入新记录
 		_, err := d.mongo.Collection(database.QA).InsertOne(ctx, answerSheet)
-		if err != nil {
-			return err
-		}
-		return nil
+		return nil, err
 	}
 
 	filter := bson.M{
@@ -81,12 +92,9 @@ func (d *Dao) SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			// 没有找到符合条件的记录，直接插入新记录
 			_, err := d.mongo.Collection(database.QA).InsertOne(ctx, answerSheet)
-			if err != nil {
-				return err
-			}
-			return nil
+			return nil, err
 		}
-		return err
+		return nil, err
 	}
 
 	// 更新找到的记录，将unique设为false
@@ -95,23 +103,19 @@ func (d *Dao) SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids
 	}
 	_, err = d.mongo.Collection(database.QA).UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// 新增一条记录
-	newAnswerSheet := AnswerSheet{
-		SurveyID: answerSheet.SurveyID,
-		Time:     answerSheet.Time,
-		Unique:   true,
-		Answers:  answerSheet.Answers,
-	}
+	newAnswerSheet := answerSheet
+	newAnswerSheet.Unique = true
 
 	_, err = d.mongo.Collection(database.QA).InsertOne(ctx, newAnswerSheet)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &result, nil
 }
 
 func contains(arr []int, item int) bool {
@@ -125,11 +129,16 @@ func contains(arr []int, item int) bool {
 
 // GetAnswerSheetBySurveyID 根据问卷ID分页获取答卷
 func (d *Dao) GetAnswerSheetBySurveyID(
-	ctx context.Context, surveyID int64, pageNum int, pageSize int, text string, unique bool) (
-	[]AnswerSheet, *int64, error) {
+	ctx context.Context, surveyID int64, pageNum int, pageSize int, text string, unique bool,
+	hidden map[string]string) ([]AnswerSheet, *int64, error) {
 	answerSheets := make([]AnswerSheet, 0)
 	filter := bson.M{"surveyid": surveyID}
 
+	// 按隐藏字段筛选，字段名需由调用方校验
+	for name, value := range hidden {
+		filter["hiddenfields."+name] = value
+	}
+
 	// 如果 text 不为空，添加 text 的查询条件
 	if text != "" {
 		filter["answers.content"] = bson.M{"$regex": text, "$options": "i"} // i 表示不区分大小写
@@ -207,9 +216,22 @@ func (d *Dao) DeleteAnswerSheetByAnswerID(ctx context.Context, answerID primitiv
 }
 
 // GetAnswerSheetByAnswerID 根据答卷ID获取答卷
-func (d *Dao) GetAnswerSheetByAnswerID(ctx context.Context, answerID primitive.ObjectID) error {
+func (d *Dao) GetAnswerSheetByAnswerID(ctx context.Context, answerID primitive.ObjectID) (*AnswerSheet, error) {
 	var answerSheet AnswerSheet
 	filter := bson.M{"_id": answerID}
 	err := d.mongo.Collection(database.QA).FindOne(ctx, filter).Decode(&answerSheet)
-	return err
+	return &answerSheet, err
+}
+
+// GetAnswerSheetsByIDs 根据答卷ID批量获取问卷的答卷，已删除的答卷不返回
+func (d *Dao) GetAnswerSheetsByIDs(ctx context.Context, surveyID int64,
+	answerIDs []primitive.ObjectID) ([]AnswerSheet, error) {
+	answerSheets := make([]AnswerSheet, 0)
+	filter := bson.M{"surveyid": surveyID, "_id": bson.M{"$in": answerIDs}}
+	cursor, err := d.mongo.Collection(database.QA).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &answerSheets)
+	return answerSheets, err
 }
@@ -0,0 +1,119 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	database "QA-System/internal/pkg/database/mongodb"
+	"QA-System/internal/pkg/redis"
+
+	redisPkg "github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const bufferedSurveysKey = "ballots:surveys" // 有待写入选票的问卷集合
+
+func ballotBufferKey(surveyID int64) string {
+	return fmt.Sprintf("ballots:buffer:sid:%d", surveyID)
+}
+
+func pendingReceiptsKey(surveyID int64) string {
+	return fmt.Sprintf("ballots:receipts:sid:%d", surveyID)
+}
+
+// BufferBallot 将匿名选票暂存到 Redis，等待成批写入
+func (d *Dao) BufferBallot(ctx context.Context, answerSheet AnswerSheet) error {
+	data, err := json.Marshal(answerSheet)
+	if err != nil {
+		return err
+	}
+	_, err = redis.RedisClient.TxPipelined(ctx, func(pipe redisPkg.Pipeliner) error {
+		pipe.RPush(ctx, ballotBufferKey(answerSheet.SurveyID), data)
+		pipe.SAdd(ctx, pendingReceiptsKey(answerSheet.SurveyID), answerSheet.Receipt)
+		pipe.SAdd(ctx, bufferedSurveysKey, answerSheet.SurveyID)
+		return nil
+	})
+	return err
+}
+
+// TakeBufferedBallots 取出问卷全部暂存的选票
+func (d *Dao) TakeBufferedBallots(ctx context.Context, surveyID int64) ([]AnswerSheet, error) {
+	key := ballotBufferKey(surveyID)
+	var items *redisPkg.StringSliceCmd
+	_, err := redis.RedisClient.TxPipelined(ctx, func(pipe redisPkg.Pipeliner) error {
+		items = pipe.LRange(ctx, key, 0, -1)
+		pipe.Del(ctx, key)
+		pipe.SRem(ctx, bufferedSurveysKey, surveyID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sheets := make([]AnswerSheet, 0, len(items.Val()))
+	for _, item := range items.Val() {
+		var sheet AnswerSheet
+		if err := json.Unmarshal([]byte(item), &sheet); err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets, nil
+}
+
+// CountBufferedBallots 获取问卷暂存的选票数量
+func (d *Dao) CountBufferedBallots(ctx context.Context, surveyID int64) (int64, error) {
+	return redis.RedisClient.LLen(ctx, ballotBufferKey(surveyID)).Result()
+}
+
+// GetBufferedSurveys 获取有暂存选票的问卷
+func (d *Dao) GetBufferedSurveys(ctx context.Context) ([]int64, error) {
+	members, err := redis.RedisClient.SMembers(ctx, bufferedSurveysKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// RemovePendingReceipts 移除已写入选票的回执
+func (d *Dao) RemovePendingReceipts(ctx context.Context, surveyID int64, receipts []string) error {
+	if len(receipts) == 0 {
+		return nil
+	}
+	members := make([]any, 0, len(receipts))
+	for _, receipt := range receipts {
+		members = append(members, receipt)
+	}
+	return redis.RedisClient.SRem(ctx, pendingReceiptsKey(surveyID), members...).Err()
+}
+
+// IsReceiptPending 判断回执对应的选票是否仍在等待写入
+func (d *Dao) IsReceiptPending(ctx context.Context, surveyID int64, receipt string) (bool, error) {
+	return redis.RedisClient.SIsMember(ctx, pendingReceiptsKey(surveyID), receipt).Result()
+}
+
+// HasReceipt 判断回执对应的选票是否已计入答卷
+func (d *Dao) HasReceipt(ctx context.Context, surveyID int64, receipt string) (bool, error) {
+	count, err := d.mongo.Collection(database.QA).CountDocuments(ctx,
+		bson.M{"surveyid": surveyID, "receipt": receipt})
+	return count > 0, err
+}
+
+// DeleteBufferedBallots 删除问卷暂存的选票和回执
+func (d *Dao) DeleteBufferedBallots(ctx context.Context, surveyID int64) error {
+	_, err := redis.RedisClient.TxPipelined(ctx, func(pipe redisPkg.Pipeliner) error {
+		pipe.Del(ctx, ballotBufferKey(surveyID), pendingReceiptsKey(surveyID))
+		pipe.SRem(ctx, bufferedSurveysKey, surveyID)
+		return nil
+	})
+	return err
+}
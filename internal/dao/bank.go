@@ -0,0 +1,152 @@
+package dao
+
+import (
+	"context"
+
+	"QA-System/internal/model"
+)
+
+// BankQuestionFilter 题库查询条件
+type BankQuestionFilter struct {
+	UserID       int    // 当前管理员ID
+	Scope        string // 查询范围 all:全部可见 mine:我的 shared:共享
+	Keyword      string // 题目关键字
+	Tag          string // 标签
+	QuestionType int    // 题目类型 0为不限制
+}
+
+// CreateBankQuestion 创建题库题目
+func (d *Dao) CreateBankQuestion(ctx context.Context, question model.BankQuestion) (model.BankQuestion, error) {
+	err := d.orm.WithContext(ctx).Create(&question).Error
+	return question, err
+}
+
+// UpdateBankQuestion 更新题库题目
+func (d *Dao) UpdateBankQuestion(ctx context.Context, question model.BankQuestion) error {
+	err := d.orm.WithContext(ctx).Model(&model.BankQuestion{}).Where("id = ?", question.ID).
+		Select("shared", "img", "subject", "description", "required", "unique", "other_option",
+			"question_type", "maximum_option", "minimum_option", "reg").
+		Updates(question).Error
+	return err
+}
+
+// GetBankQuestionByID 根据ID获取题库题目
+func (d *Dao) GetBankQuestionByID(ctx context.Context, id int) (*model.BankQuestion, error) {
+	var question model.BankQuestion
+	err := d.orm.WithContext(ctx).Where("id = ?", id).First(&question).Error
+	return &question, err
+}
+
+// GetBankQuestions 分页查询题库题目
+func (d *Dao) GetBankQuestions(ctx context.Context, filter BankQuestionFilter, pageNum, pageSize int) (
+	[]model.BankQuestion, int64, error) {
+	var questions []model.BankQuestion
+	var total int64
+	query := d.orm.WithContext(ctx).Model(&model.BankQuestion{})
+	switch filter.Scope {
+	case "mine":
+		query = query.Where("user_id = ?", filter.UserID)
+	case "shared":
+		query = query.Where("shared = ?", true)
+	default:
+		query = query.Where("shared = ? OR user_id = ?", true, filter.UserID)
+	}
+	if filter.Keyword != "" {
+		query = query.Where("subject LIKE ?", "%"+filter.Keyword+"%")
+	}
+	if filter.QuestionType != 0 {
+		query = query.Where("question_type = ?", filter.QuestionType)
+	}
+	if filter.Tag != "" {
+		sub := d.orm.Model(&model.BankTag{}).Select("bank_question_id").Where("name = ?", filter.Tag)
+		query = query.Where("id IN (?)", sub)
+	}
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("updated_at DESC").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&questions).Error
+	return questions, total, err
+}
+
+// DeleteBankQuestion 删除题库题目
+func (d *Dao) DeleteBankQuestion(ctx context.Context, id int) error {
+	err := d.orm.WithContext(ctx).Where("id = ?", id).Delete(&model.BankQuestion{}).Error
+	return err
+}
+
+// CreateBankOption 创建题库选项
+func (d *Dao) CreateBankOption(ctx context.Context, option model.BankOption) error {
+	err := d.orm.WithContext(ctx).Create(&option).Error
+	return err
+}
+
+// GetBankOptionsByQuestionID 根据题库题目ID获取选项
+func (d *Dao) GetBankOptionsByQuestionID(ctx context.Context, id int) ([]model.BankOption, error) {
+	var options []model.BankOption
+	err := d.orm.WithContext(ctx).Where("bank_question_id = ?", id).Order("serial_num").Find(&options).Error
+	return options, err
+}
+
+// DeleteBankOptions 删除题库题目的所有选项
+func (d *Dao) DeleteBankOptions(ctx context.Context, id int) error {
+	err := d.orm.WithContext(ctx).Where("bank_question_id = ?", id).Delete(&model.BankOption{}).Error
+	return err
+}
+
+// SetBankTags 覆盖设置题库题目的标签
+func (d *Dao) SetBankTags(ctx context.Context, id int, tags []string) error {
+	err := d.orm.WithContext(ctx).Where("bank_question_id = ?", id).Delete(&model.BankTag{}).Error
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		err = d.orm.WithContext(ctx).Create(&model.BankTag{BankQuestionID: id, Name: tag}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBankTagsByQuestionID 根据题库题目ID获取标签
+func (d *Dao) GetBankTagsByQuestionID(ctx context.Context, id int) ([]string, error) {
+	tags := make([]string, 0)
+	err := d.orm.WithContext(ctx).Model(&model.BankTag{}).Where("bank_question_id = ?", id).
+		Pluck("name", &tags).Error
+	return tags, err
+}
+
+// GetQuestionsByBankQuestionID 获取引用了题库题目的问卷问题
+func (d *Dao) GetQuestionsByBankQuestionID(ctx context.Context, id int) ([]model.Question, error) {
+	var questions []model.Question
+	err := d.orm.WithContext(ctx).Where("bank_question_id = ?", id).Find(&questions).Error
+	return questions, err
+}
+
+// UpdateQuestionFromBank 将题库题目内容同步到引用它的问卷问题
+func (d *Dao) UpdateQuestionFromBank(ctx context.Context, questionID int, bank model.BankQuestion) error {
+	err := d.orm.WithContext(ctx).Model(&model.Question{}).Where("id = ?", questionID).
+		Select("img", "subject", "description", "required", "unique", "other_option",
+			"question_type", "maximum_option", "minimum_option", "reg").
+		Updates(model.Question{
+			Img:           bank.Img,
+			Subject:       bank.Subject,
+			Description:   bank.Description,
+			Required:      bank.Required,
+			Unique:        bank.Unique,
+			OtherOption:   bank.OtherOption,
+			QuestionType:  bank.QuestionType,
+			MaximumOption: bank.MaximumOption,
+			MinimumOption: bank.MinimumOption,
+			Reg:           bank.Reg,
+		}).Error
+	return err
+}
+
+// UnlinkBankQuestion 解除问卷问题对题库题目的引用
+func (d *Dao) UnlinkBankQuestion(ctx context.Context, id int) error {
+	err := d.orm.WithContext(ctx).Model(&model.Question{}).Where("bank_question_id = ?", id).
+		Update("bank_question_id", 0).Error
+	return err
+}
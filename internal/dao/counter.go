@@ -0,0 +1,161 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"QA-System/internal/pkg/redis"
+
+	redisPkg "github.com/redis/go-redis/v9"
+)
+
+const counterSurveysKey = "stats:surveys" // 有统计计数器的问卷集合
+
+// 计数器哈希的字段：答卷数、问题的答案数、问题各答案的次数
+const (
+	counterTotalField     = "total"
+	counterPresencePrefix = "p:"
+	counterCountPrefix    = "c:"
+)
+
+// increaseCountersScript 计数器存在时才累加，避免提交时建出不完整的计数器
+var increaseCountersScript = redisPkg.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+for i = 1, #ARGV, 2 do
+	redis.call('HINCRBY', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+return 1
+`)
+
+func statsCounterKey(surveyID int64) string {
+	return fmt.Sprintf("stats:sid:%d", surveyID)
+}
+
+// GetStatsCounters 获取问卷的统计计数器，计数器不存在时返回 nil
+//
+// 计数器不分组，结果中次数为 0 的问题和答案会被省略。
+func (d *Dao) GetStatsCounters(ctx context.Context, surveyID int64) (*AnswerStats, error) {
+	fields, err := redis.RedisClient.HGetAll(ctx, statsCounterKey(surveyID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	stats := &AnswerStats{
+		Groups:   make([]StatsGroup, 0, 1),
+		Presence: make([]StatsPresence, 0),
+		Counts:   make([]StatsCount, 0),
+	}
+	for field, value := range fields {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			continue
+		}
+		switch {
+		case field == counterTotalField:
+			stats.Groups = append(stats.Groups, StatsGroup{Total: n})
+		case strings.HasPrefix(field, counterPresencePrefix):
+			qid, err := strconv.Atoi(strings.TrimPrefix(field, counterPresencePrefix))
+			if err != nil {
+				return nil, err
+			}
+			stats.Presence = append(stats.Presence, StatsPresence{QuestionID: qid, Count: n})
+		case strings.HasPrefix(field, counterCountPrefix):
+			qid, content, ok := strings.Cut(strings.TrimPrefix(field, counterCountPrefix), ":")
+			if !ok {
+				return nil, errors.New("计数器字段" + field + "格式错误")
+			}
+			id, err := strconv.Atoi(qid)
+			if err != nil {
+				return nil, err
+			}
+			stats.Counts = append(stats.Counts, StatsCount{QuestionID: id, Content: content, Count: n})
+		}
+	}
+	return stats, nil
+}
+
+// SetStatsCounters 用统计结果覆盖问卷的计数器，统计结果的分组会被合并
+func (d *Dao) SetStatsCounters(ctx context.Context, surveyID int64, stats *AnswerStats) error {
+	key := statsCounterKey(surveyID)
+	fields := statsCounterFields(stats)
+	// 没有答卷时也保留 total 字段，使计数器存在
+	if _, ok := fields[counterTotalField]; !ok {
+		fields[counterTotalField] = 0
+	}
+	_, err := redis.RedisClient.TxPipelined(ctx, func(pipe redisPkg.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, fields)
+		pipe.SAdd(ctx, counterSurveysKey, surveyID)
+		return nil
+	})
+	return err
+}
+
+// IncreaseStatsCounters 将答卷的统计结果累加到问卷的计数器，次数为负时扣减，计数器不存在时忽略
+func (d *Dao) IncreaseStatsCounters(ctx context.Context, surveyID int64, delta *AnswerStats) error {
+	fields := statsCounterFields(delta)
+	args := make([]any, 0, len(fields)*2)
+	for field, n := range fields {
+		args = append(args, field, n)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return increaseCountersScript.Run(ctx, redis.RedisClient, []string{statsCounterKey(surveyID)}, args...).Err()
+}
+
+// DeleteStatsCounters 删除问卷的统计计数器
+func (d *Dao) DeleteStatsCounters(ctx context.Context, surveyID int64) error {
+	_, err := redis.RedisClient.TxPipelined(ctx, func(pipe redisPkg.Pipeliner) error {
+		pipe.Del(ctx, statsCounterKey(surveyID))
+		pipe.SRem(ctx, counterSurveysKey, surveyID)
+		return nil
+	})
+	return err
+}
+
+// GetCounterSurveys 获取有统计计数器的问卷
+func (d *Dao) GetCounterSurveys(ctx context.Context) ([]int64, error) {
+	members, err := redis.RedisClient.SMembers(ctx, counterSurveysKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// statsCounterFields 将统计结果转换为计数器字段，忽略分组
+func statsCounterFields(stats *AnswerStats) map[string]any {
+	totals := make(map[string]int)
+	for _, group := range stats.Groups {
+		totals[counterTotalField] += group.Total
+	}
+	for _, presence := range stats.Presence {
+		totals[counterPresencePrefix+strconv.Itoa(presence.QuestionID)] += presence.Count
+	}
+	for _, count := range stats.Counts {
+		totals[counterCountPrefix+strconv.Itoa(count.QuestionID)+":"+count.Content] += count.Count
+	}
+	fields := make(map[string]any, len(totals))
+	for field, n := range totals {
+		fields[field] = n
+	}
+	return fields
+}
@@ -30,13 +30,49 @@ func New(orm *gorm.DB, mongodb *mongo.Database) Daos {
 
 // Daos 数据访问对象接口
 type Daos interface {
-	SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids []int) error
+	SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids []int) (*AnswerSheet, error)
 	GetAnswerSheetBySurveyID(
-		ctx context.Context, surveyID int64, pageNum int, pageSize int, text string, unique bool) (
-		[]AnswerSheet, *int64, error)
+		ctx context.Context, surveyID int64, pageNum int, pageSize int, text string, unique bool,
+		hidden map[string]string) ([]AnswerSheet, *int64, error)
 	DeleteAnswerSheetBySurveyID(ctx context.Context, surveyID int64) error
 	DeleteAnswerSheetByAnswerID(ctx context.Context, answerID primitive.ObjectID) error
-	GetAnswerSheetByAnswerID(ctx context.Context, answerID primitive.ObjectID) error
+	GetAnswerSheetByAnswerID(ctx context.Context, answerID primitive.ObjectID) (*AnswerSheet, error)
+	GetAnswerSheetsByIDs(ctx context.Context, surveyID int64, answerIDs []primitive.ObjectID) ([]AnswerSheet, error)
+	AggregateAnswerStats(ctx context.Context, surveyID int64, hidden map[string]string,
+		dimension string, questionIDs []int) (*AnswerStats, error)
+	GetAnswerContents(ctx context.Context, surveyID int64, hidden map[string]string,
+		questionIDs []int) ([]AnswerContent, error)
+	SaveWeighting(ctx context.Context, weighting model.Weighting) error
+	GetWeighting(ctx context.Context, surveyID int64) (*model.Weighting, error)
+	DeleteWeighting(ctx context.Context, surveyID int64) error
+
+	CreateStudy(ctx context.Context, study model.Study, waves []model.StudyWave) (model.Study, error)
+	UpdateStudy(ctx context.Context, study model.Study, waves []model.StudyWave) error
+	GetStudyByID(ctx context.Context, id int) (*model.Study, error)
+	GetStudies(ctx context.Context, userID int, pageNum, pageSize int) ([]model.Study, int64, error)
+	GetStudyWaves(ctx context.Context, studyID int) ([]model.StudyWave, error)
+	DeleteStudy(ctx context.Context, id int) error
+	DeleteStudyWavesBySurveyID(ctx context.Context, surveyID int64) error
+
+	CreateVisit(ctx context.Context, visit Visit) error
+	StartVisit(ctx context.Context, surveyID int64, visitID string, t time.Time) error
+	ReachVisitSection(ctx context.Context, surveyID int64, visitID string, section int, t time.Time) error
+	SubmitVisit(ctx context.Context, surveyID int64, visitID string, t time.Time) error
+	GetVisitsBySurveyID(ctx context.Context, surveyID int64) ([]Visit, error)
+	DeleteVisitsBySurveyID(ctx context.Context, surveyID int64) error
+	AggregateSubmissionTimeline(ctx context.Context, surveyID int64, unit, timezone string,
+		start, end time.Time) ([]TimeBucket, error)
+	AggregateSubmissionHeatmap(ctx context.Context, surveyID int64, timezone string,
+		start, end time.Time) ([]HeatmapCell, error)
+	CountUntimedAnswerSheets(ctx context.Context, surveyID int64) (int64, error)
+	MigrateSubmittedAt(ctx context.Context, timezone string) (int64, error)
+	GetStatsCounters(ctx context.Context, surveyID int64) (*AnswerStats, error)
+	SetStatsCounters(ctx context.Context, surveyID int64, stats *AnswerStats) error
+	IncreaseStatsCounters(ctx context.Context, surveyID int64, delta *AnswerStats) error
+	DeleteStatsCounters(ctx context.Context, surveyID int64) error
+	GetCounterSurveys(ctx context.Context) ([]int64, error)
+	PublishStatsUpdate(ctx context.Context, surveyID int64) error
+	SubscribeStatsUpdates(ctx context.Context) <-chan int64
 
 	CreateManage(ctx context.Context, id int, surveyID int64) error
 	DeleteManage(ctx context.Context, id int, surveyID int64) error
@@ -59,14 +95,40 @@ type Daos interface {
 	CreateType(ctx context.Context, name string, value string) error
 	GetType(ctx context.Context, name string) (string, error)
 
+	BufferBallot(ctx context.Context, answerSheet AnswerSheet) error
+	TakeBufferedBallots(ctx context.Context, surveyID int64) ([]AnswerSheet, error)
+	CountBufferedBallots(ctx context.Context, surveyID int64) (int64, error)
+	GetBufferedSurveys(ctx context.Context) ([]int64, error)
+	RemovePendingReceipts(ctx context.Context, surveyID int64, receipts []string) error
+	IsReceiptPending(ctx context.Context, surveyID int64, receipt string) (bool, error)
+	HasReceipt(ctx context.Context, surveyID int64, receipt string) (bool, error)
+	DeleteBufferedBallots(ctx context.Context, surveyID int64) error
+
+	CreateDraw(ctx context.Context, draw model.Draw) (model.Draw, error)
+	GetDrawByID(ctx context.Context, id int) (*model.Draw, error)
+	GetDrawsBySurveyID(ctx context.Context, surveyID int64) ([]model.Draw, error)
+	FinishDraw(ctx context.Context, draw model.Draw, winners []model.DrawWinner) error
+	GetDrawWinners(ctx context.Context, drawID int) ([]model.DrawWinner, error)
+	DeleteDraw(ctx context.Context, id int) error
+	DeleteDrawsBySurveyID(ctx context.Context, surveyID int64) error
+
+	GetLastLedgerEntry(ctx context.Context, surveyID int64) (*LedgerEntry, error)
+	InsertLedgerEntry(ctx context.Context, entry LedgerEntry) error
+	GetLedgerEntries(ctx context.Context, surveyID int64) ([]LedgerEntry, error)
+	GetLedgerEntriesAfter(ctx context.Context, surveyID int64, seq int64, limit int64) ([]LedgerEntry, error)
+	GetLedgerSurveys(ctx context.Context) ([]int64, error)
+	SaveLedgerCheckpoint(ctx context.Context, checkpoint LedgerCheckpoint) error
+	GetLedgerCheckpoints(ctx context.Context, surveyID int64) ([]LedgerCheckpoint, error)
+	GetLastLedgerCheckpoint(ctx context.Context, surveyID int64) (*LedgerCheckpoint, error)
+
 	SaveRecordSheet(ctx context.Context, answerSheet RecordSheet, sid int64) error
+	HasRecordSheet(ctx context.Context, sid int64, studentID string) (bool, error)
+	GetRecordSheets(ctx context.Context, sid int64) ([]RecordEntry, error)
 	DeleteRecordSheets(ctx context.Context, surveyID int64) error
 
 	CreateSurvey(ctx context.Context, survey model.Survey) (model.Survey, error)
 	UpdateSurveyStatus(ctx context.Context, surveyID int64, status int) error
-	UpdateSurvey(ctx context.Context, id int64, surveyType, limit uint,
-		sumLimit uint, verify bool, undergradOnly bool, desc string, title string, deadline, startTime time.Time,
-		needNotify bool) error
+	UpdateSurvey(ctx context.Context, survey model.Survey) error
 	GetSurveyByUserID(ctx context.Context, userId int) ([]model.Survey, error)
 	GetSurveyByID(ctx context.Context, surveyID int64) (*model.Survey, error)
 	GetAllSurvey(ctx context.Context) ([]model.Survey, error)
@@ -79,4 +141,42 @@ type Daos interface {
 	UpdateUserPassword(ctx context.Context, uid int, password string) error
 	UpdateUserEmail(ctx context.Context, uid int, email string) error
 	GetUserEmailByID(ctx context.Context, uid int) (string, error)
+
+	CreateBankQuestion(ctx context.Context, question model.BankQuestion) (model.BankQuestion, error)
+	UpdateBankQuestion(ctx context.Context, question model.BankQuestion) error
+	GetBankQuestionByID(ctx context.Context, id int) (*model.BankQuestion, error)
+	GetBankQuestions(ctx context.Context, filter BankQuestionFilter, pageNum, pageSize int) (
+		[]model.BankQuestion, int64, error)
+	DeleteBankQuestion(ctx context.Context, id int) error
+	CreateBankOption(ctx context.Context, option model.BankOption) error
+	GetBankOptionsByQuestionID(ctx context.Context, id int) ([]model.BankOption, error)
+	DeleteBankOptions(ctx context.Context, id int) error
+	SetBankTags(ctx context.Context, id int, tags []string) error
+	GetBankTagsByQuestionID(ctx context.Context, id int) ([]string, error)
+	GetQuestionsByBankQuestionID(ctx context.Context, id int) ([]model.Question, error)
+	UpdateQuestionFromBank(ctx context.Context, questionID int, bank model.BankQuestion) error
+	UnlinkBankQuestion(ctx context.Context, id int) error
+
+	CreateOptionSet(ctx context.Context, set model.OptionSet) (model.OptionSet, error)
+	UpdateOptionSet(ctx context.Context, set model.OptionSet) error
+	GetOptionSetByID(ctx context.Context, id int) (*model.OptionSet, error)
+	GetOptionSets(ctx context.Context, keyword string, pageNum, pageSize int) ([]model.OptionSet, int64, error)
+	DeleteOptionSet(ctx context.Context, id int) error
+	CreateOptionSetItem(ctx context.Context, item model.OptionSetItem) (model.OptionSetItem, error)
+	GetOptionSetItems(ctx context.Context, id int, version int) ([]model.OptionSetItem, error)
+	CountQuestionsByOptionSetID(ctx context.Context, id int) (int64, error)
+
+	CreateTemplate(ctx context.Context, template model.Template) (model.Template, error)
+	GetTemplateByID(ctx context.Context, id int) (*model.Template, error)
+	GetTemplates(ctx context.Context, category string) ([]model.Template, error)
+	DeleteTemplate(ctx context.Context, id int) error
+
+	CreateSection(ctx context.Context, section model.Section) (model.Section, error)
+	GetSectionsBySurveyID(ctx context.Context, surveyID int64) ([]model.Section, error)
+	DeleteSectionsBySurveyID(ctx context.Context, surveyID int64) error
+
+	SetTranslations(ctx context.Context, surveyID int64, lang string, translations []model.Translation) error
+	GetTranslations(ctx context.Context, surveyID int64, lang string) ([]model.Translation, error)
+	GetTranslationLangs(ctx context.Context, surveyID int64) ([]string, error)
+	DeleteTranslationsBySurveyID(ctx context.Context, surveyID int64) error
 }
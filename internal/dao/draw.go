@@ -0,0 +1,75 @@
+package dao
+
+import (
+	"context"
+
+	"QA-System/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateDraw 创建抽奖
+func (d *Dao) CreateDraw(ctx context.Context, draw model.Draw) (model.Draw, error) {
+	err := d.orm.WithContext(ctx).Create(&draw).Error
+	return draw, err
+}
+
+// GetDrawByID 根据ID获取抽奖
+func (d *Dao) GetDrawByID(ctx context.Context, id int) (*model.Draw, error) {
+	var draw model.Draw
+	err := d.orm.WithContext(ctx).Where("id = ?", id).First(&draw).Error
+	return &draw, err
+}
+
+// GetDrawsBySurveyID 获取问卷的全部抽奖
+func (d *Dao) GetDrawsBySurveyID(ctx context.Context, surveyID int64) ([]model.Draw, error) {
+	var draws []model.Draw
+	err := d.orm.WithContext(ctx).Where("survey_id = ?", surveyID).Order("id").Find(&draws).Error
+	return draws, err
+}
+
+// FinishDraw 保存开奖结果，抽奖已开奖时不做修改
+func (d *Dao) FinishDraw(ctx context.Context, draw model.Draw, winners []model.DrawWinner) error {
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Draw{}).Where("id = ? AND status = 0", draw.ID).
+			Select("seed", "salt", "pool_hash", "candidates", "status", "drawn_at", "candidate_keys").Updates(draw)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if len(winners) == 0 {
+			return nil
+		}
+		return tx.Create(&winners).Error
+	})
+}
+
+// GetDrawWinners 按中奖顺序获取中奖记录
+func (d *Dao) GetDrawWinners(ctx context.Context, drawID int) ([]model.DrawWinner, error) {
+	var winners []model.DrawWinner
+	err := d.orm.WithContext(ctx).Where("draw_id = ?", drawID).Order("`rank`").Find(&winners).Error
+	return winners, err
+}
+
+// DeleteDraw 删除抽奖及其中奖记录
+func (d *Dao) DeleteDraw(ctx context.Context, id int) error {
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("draw_id = ?", id).Delete(&model.DrawWinner{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.Draw{}).Error
+	})
+}
+
+// DeleteDrawsBySurveyID 删除问卷的全部抽奖及中奖记录
+func (d *Dao) DeleteDrawsBySurveyID(ctx context.Context, surveyID int64) error {
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&model.Draw{}).Select("id").Where("survey_id = ?", surveyID)
+		if err := tx.Where("draw_id IN (?)", ids).Delete(&model.DrawWinner{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("survey_id = ?", surveyID).Delete(&model.Draw{}).Error
+	})
+}
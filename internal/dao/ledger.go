@@ -0,0 +1,131 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	database "QA-System/internal/pkg/database/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LedgerEntry 答卷哈希链条目
+type LedgerEntry struct {
+	SurveyID int64              `json:"survey_id" bson:"survey_id"`                     // 问卷ID
+	Seq      int64              `json:"seq" bson:"seq"`                                 // 序号 从1开始
+	Type     string             `json:"type" bson:"type"`                               // 类型 submit delete purge
+	AnswerID primitive.ObjectID `json:"answer_id,omitempty" bson:"answer_id,omitempty"` // 答卷ID
+	Digest   string             `json:"digest,omitempty" bson:"digest,omitempty"`       // 答卷内容摘要
+	Operator int                `json:"operator,omitempty" bson:"operator,omitempty"`   // 执行删除的管理员ID
+	Time     time.Time          `json:"time" bson:"time"`                               // 记录时间
+	PrevHash string             `json:"prev_hash" bson:"prev_hash"`                     // 上一条目的哈希
+	Hash     string             `json:"hash" bson:"hash"`                               // 本条目的哈希
+}
+
+// LedgerCheckpoint 哈希链签名检查点
+type LedgerCheckpoint struct {
+	SurveyID  int64     `json:"survey_id" bson:"survey_id"`   // 问卷ID
+	Seq       int64     `json:"seq" bson:"seq"`               // 签名时哈希链的最新序号
+	Hash      string    `json:"hash" bson:"hash"`             // 签名时哈希链的最新哈希
+	Time      time.Time `json:"time" bson:"time"`             // 签名时间
+	PublicKey string    `json:"public_key" bson:"public_key"` // 签名公钥 base64
+	Signature string    `json:"signature" bson:"signature"`   // 签名 base64
+}
+
+// GetLastLedgerEntry 获取问卷哈希链的最新条目，没有条目时返回 nil
+func (d *Dao) GetLastLedgerEntry(ctx context.Context, surveyID int64) (*LedgerEntry, error) {
+	var entry LedgerEntry
+	opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})
+	err := d.mongo.Collection(database.Ledger).FindOne(ctx, bson.M{"survey_id": surveyID}, opts).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// InsertLedgerEntry 追加哈希链条目，序号已存在时返回 mongo 的重复键错误
+func (d *Dao) InsertLedgerEntry(ctx context.Context, entry LedgerEntry) error {
+	_, err := d.mongo.Collection(database.Ledger).InsertOne(ctx, entry)
+	return err
+}
+
+// GetLedgerEntries 按序号获取问卷哈希链的全部条目
+func (d *Dao) GetLedgerEntries(ctx context.Context, surveyID int64) ([]LedgerEntry, error) {
+	entries := make([]LedgerEntry, 0)
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	cursor, err := d.mongo.Collection(database.Ledger).Find(ctx, bson.M{"survey_id": surveyID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &entries)
+	return entries, err
+}
+
+// GetLedgerEntriesAfter 按序号获取问卷哈希链中 seq 之后的条目，最多 limit 条
+func (d *Dao) GetLedgerEntriesAfter(ctx context.Context, surveyID int64, seq int64,
+	limit int64) ([]LedgerEntry, error) {
+	entries := make([]LedgerEntry, 0)
+	filter := bson.M{"survey_id": surveyID, "seq": bson.M{"$gt": seq}}
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}).SetLimit(limit)
+	cursor, err := d.mongo.Collection(database.Ledger).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &entries)
+	return entries, err
+}
+
+// GetLedgerSurveys 获取有哈希链的问卷
+func (d *Dao) GetLedgerSurveys(ctx context.Context) ([]int64, error) {
+	values, err := d.mongo.Collection(database.Ledger).Distinct(ctx, "survey_id", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(int64); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// SaveLedgerCheckpoint 保存哈希链检查点
+func (d *Dao) SaveLedgerCheckpoint(ctx context.Context, checkpoint LedgerCheckpoint) error {
+	_, err := d.mongo.Collection(database.Checkpoint).InsertOne(ctx, checkpoint)
+	return err
+}
+
+// GetLedgerCheckpoints 按序号获取问卷的全部检查点
+func (d *Dao) GetLedgerCheckpoints(ctx context.Context, surveyID int64) ([]LedgerCheckpoint, error) {
+	checkpoints := make([]LedgerCheckpoint, 0)
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	cursor, err := d.mongo.Collection(database.Checkpoint).Find(ctx, bson.M{"survey_id": surveyID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &checkpoints)
+	return checkpoints, err
+}
+
+// GetLastLedgerCheckpoint 获取问卷的最新检查点，没有检查点时返回 nil
+func (d *Dao) GetLastLedgerCheckpoint(ctx context.Context, surveyID int64) (*LedgerCheckpoint, error) {
+	var checkpoint LedgerCheckpoint
+	opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})
+	err := d.mongo.Collection(database.Checkpoint).FindOne(ctx, bson.M{"survey_id": surveyID}, opts).
+		Decode(&checkpoint)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
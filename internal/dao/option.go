@@ -16,6 +16,7 @@ type Option struct {
 	Content     string `json:"content"`     // 选项内容
 	Description string `json:"description"` // 选项描述
 	Img         string `json:"img"`         // 图片
+	Pinned      bool   `json:"pinned"`      // 打乱选项顺序时是否固定位置
 }
 
 // CreateOption 创建选项
@@ -0,0 +1,100 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/redis"
+)
+
+// OptionSetNode 选项集树节点
+type OptionSetNode struct {
+	Content  string          `json:"content" binding:"required"` // 条目内容
+	Children []OptionSetNode `json:"children" binding:"dive"`    // 下一级条目
+}
+
+// CreateOptionSet 创建选项集
+func (d *Dao) CreateOptionSet(ctx context.Context, set model.OptionSet) (model.OptionSet, error) {
+	err := d.orm.WithContext(ctx).Create(&set).Error
+	return set, err
+}
+
+// UpdateOptionSet 更新选项集信息和当前版本号
+func (d *Dao) UpdateOptionSet(ctx context.Context, set model.OptionSet) error {
+	err := d.orm.WithContext(ctx).Model(&model.OptionSet{}).Where("id = ?", set.ID).
+		Select("name", "description", "version").Updates(set).Error
+	return err
+}
+
+// GetOptionSetByID 根据ID获取选项集
+func (d *Dao) GetOptionSetByID(ctx context.Context, id int) (*model.OptionSet, error) {
+	var set model.OptionSet
+	err := d.orm.WithContext(ctx).Where("id = ?", id).First(&set).Error
+	return &set, err
+}
+
+// GetOptionSets 分页查询选项集
+func (d *Dao) GetOptionSets(ctx context.Context, keyword string, pageNum, pageSize int) (
+	[]model.OptionSet, int64, error) {
+	var sets []model.OptionSet
+	var total int64
+	query := d.orm.WithContext(ctx).Model(&model.OptionSet{})
+	if keyword != "" {
+		query = query.Where("name LIKE ?", "%"+keyword+"%")
+	}
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("updated_at DESC").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&sets).Error
+	return sets, total, err
+}
+
+// DeleteOptionSet 删除选项集及其所有版本的条目
+func (d *Dao) DeleteOptionSet(ctx context.Context, id int) error {
+	err := d.orm.WithContext(ctx).Where("option_set_id = ?", id).Delete(&model.OptionSetItem{}).Error
+	if err != nil {
+		return err
+	}
+	err = d.orm.WithContext(ctx).Where("id = ?", id).Delete(&model.OptionSet{}).Error
+	return err
+}
+
+// CreateOptionSetItem 创建选项集条目
+func (d *Dao) CreateOptionSetItem(ctx context.Context, item model.OptionSetItem) (model.OptionSetItem, error) {
+	err := d.orm.WithContext(ctx).Create(&item).Error
+	return item, err
+}
+
+// GetOptionSetItems 获取选项集某个版本的全部条目
+func (d *Dao) GetOptionSetItems(ctx context.Context, id int, version int) ([]model.OptionSetItem, error) {
+	var items []model.OptionSetItem
+	// 历史版本的条目不会再被修改，可以放心缓存
+	key := fmt.Sprintf("optionset:id:%d:version:%d", id, version)
+	cachedData, err := redis.RedisClient.Get(ctx, key).Result()
+	if err == nil && cachedData != "" {
+		if err := json.Unmarshal([]byte(cachedData), &items); err == nil {
+			return items, nil
+		}
+	}
+	err = d.orm.WithContext(ctx).Where("option_set_id = ? AND version = ?", id, version).
+		Order("parent_id, serial_num").Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := json.Marshal(items)
+	if err == nil {
+		redis.RedisClient.Set(ctx, key, jsonData, 20*time.Minute)
+	}
+	return items, nil
+}
+
+// CountQuestionsByOptionSetID 统计绑定了选项集的问题数量
+func (d *Dao) CountQuestionsByOptionSetID(ctx context.Context, id int) (int64, error) {
+	var count int64
+	err := d.orm.WithContext(ctx).Model(&model.Question{}).Where("option_set_id = ?", id).Count(&count).Error
+	return count, err
+}
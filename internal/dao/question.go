@@ -19,6 +19,17 @@ type BaseConfig struct {
 	Verify        bool   `json:"verify"`         // 问卷是否需要统一验证
 	UndergradOnly bool   `json:"undergrad_only"` // 是否只限制本科生作答
 	NeedNotify    bool   `json:"need_notify"`    // 问卷在收到回复时是否需要提醒
+
+	ShuffleQuestions bool     `json:"shuffle_questions"` // 是否为每位答题者打乱题目顺序
+	HiddenFields     []string `json:"hidden_fields"`     // 隐藏字段名，如 channel、class
+	DefaultLang      string   `json:"default_lang"`      // 问卷原文的语言 为空时为简体中文
+
+	Completion model.SurveyCompletion `json:"completion"` // 提交后的完成页设置
+	Theme      model.SurveyTheme      `json:"theme"`      // 问卷主题
+
+	ResultPolicy model.SurveyResultPolicy `json:"result_policy"` // 投票结果公开方式
+
+	Anonymous bool `json:"anonymous"` // 是否为匿名投票 需要开启统一验证
 }
 
 // QuestionConfig 问题配置模型
@@ -26,6 +37,7 @@ type QuestionConfig struct {
 	Desc         string         `json:"desc" `
 	Title        string         `json:"title"`
 	QuestionList []QuestionList `json:"question_list"`
+	Sections     []Section      `json:"sections"` // 分页 为空时不分页
 }
 
 // QuestionList 问题列表模型
@@ -36,18 +48,30 @@ type QuestionList struct {
 	Img             string          `json:"img"`          // 图片
 	QuestionSetting QuestionSetting `json:"ques_setting"` // 问题设置
 	Options         []Option        `json:"options"`      // 选项
+	BankID          int             `json:"bank_id"`      // 题库题目ID 0为不使用题库
+	BankRef         bool            `json:"bank_ref"`     // 是否引用题库题目 否则为复制
+	Section         int             `json:"section"`      // 所属分页序号 0为不分页
 }
 
 // QuestionSetting 问题设置模型
 type QuestionSetting struct {
-	Required      bool     `json:"required"`                                           // 是否必填
-	Unique        bool     `json:"unique"`                                             // 是否唯一
-	OtherOption   bool     `json:"other_option"`                                       // 是否有其他选项
-	QuestionType  int      `json:"question_type" binding:"required,oneof=1 2 3 4 5 6"` // 问题类型 1单选2多选3填空4简答5图片6文件
-	Reg           string   `json:"reg"`                                                // 正则表达式
-	Options       []Option `json:"options"`                                            // 选项
-	MaximumOption uint     `json:"maximum_option"`                                     // 多选最多选项数 0为不限制
-	MinimumOption uint     `json:"minimum_option"`                                     // 多选最少选项数 0为不限制
+	Required         bool     `json:"required"`                                                    // 是否必填
+	Unique           bool     `json:"unique"`                                                      // 是否唯一
+	OtherOption      bool     `json:"other_option"`                                                // 是否有其他选项
+	QuestionType     int      `json:"question_type" binding:"required,oneof=1 2 3 4 5 6 7 8 9 10"` // 问题类型 1单选2多选3填空4简答5图片6文件7级联8计算9排序10评分
+	Reg              string   `json:"reg"`                                                         // 正则表达式
+	Options          []Option `json:"options"`                                                     // 选项
+	MaximumOption    uint     `json:"maximum_option"`                                              // 多选最多选项数 0为不限制
+	MinimumOption    uint     `json:"minimum_option"`                                              // 多选最少选项数 0为不限制
+	OptionSetID      int      `json:"option_set_id"`                                               // 绑定的选项集ID 0为使用自身选项
+	OptionSetVersion int      `json:"option_set_version"`                                          // 绑定的选项集版本 0为跟随最新版本
+	ShuffleOptions   bool     `json:"shuffle_options"`                                             // 是否打乱选项顺序
+	Pinned           bool     `json:"pinned"`                                                      // 打乱题目顺序时是否固定位置
+	Expression       string   `json:"expression"`                                                  // 计算题的计算公式，如 Q1/(Q2/100)^2
+	TallyMethod      string   `json:"tally_method"`                                                // 排序题的计票方法 instant_runoff 或 borda
+	TieBreak         string   `json:"tie_break"`                                                   // 计票的平票规则 serial、count_back 或 random
+	MaxScore         int      `json:"max_score"`                                                   // 评分题的最高分
+	Numeric          bool     `json:"numeric"`                                                     // 填空题的答案是否为数值
 }
 
 // QuestionsList 问题列表模型
@@ -2,11 +2,13 @@ package dao
 
 import (
 	"context"
+	"crypto/rand"
 	"time"
 
 	database "QA-System/internal/pkg/database/mongodb"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // RecordSheet 记录表模型
@@ -20,12 +22,44 @@ type RecordSheet struct {
 	Time         time.Time `json:"time" bson:"time"`                     // 答卷时间
 }
 
+// RecordEntry 统一验证记录
+type RecordEntry struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	SurveyID int64              `bson:"survey_id"`
+	Record   RecordSheet        `bson:"record"`
+}
+
 // SaveRecordSheet 将记录直接保存到 MongoDB 集合中
+//
+// 记录ID使用随机值，驱动生成的 ObjectID 包含精确到秒的插入时间，会暴露匿名投票者的提交时间。
 func (d *Dao) SaveRecordSheet(ctx context.Context, answerSheet RecordSheet, sid int64) error {
-	_, err := d.mongo.Collection(database.Record).InsertOne(ctx, bson.M{"survey_id": sid, "record": answerSheet})
+	var id primitive.ObjectID
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	_, err := d.mongo.Collection(database.Record).InsertOne(ctx,
+		bson.M{"_id": id, "survey_id": sid, "record": answerSheet})
 	return err
 }
 
+// HasRecordSheet 判断学生是否已填写过问卷
+func (d *Dao) HasRecordSheet(ctx context.Context, sid int64, studentID string) (bool, error) {
+	count, err := d.mongo.Collection(database.Record).CountDocuments(ctx,
+		bson.M{"survey_id": sid, "record.student_id": studentID})
+	return count > 0, err
+}
+
+// GetRecordSheets 获取问卷的全部统一验证记录
+func (d *Dao) GetRecordSheets(ctx context.Context, sid int64) ([]RecordEntry, error) {
+	entries := make([]RecordEntry, 0)
+	cursor, err := d.mongo.Collection(database.Record).Find(ctx, bson.M{"survey_id": sid})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &entries)
+	return entries, err
+}
+
 // DeleteRecordSheets 删除记录表
 func (d *Dao) DeleteRecordSheets(ctx context.Context, surveyID int64) error {
 	_, err := d.mongo.Collection(database.Record).DeleteMany(ctx, bson.M{"survey_id": surveyID})
@@ -0,0 +1,34 @@
+package dao
+
+import (
+	"context"
+
+	"QA-System/internal/model"
+)
+
+// Section 分页配置模型
+type Section struct {
+	SerialNum   int    `json:"serial_num"`  // 分页序号
+	Title       string `json:"title"`       // 分页标题
+	Description string `json:"description"` // 分页介绍
+	Shuffle     bool   `json:"shuffle"`     // 是否打乱本页题目顺序
+}
+
+// CreateSection 创建分页
+func (d *Dao) CreateSection(ctx context.Context, section model.Section) (model.Section, error) {
+	err := d.orm.WithContext(ctx).Create(&section).Error
+	return section, err
+}
+
+// GetSectionsBySurveyID 根据问卷ID获取分页列表
+func (d *Dao) GetSectionsBySurveyID(ctx context.Context, surveyID int64) ([]model.Section, error) {
+	var sections []model.Section
+	err := d.orm.WithContext(ctx).Where("survey_id = ?", surveyID).Order("serial_num").Find(&sections).Error
+	return sections, err
+}
+
+// DeleteSectionsBySurveyID 根据问卷ID删除分页
+func (d *Dao) DeleteSectionsBySurveyID(ctx context.Context, surveyID int64) error {
+	err := d.orm.WithContext(ctx).Where("survey_id = ?", surveyID).Delete(&model.Section{}).Error
+	return err
+}
@@ -0,0 +1,132 @@
+package dao
+
+import (
+	"context"
+
+	database "QA-System/internal/pkg/database/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// StatsGroup 按隐藏字段分组后的答卷数
+type StatsGroup struct {
+	Value string `bson:"_id"`   // 隐藏字段的值 未分组时为空
+	Total int    `bson:"total"` // 答卷数
+}
+
+// StatsCount 某一分组中问题的某个答案出现的次数，多选答案按选项拆分计数
+type StatsCount struct {
+	Group      string `bson:"group"`
+	QuestionID int    `bson:"question_id"`
+	Content    string `bson:"content"`
+	Count      int    `bson:"count"`
+}
+
+// StatsPresence 某一分组中出现过答案的问题
+type StatsPresence struct {
+	Group      string `bson:"group"`
+	QuestionID int    `bson:"question_id"`
+	Count      int    `bson:"count"` // 答案数
+}
+
+// AnswerStats 答卷的聚合统计结果
+type AnswerStats struct {
+	Groups   []StatsGroup    `bson:"groups"` // 按首次提交的顺序排列
+	Presence []StatsPresence `bson:"presence"`
+	Counts   []StatsCount    `bson:"counts"`
+}
+
+// AggregateAnswerStats 在 MongoDB 中统计有效答卷的答案次数
+//
+// 只统计 questionIDs 中问题的答案，dimension 不为空时按该隐藏字段的值分组，字段名需由调用方校验。
+func (d *Dao) AggregateAnswerStats(ctx context.Context, surveyID int64, hidden map[string]string,
+	dimension string, questionIDs []int) (*AnswerStats, error) {
+	match := bson.M{"surveyid": surveyID, "unique": true}
+	for name, value := range hidden {
+		match["hiddenfields."+name] = value
+	}
+	var group any = ""
+	if dimension != "" {
+		group = bson.M{"$ifNull": bson.A{"$hiddenfields." + dimension, ""}}
+	}
+	pipeline := bson.A{
+		bson.M{"$match": match},
+		bson.M{"$project": bson.M{"group": group, "answers.questionid": 1, "answers.content": 1}},
+		bson.M{"$facet": bson.M{
+			"groups": bson.A{
+				bson.M{"$group": bson.M{"_id": "$group", "total": bson.M{"$sum": 1}, "first": bson.M{"$min": "$_id"}}},
+				bson.M{"$sort": bson.M{"first": 1}},
+			},
+			"presence": bson.A{
+				bson.M{"$unwind": "$answers"},
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"group": "$group", "question_id": "$answers.questionid"},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$replaceRoot": bson.M{
+					"newRoot": bson.M{"$mergeObjects": bson.A{"$_id", bson.M{"count": "$count"}}},
+				}},
+			},
+			"counts": bson.A{
+				bson.M{"$unwind": "$answers"},
+				bson.M{"$match": bson.M{"answers.questionid": bson.M{"$in": questionIDs}}},
+				bson.M{"$project": bson.M{
+					"group":       1,
+					"question_id": "$answers.questionid",
+					"content":     bson.M{"$split": bson.A{"$answers.content", "┋"}},
+				}},
+				bson.M{"$unwind": "$content"},
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"group": "$group", "question_id": "$question_id", "content": "$content"},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$replaceRoot": bson.M{
+					"newRoot": bson.M{"$mergeObjects": bson.A{"$_id", bson.M{"count": "$count"}}},
+				}},
+			},
+		}},
+	}
+	cursor, err := d.mongo.Collection(database.QA).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]AnswerStats, 0, 1)
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return &AnswerStats{}, nil
+	}
+	return &results[0], nil
+}
+
+// AnswerContent 问题的一条非空答案
+type AnswerContent struct {
+	QuestionID int    `bson:"question_id"`
+	Content    string `bson:"content"`
+}
+
+// GetAnswerContents 获取有效答卷中 questionIDs 中问题的非空答案
+func (d *Dao) GetAnswerContents(ctx context.Context, surveyID int64, hidden map[string]string,
+	questionIDs []int) ([]AnswerContent, error) {
+	match := bson.M{"surveyid": surveyID, "unique": true}
+	for name, value := range hidden {
+		match["hiddenfields."+name] = value
+	}
+	pipeline := bson.A{
+		bson.M{"$match": match},
+		bson.M{"$unwind": "$answers"},
+		bson.M{"$match": bson.M{
+			"answers.questionid": bson.M{"$in": questionIDs},
+			"answers.content":    bson.M{"$ne": ""},
+		}},
+		bson.M{"$project": bson.M{"_id": 0, "question_id": "$answers.questionid", "content": "$answers.content"}},
+	}
+	cursor, err := d.mongo.Collection(database.QA).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	contents := make([]AnswerContent, 0)
+	err = cursor.All(ctx, &contents)
+	return contents, err
+}
@@ -0,0 +1,52 @@
+package dao
+
+import (
+	"context"
+	"strconv"
+
+	"QA-System/internal/pkg/redis"
+
+	"go.uber.org/zap"
+)
+
+const statsUpdateChannel = "stats:updates" // 答卷变化通知频道，消息为问卷ID
+
+// PublishStatsUpdate 通知各实例问卷的答卷已变化
+func (d *Dao) PublishStatsUpdate(ctx context.Context, surveyID int64) error {
+	return redis.RedisClient.Publish(ctx, statsUpdateChannel, surveyID).Err()
+}
+
+// SubscribeStatsUpdates 订阅答卷变化通知，ctx 结束后关闭返回的通道
+func (d *Dao) SubscribeStatsUpdates(ctx context.Context) <-chan int64 {
+	pubsub := redis.RedisClient.Subscribe(ctx, statsUpdateChannel)
+	updates := make(chan int64)
+	go func() {
+		defer close(updates)
+		defer func() {
+			if err := pubsub.Close(); err != nil {
+				zap.L().Error("关闭答卷变化订阅失败", zap.Error(err))
+			}
+		}()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				sid, err := strconv.ParseInt(msg.Payload, 10, 64)
+				if err != nil {
+					continue
+				}
+				select {
+				case updates <- sid:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return updates
+}
@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"context"
+
+	"QA-System/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateStudy 创建研究及其轮次
+func (d *Dao) CreateStudy(ctx context.Context, study model.Study, waves []model.StudyWave) (model.Study, error) {
+	err := d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&study).Error; err != nil {
+			return err
+		}
+		return createStudyWaves(tx, study.ID, waves)
+	})
+	return study, err
+}
+
+// UpdateStudy 更新研究信息和对应题目，并替换全部轮次
+func (d *Dao) UpdateStudy(ctx context.Context, study model.Study, waves []model.StudyWave) error {
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.Study{}).Where("id = ?", study.ID).
+			Select("name", "description", "items").Updates(study).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("study_id = ?", study.ID).Delete(&model.StudyWave{}).Error; err != nil {
+			return err
+		}
+		return createStudyWaves(tx, study.ID, waves)
+	})
+}
+
+// GetStudyByID 根据ID获取研究
+func (d *Dao) GetStudyByID(ctx context.Context, id int) (*model.Study, error) {
+	var study model.Study
+	err := d.orm.WithContext(ctx).Where("id = ?", id).First(&study).Error
+	return &study, err
+}
+
+// GetStudies 分页查询研究，userID 为 0 时查询全部
+func (d *Dao) GetStudies(ctx context.Context, userID int, pageNum, pageSize int) ([]model.Study, int64, error) {
+	var studies []model.Study
+	var total int64
+	query := d.orm.WithContext(ctx).Model(&model.Study{})
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("updated_at DESC").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&studies).Error
+	return studies, total, err
+}
+
+// GetStudyWaves 获取研究的全部轮次，按轮次顺序排列
+func (d *Dao) GetStudyWaves(ctx context.Context, studyID int) ([]model.StudyWave, error) {
+	var waves []model.StudyWave
+	err := d.orm.WithContext(ctx).Where("study_id = ?", studyID).Order("serial_num").Find(&waves).Error
+	return waves, err
+}
+
+// DeleteStudy 删除研究及其轮次
+func (d *Dao) DeleteStudy(ctx context.Context, id int) error {
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("study_id = ?", id).Delete(&model.StudyWave{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.Study{}).Error
+	})
+}
+
+// DeleteStudyWavesBySurveyID 从所有研究中移除问卷对应的轮次
+func (d *Dao) DeleteStudyWavesBySurveyID(ctx context.Context, surveyID int64) error {
+	return d.orm.WithContext(ctx).Where("survey_id = ?", surveyID).Delete(&model.StudyWave{}).Error
+}
+
+func createStudyWaves(tx *gorm.DB, studyID int, waves []model.StudyWave) error {
+	for i := range waves {
+		waves[i].ID = 0
+		waves[i].StudyID = studyID
+		waves[i].SerialNum = i + 1
+	}
+	if len(waves) == 0 {
+		return nil
+	}
+	return tx.Create(&waves).Error
+}
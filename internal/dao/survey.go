@@ -2,7 +2,6 @@ package dao
 
 import (
 	"context"
-	"time"
 
 	"QA-System/internal/model"
 
@@ -21,23 +20,13 @@ func (d *Dao) UpdateSurveyStatus(ctx context.Context, surveyID int64, status int
 	return err
 }
 
-// UpdateSurvey 更新问卷
-func (d *Dao) UpdateSurvey(ctx context.Context, id int64, surveyType, limit uint,
-	sumLimit uint, verify bool, undergrad_only bool, desc string, title string, deadline, startTime time.Time,
-	needNotify bool) error {
-	err := d.orm.WithContext(ctx).Model(&model.Survey{}).Where("id = ?", id).
-		Updates(model.Survey{
-			Deadline:      deadline,
-			DailyLimit:    limit,
-			SumLimit:      sumLimit,
-			Verify:        verify,
-			UndergradOnly: undergrad_only,
-			Desc:          desc,
-			Title:         title,
-			Type:          surveyType,
-			StartTime:     startTime,
-			NeedNotify:    needNotify,
-		}).Error
+// UpdateSurvey 更新问卷配置，零值字段同样会被更新
+func (d *Dao) UpdateSurvey(ctx context.Context, survey model.Survey) error {
+	err := d.orm.WithContext(ctx).Model(&model.Survey{}).Where("id = ?", survey.ID).
+		Select("deadline", "daily_limit", "sum_limit", "verify", "undergrad_only", "desc", "title", "type",
+			"start_time", "need_notify", "shuffle_questions", "hidden_fields", "default_lang",
+			"completion", "theme", "result_policy", "anonymous").
+		Updates(survey).Error
 	return err
 }
 
@@ -0,0 +1,43 @@
+package dao
+
+import (
+	"context"
+
+	"QA-System/internal/model"
+)
+
+// TemplateContent 问卷模板内容
+type TemplateContent struct {
+	BaseConfig     BaseConfig     `json:"base_config"` // 基本配置 模板中的时间不生效
+	QuestionConfig QuestionConfig `json:"ques_config"` // 问题设置
+}
+
+// CreateTemplate 创建组织模板
+func (d *Dao) CreateTemplate(ctx context.Context, template model.Template) (model.Template, error) {
+	err := d.orm.WithContext(ctx).Create(&template).Error
+	return template, err
+}
+
+// GetTemplateByID 根据ID获取组织模板
+func (d *Dao) GetTemplateByID(ctx context.Context, id int) (*model.Template, error) {
+	var template model.Template
+	err := d.orm.WithContext(ctx).Where("id = ?", id).First(&template).Error
+	return &template, err
+}
+
+// GetTemplates 获取组织模板列表
+func (d *Dao) GetTemplates(ctx context.Context, category string) ([]model.Template, error) {
+	var templates []model.Template
+	query := d.orm.WithContext(ctx).Model(&model.Template{})
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	err := query.Order("created_at DESC").Find(&templates).Error
+	return templates, err
+}
+
+// DeleteTemplate 删除组织模板
+func (d *Dao) DeleteTemplate(ctx context.Context, id int) error {
+	err := d.orm.WithContext(ctx).Where("id = ?", id).Delete(&model.Template{}).Error
+	return err
+}
@@ -0,0 +1,116 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	database "QA-System/internal/pkg/database/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TimeBucket 某个时间段内提交的答卷数
+type TimeBucket struct {
+	Start string `bson:"_id"`   // 时间段的开始时间
+	Count int    `bson:"count"` // 答卷数
+}
+
+// HeatmapCell 某个星期几的某个小时内提交的答卷数
+type HeatmapCell struct {
+	Weekday int `bson:"weekday"` // 1 为周一，7 为周日
+	Hour    int `bson:"hour"`
+	Count   int `bson:"count"`
+}
+
+// submittedMatch 筛选问卷在时间范围内的有效答卷，start、end 为零值时不限制
+func submittedMatch(surveyID int64, start, end time.Time) bson.M {
+	submittedAt := bson.M{"$type": "date"}
+	if !start.IsZero() {
+		submittedAt["$gte"] = start
+	}
+	if !end.IsZero() {
+		submittedAt["$lt"] = end
+	}
+	return bson.M{"surveyid": surveyID, "unique": true, "submittedat": submittedAt}
+}
+
+// AggregateSubmissionTimeline 按时间段统计问卷的有效答卷数，按时间段排序
+//
+// unit 为 hour、day 或 week，周从周一开始；timezone 为 +08:00 形式的时区偏移，结果按 time.DateTime 格式返回开始时间。
+func (d *Dao) AggregateSubmissionTimeline(ctx context.Context, surveyID int64, unit, timezone string,
+	start, end time.Time) ([]TimeBucket, error) {
+	date := any("$submittedat")
+	format := "%Y-%m-%d 00:00:00"
+	switch unit {
+	case "hour":
+		format = "%Y-%m-%d %H:00:00"
+	case "week":
+		// 回退到当周周一
+		weekday := bson.M{"$isoDayOfWeek": bson.M{"date": "$submittedat", "timezone": timezone}}
+		days := bson.M{"$subtract": bson.A{weekday, 1}}
+		date = bson.M{"$subtract": bson.A{"$submittedat", bson.M{"$multiply": bson.A{days, 24 * 60 * 60 * 1000}}}}
+	}
+	pipeline := bson.A{
+		bson.M{"$match": submittedMatch(surveyID, start, end)},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": format, "date": date, "timezone": timezone}},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+	cursor, err := d.mongo.Collection(database.QA).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	buckets := make([]TimeBucket, 0)
+	err = cursor.All(ctx, &buckets)
+	return buckets, err
+}
+
+// AggregateSubmissionHeatmap 按星期几和小时统计问卷的有效答卷数，只返回有答卷的格子
+func (d *Dao) AggregateSubmissionHeatmap(ctx context.Context, surveyID int64, timezone string,
+	start, end time.Time) ([]HeatmapCell, error) {
+	pipeline := bson.A{
+		bson.M{"$match": submittedMatch(surveyID, start, end)},
+		bson.M{"$group": bson.M{
+			"_id": bson.M{
+				"weekday": bson.M{"$isoDayOfWeek": bson.M{"date": "$submittedat", "timezone": timezone}},
+				"hour":    bson.M{"$hour": bson.M{"date": "$submittedat", "timezone": timezone}},
+			},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$project": bson.M{"_id": 0, "weekday": "$_id.weekday", "hour": "$_id.hour", "count": 1}},
+	}
+	cursor, err := d.mongo.Collection(database.QA).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	cells := make([]HeatmapCell, 0)
+	err = cursor.All(ctx, &cells)
+	return cells, err
+}
+
+// CountUntimedAnswerSheets 统计问卷中没有可查询答卷时间的有效答卷数，即未迁移或时间无法解析的旧答卷
+func (d *Dao) CountUntimedAnswerSheets(ctx context.Context, surveyID int64) (int64, error) {
+	filter := bson.M{"surveyid": surveyID, "unique": true, "submittedat": bson.M{"$not": bson.M{"$type": "date"}}}
+	return d.mongo.Collection(database.QA).CountDocuments(ctx, filter)
+}
+
+// MigrateSubmittedAt 根据答卷时间字符串为旧答卷补充答卷时间，返回更新的答卷数
+//
+// 在服务端按 time.DateTime 格式解析，无法解析的答卷记为 null，不再重复处理。
+func (d *Dao) MigrateSubmittedAt(ctx context.Context, timezone string) (int64, error) {
+	update := bson.A{bson.M{"$set": bson.M{"submittedat": bson.M{"$dateFromString": bson.M{
+		"dateString": "$time",
+		"format":     "%Y-%m-%d %H:%M:%S",
+		"timezone":   timezone,
+		"onError":    nil,
+		"onNull":     nil,
+	}}}}}
+	filter := bson.M{"submittedat": bson.M{"$exists": false}}
+	result, err := d.mongo.Collection(database.QA).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,76 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/redis"
+
+	"gorm.io/gorm"
+)
+
+// SetTranslations 替换问卷某一语言的全部译文
+func (d *Dao) SetTranslations(ctx context.Context, surveyID int64, lang string,
+	translations []model.Translation) error {
+	err := redis.RedisClient.Del(ctx, fmt.Sprintf("translations:sid:%d:%s", surveyID, lang)).Err()
+	if err != nil {
+		return err
+	}
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("survey_id = ? AND lang = ?", surveyID, lang).Delete(&model.Translation{}).Error
+		if err != nil {
+			return err
+		}
+		if len(translations) == 0 {
+			return nil
+		}
+		return tx.Create(&translations).Error
+	})
+}
+
+// GetTranslations 获取问卷某一语言的全部译文
+func (d *Dao) GetTranslations(ctx context.Context, surveyID int64, lang string) ([]model.Translation, error) {
+	var translations []model.Translation
+	cacheKey := fmt.Sprintf("translations:sid:%d:%s", surveyID, lang)
+	cacheData, err := redis.RedisClient.Get(ctx, cacheKey).Result()
+	if err == nil && cacheData != "" {
+		if err := json.Unmarshal([]byte(cacheData), &translations); err == nil {
+			return translations, nil
+		}
+	}
+	err = d.orm.WithContext(ctx).Where("survey_id = ? AND lang = ?", surveyID, lang).Find(&translations).Error
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := json.Marshal(translations)
+	if err == nil {
+		redis.RedisClient.Set(ctx, cacheKey, jsonData, 20*time.Minute)
+	}
+	return translations, nil
+}
+
+// GetTranslationLangs 获取问卷已有译文的语言
+func (d *Dao) GetTranslationLangs(ctx context.Context, surveyID int64) ([]string, error) {
+	var langs []string
+	err := d.orm.WithContext(ctx).Model(&model.Translation{}).Where("survey_id = ?", surveyID).
+		Distinct("lang").Order("lang").Pluck("lang", &langs).Error
+	return langs, err
+}
+
+// DeleteTranslationsBySurveyID 删除问卷的全部译文
+func (d *Dao) DeleteTranslationsBySurveyID(ctx context.Context, surveyID int64) error {
+	langs, err := d.GetTranslationLangs(ctx, surveyID)
+	if err != nil {
+		return err
+	}
+	for _, lang := range langs {
+		err := redis.RedisClient.Del(ctx, fmt.Sprintf("translations:sid:%d:%s", surveyID, lang)).Err()
+		if err != nil {
+			return err
+		}
+	}
+	return d.orm.WithContext(ctx).Where("survey_id = ?", surveyID).Delete(&model.Translation{}).Error
+}
@@ -0,0 +1,69 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	database "QA-System/internal/pkg/database/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Visit 答题者打开问卷的一次访问，不记录任何可识别答题者的信息
+type Visit struct {
+	VisitID     string     `bson:"_id"`                   // 获取问卷时随机生成的访问标识
+	SurveyID    int64      `bson:"surveyid"`              // 问卷ID
+	ViewedAt    time.Time  `bson:"viewedat"`              // 获取问卷的时间
+	StartedAt   *time.Time `bson:"startedat,omitempty"`   // 开始作答的时间
+	Section     int        `bson:"section"`               // 到达过的最大分页序号 0为未上报
+	SubmittedAt *time.Time `bson:"submittedat,omitempty"` // 提交的时间
+}
+
+// CreateVisit 记录一次访问
+func (d *Dao) CreateVisit(ctx context.Context, visit Visit) error {
+	_, err := d.mongo.Collection(database.Visit).InsertOne(ctx, visit)
+	return err
+}
+
+// StartVisit 记录访问开始作答，只保留最早的时间
+func (d *Dao) StartVisit(ctx context.Context, surveyID int64, visitID string, t time.Time) error {
+	return d.updateVisit(ctx, surveyID, visitID, bson.M{"$min": bson.M{"startedat": t}})
+}
+
+// ReachVisitSection 记录访问保存草稿时所在的分页，同时视为开始作答
+func (d *Dao) ReachVisitSection(ctx context.Context, surveyID int64, visitID string, section int,
+	t time.Time) error {
+	return d.updateVisit(ctx, surveyID, visitID, bson.M{
+		"$min": bson.M{"startedat": t},
+		"$max": bson.M{"section": section},
+	})
+}
+
+// SubmitVisit 记录访问提交问卷，只保留最早的时间
+func (d *Dao) SubmitVisit(ctx context.Context, surveyID int64, visitID string, t time.Time) error {
+	return d.updateVisit(ctx, surveyID, visitID, bson.M{"$min": bson.M{"submittedat": t}})
+}
+
+// GetVisitsBySurveyID 获取问卷的所有访问
+func (d *Dao) GetVisitsBySurveyID(ctx context.Context, surveyID int64) ([]Visit, error) {
+	cursor, err := d.mongo.Collection(database.Visit).Find(ctx, bson.M{"surveyid": surveyID})
+	if err != nil {
+		return nil, err
+	}
+	visits := make([]Visit, 0)
+	err = cursor.All(ctx, &visits)
+	return visits, err
+}
+
+// DeleteVisitsBySurveyID 删除问卷的所有访问
+func (d *Dao) DeleteVisitsBySurveyID(ctx context.Context, surveyID int64) error {
+	_, err := d.mongo.Collection(database.Visit).DeleteMany(ctx, bson.M{"surveyid": surveyID})
+	return err
+}
+
+// updateVisit 更新已有的访问，访问不存在或不属于该问卷时忽略
+func (d *Dao) updateVisit(ctx context.Context, surveyID int64, visitID string, update bson.M) error {
+	filter := bson.M{"_id": visitID, "surveyid": surveyID}
+	_, err := d.mongo.Collection(database.Visit).UpdateOne(ctx, filter, update)
+	return err
+}
@@ -0,0 +1,29 @@
+package dao
+
+import (
+	"context"
+
+	"QA-System/internal/model"
+
+	"gorm.io/gorm/clause"
+)
+
+// SaveWeighting 保存问卷的加权设置，已有设置时覆盖
+func (d *Dao) SaveWeighting(ctx context.Context, weighting model.Weighting) error {
+	return d.orm.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "survey_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"method", "targets", "updated_at"}),
+	}).Create(&weighting).Error
+}
+
+// GetWeighting 获取问卷的加权设置
+func (d *Dao) GetWeighting(ctx context.Context, surveyID int64) (*model.Weighting, error) {
+	var weighting model.Weighting
+	err := d.orm.WithContext(ctx).Where("survey_id = ?", surveyID).First(&weighting).Error
+	return &weighting, err
+}
+
+// DeleteWeighting 删除问卷的加权设置
+func (d *Dao) DeleteWeighting(ctx context.Context, surveyID int64) error {
+	return d.orm.WithContext(ctx).Where("survey_id = ?", surveyID).Delete(&model.Weighting{}).Error
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"testing"
 
 	"github.com/spf13/viper"
 )
@@ -16,7 +17,8 @@ func init() {
 	Config.AddConfigPath(".")
 	Config.WatchConfig() // 自动将配置读入Config变量
 	err := Config.ReadInConfig()
-	if err != nil {
+	// 单元测试不依赖配置文件，使用默认值
+	if err != nil && !testing.Testing() {
 		log.Fatal("Config not find", err)
 	}
 }
@@ -0,0 +1,35 @@
+package admin
+
+import (
+	"errors"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// getManagedSurvey 获取当前管理员有权限管理的问卷，超级管理员、问卷创建者和协作管理员有权限
+func getManagedSurvey(c *gin.Context, sid int64) (*model.User, *model.Survey, bool) {
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return nil, nil, false
+	}
+	survey, err := service.GetSurveyByID(sid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return nil, nil, false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, nil, false
+	}
+	if (user.AdminType != 2) && (user.AdminType != 1 || survey.UserID != user.ID) &&
+		!service.UserInManage(user.ID, survey.ID) {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return nil, nil, false
+	}
+	return user, survey, true
+}
@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type crossTabData struct {
+	SurveyID int64 `json:"survey_id" binding:"required"`
+	service.CrossTabQuery
+}
+
+// GetCrossTab 交叉分析问卷的答卷
+func GetCrossTab(c *gin.Context) {
+	var data crossTabData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	_, survey, ok := getManagedSurvey(c, data.SurveyID)
+	if !ok {
+		return
+	}
+	if err := service.CheckCrossTab(survey, data.CrossTabQuery); err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	table, err := service.GetCrossTab(survey, data.CrossTabQuery)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, table)
+}
+
+// ExportCrossTab 导出交叉分析结果
+func ExportCrossTab(c *gin.Context) {
+	var data crossTabData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	_, survey, ok := getManagedSurvey(c, data.SurveyID)
+	if !ok {
+		return
+	}
+	if err := service.CheckCrossTab(survey, data.CrossTabQuery); err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	table, err := service.GetCrossTab(survey, data.CrossTabQuery)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	url, err := service.ExportCrossTab(survey, table)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, url)
+}
+
+type timelineData struct {
+	SurveyID int64  `form:"id" binding:"required"`
+	Unit     string `form:"unit" binding:"required,oneof=hour day week"`
+	service.TimeRange
+}
+
+// GetSubmissionTimeline 按时间段统计问卷的答卷数
+func GetSubmissionTimeline(c *gin.Context) {
+	var data timelineData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if err := service.CheckTimeRange(data.TimeRange); err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if _, _, ok := getManagedSurvey(c, data.SurveyID); !ok {
+		return
+	}
+	timeline, err := service.GetSubmissionTimeline(data.SurveyID, data.Unit, data.TimeRange)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, timeline)
+}
+
+type heatmapData struct {
+	SurveyID int64 `form:"id" binding:"required"`
+	service.TimeRange
+}
+
+// GetSubmissionHeatmap 按星期几和小时统计问卷的答卷数
+func GetSubmissionHeatmap(c *gin.Context) {
+	var data heatmapData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if err := service.CheckTimeRange(data.TimeRange); err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if _, _, ok := getManagedSurvey(c, data.SurveyID); !ok {
+		return
+	}
+	heatmap, err := service.GetSubmissionHeatmap(data.SurveyID, data.TimeRange)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, heatmap)
+}
+
+type funnelData struct {
+	SurveyID int64 `form:"id" binding:"required"`
+}
+
+// GetFunnel 统计问卷从获取到提交的访问漏斗
+func GetFunnel(c *gin.Context) {
+	var data funnelData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if _, _, ok := getManagedSurvey(c, data.SurveyID); !ok {
+		return
+	}
+	funnel, err := service.GetFunnel(data.SurveyID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, funnel)
+}
@@ -0,0 +1,266 @@
+package admin
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type bankQuestionData struct {
+	Subject         string              `json:"subject" binding:"required"`
+	Description     string              `json:"description"`
+	Img             string              `json:"img"`
+	QuestionSetting dao.QuestionSetting `json:"ques_setting"`
+	Options         []dao.Option        `json:"options"`
+	Tags            []string            `json:"tags"`
+	Shared          bool                `json:"shared"` // 是否共享给所有管理员
+}
+
+func (data bankQuestionData) toQuestionList() dao.QuestionList {
+	return dao.QuestionList{
+		Subject:         data.Subject,
+		Description:     data.Description,
+		Img:             data.Img,
+		QuestionSetting: data.QuestionSetting,
+		Options:         data.Options,
+	}
+}
+
+// checkBankOptions 检查题库选择题的选项
+func checkBankOptions(c *gin.Context, data bankQuestionData) bool {
+	if data.QuestionSetting.QuestionType != 1 && data.QuestionSetting.QuestionType != 2 {
+		return true
+	}
+	if len(data.Options) < 1 {
+		code.AbortWithException(c, code.SurveyIncomplete, errors.New("题目选项数量太少"))
+		return false
+	}
+	optionMap := make(map[string]bool)
+	for _, option := range data.Options {
+		if option.Content == "" {
+			code.AbortWithException(c, code.SurveyIncomplete,
+				errors.New("选项"+strconv.Itoa(option.SerialNum)+"内容为空"))
+			return false
+		}
+		if optionMap[option.Content] {
+			code.AbortWithException(c, code.SurveyContentRepeat, errors.New("选项内容"+option.Content+"重复"))
+			return false
+		}
+		optionMap[option.Content] = true
+	}
+	if data.QuestionSetting.MaximumOption != 0 &&
+		data.QuestionSetting.MaximumOption < data.QuestionSetting.MinimumOption {
+		code.AbortWithException(c, code.OptionNumError, errors.New("多选最多选项数小于最少选项数"))
+		return false
+	}
+	return true
+}
+
+// resolveBankQuestions 将问题列表中来自题库的题目替换为题库内容
+func resolveBankQuestions(c *gin.Context, user *model.User, questionList []dao.QuestionList) bool {
+	err := service.ResolveBankQuestions(user, questionList)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.BankQuestionNotExist, err)
+		return false
+	} else if errors.Is(err, code.NoPermission) {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权使用该题库题目"))
+		return false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return false
+	}
+	return true
+}
+
+// CreateBankQuestion 创建题库题目
+func CreateBankQuestion(c *gin.Context) {
+	var data bankQuestionData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if !checkBankOptions(c, data) {
+		return
+	}
+	// 创建题库题目
+	id, err := service.CreateBankQuestion(user.ID, data.toQuestionList(), data.Tags, data.Shared)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"id": id})
+}
+
+type updateBankQuestionData struct {
+	ID int `json:"id" binding:"required"`
+	bankQuestionData
+}
+
+// UpdateBankQuestion 修改题库题目
+func UpdateBankQuestion(c *gin.Context) {
+	var data updateBankQuestionData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	// 获取题库题目
+	question, err := service.GetBankQuestionByID(data.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.BankQuestionNotExist, errors.New("题库题目不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 判断权限
+	if !service.CanEditBankQuestion(user, question) {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return
+	}
+	if !checkBankOptions(c, data.bankQuestionData) {
+		return
+	}
+	// 修改题库题目
+	err = service.UpdateBankQuestion(data.ID, data.toQuestionList(), data.Tags, data.Shared)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type bankQuestionIDData struct {
+	ID int `form:"id" binding:"required"`
+}
+
+// DeleteBankQuestion 删除题库题目
+func DeleteBankQuestion(c *gin.Context) {
+	var data bankQuestionIDData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	// 获取题库题目
+	question, err := service.GetBankQuestionByID(data.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.BankQuestionNotExist, errors.New("题库题目不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 判断权限
+	if !service.CanEditBankQuestion(user, question) {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return
+	}
+	// 删除题库题目
+	err = service.DeleteBankQuestion(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// GetBankQuestion 获取题库题目详情
+func GetBankQuestion(c *gin.Context) {
+	var data bankQuestionIDData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	// 获取题库题目
+	detail, err := service.GetBankQuestionDetail(data.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.BankQuestionNotExist, errors.New("题库题目不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 判断权限
+	if !service.CanViewBankQuestion(user, &detail.BankQuestion) {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return
+	}
+	utils.JsonSuccessResponse(c, detail)
+}
+
+type getBankQuestionsData struct {
+	PageNum      int    `form:"page_num" binding:"required"`
+	PageSize     int    `form:"page_size" binding:"required"`
+	Scope        string `form:"scope" binding:"omitempty,oneof=all mine shared"` // 查询范围
+	Keyword      string `form:"keyword"`                                         // 题目关键字
+	Tag          string `form:"tag"`                                             // 标签
+	QuestionType int    `form:"question_type"`                                   // 题目类型
+}
+
+// GetBankQuestions 查询题库题目
+func GetBankQuestions(c *gin.Context) {
+	var data getBankQuestionsData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	// 查询题库题目
+	questions, total, err := service.GetBankQuestions(dao.BankQuestionFilter{
+		UserID:       user.ID,
+		Scope:        data.Scope,
+		Keyword:      data.Keyword,
+		Tag:          data.Tag,
+		QuestionType: data.QuestionType,
+	}, data.PageNum, data.PageSize)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"question_list":  questions,
+		"total_page_num": math.Ceil(float64(total) / float64(data.PageSize)),
+	})
+}
@@ -0,0 +1,193 @@
+package admin
+
+import (
+	"errors"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type createDrawData struct {
+	SurveyID      int64            `json:"survey_id" binding:"required"`
+	Name          string           `json:"name" binding:"required"`
+	Pool          string           `json:"pool" binding:"required,oneof=answer record"`
+	Count         int              `json:"count" binding:"required"`
+	OnePerStudent bool             `json:"one_per_student"`
+	Filter        model.DrawFilter `json:"filter"`
+}
+
+// CreateDraw 创建抽奖，返回的种子摘要应在开奖前公布
+func CreateDraw(c *gin.Context) {
+	var data createDrawData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, survey, ok := getManagedSurvey(c, data.SurveyID)
+	if !ok {
+		return
+	}
+	draw := model.Draw{
+		SurveyID:      survey.ID,
+		UserID:        user.ID,
+		Name:          data.Name,
+		Pool:          data.Pool,
+		Count:         data.Count,
+		OnePerStudent: data.OnePerStudent,
+		Filter:        data.Filter,
+	}
+	if err := service.CheckDraw(survey, draw); err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	draw, err = service.CreateDraw(draw)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, draw)
+}
+
+type runDrawData struct {
+	ID   int    `json:"id" binding:"required"`
+	Salt string `json:"salt"` // 开奖时加入的公开随机数 如彩票开奖号码
+}
+
+// RunDraw 开奖
+func RunDraw(c *gin.Context) {
+	var data runDrawData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	draw, survey, ok := getDraw(c, data.ID)
+	if !ok {
+		return
+	}
+	_, err = service.RunDraw(survey, draw, data.Salt)
+	if errors.Is(err, service.ErrDrawFinished) {
+		code.AbortWithException(c, code.DrawFinished, err)
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	detail, err := service.GetDrawDetail(draw, false)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, detail)
+}
+
+type getDrawsData struct {
+	SurveyID int64 `form:"survey_id" binding:"required"`
+}
+
+// GetDraws 获取问卷的抽奖列表
+func GetDraws(c *gin.Context) {
+	var data getDrawsData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	_, survey, ok := getManagedSurvey(c, data.SurveyID)
+	if !ok {
+		return
+	}
+	draws, err := service.GetDrawsBySurveyID(survey.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"draw_list": draws})
+}
+
+type drawIDData struct {
+	ID int `form:"id" binding:"required"`
+}
+
+// GetDraw 获取抽奖详情
+func GetDraw(c *gin.Context) {
+	var data drawIDData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	draw, _, ok := getDraw(c, data.ID)
+	if !ok {
+		return
+	}
+	detail, err := service.GetDrawDetail(draw, false)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, detail)
+}
+
+// ExportDraw 导出中奖名单
+func ExportDraw(c *gin.Context) {
+	var data drawIDData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	draw, survey, ok := getDraw(c, data.ID)
+	if !ok {
+		return
+	}
+	if draw.Status == 0 {
+		code.AbortWithException(c, code.ParamError, errors.New("抽奖尚未开奖"))
+		return
+	}
+	url, err := service.ExportDraw(survey, draw)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, url)
+}
+
+// DeleteDraw 删除抽奖
+func DeleteDraw(c *gin.Context) {
+	var data drawIDData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	draw, _, ok := getDraw(c, data.ID)
+	if !ok {
+		return
+	}
+	if err := service.DeleteDraw(draw.ID); err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// getDraw 获取抽奖及其所属问卷，并判断管理员权限
+func getDraw(c *gin.Context, id int) (*model.Draw, *model.Survey, bool) {
+	draw, err := service.GetDrawByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.DrawNotExist, errors.New("抽奖不存在"))
+		return nil, nil, false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, nil, false
+	}
+	_, survey, ok := getManagedSurvey(c, draw.SurveyID)
+	return draw, survey, ok
+}
@@ -0,0 +1,231 @@
+package admin
+
+import (
+	"errors"
+	"math"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type optionSetData struct {
+	Name        string              `json:"name" binding:"required"`
+	Description string              `json:"description"`
+	Items       []dao.OptionSetNode `json:"items" binding:"required,dive"` // 条目树
+}
+
+// resolveOptionSets 检查问题列表绑定的选项集
+func resolveOptionSets(c *gin.Context, questionList []dao.QuestionList) bool {
+	err := service.ResolveOptionSets(questionList)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.OptionSetNotExist, err)
+		return false
+	} else if err != nil {
+		code.AbortWithException(c, code.SurveyError, err)
+		return false
+	}
+	return true
+}
+
+// getEditableOptionSet 获取当前管理员可修改的选项集
+func getEditableOptionSet(c *gin.Context, user *model.User, id int) (*model.OptionSet, bool) {
+	set, err := service.GetOptionSetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.OptionSetNotExist, errors.New("选项集不存在"))
+		return nil, false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, false
+	}
+	if user.AdminType != 2 && set.UserID != user.ID {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return nil, false
+	}
+	return set, true
+}
+
+// CreateOptionSet 创建选项集
+func CreateOptionSet(c *gin.Context) {
+	var data optionSetData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if err := service.CheckOptionSetNodes(data.Items); err != nil {
+		code.AbortWithException(c, code.SurveyContentRepeat, err)
+		return
+	}
+	// 创建选项集
+	id, err := service.CreateOptionSet(user.ID, data.Name, data.Description, data.Items)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"id": id})
+}
+
+type updateOptionSetData struct {
+	ID int `json:"id" binding:"required"`
+	optionSetData
+}
+
+// UpdateOptionSet 修改选项集，修改后生成新版本
+func UpdateOptionSet(c *gin.Context) {
+	var data updateOptionSetData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	set, ok := getEditableOptionSet(c, user, data.ID)
+	if !ok {
+		return
+	}
+	if err := service.CheckOptionSetNodes(data.Items); err != nil {
+		code.AbortWithException(c, code.SurveyContentRepeat, err)
+		return
+	}
+	// 修改选项集
+	err = service.UpdateOptionSet(set, data.Name, data.Description, data.Items)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"version": set.Version + 1})
+}
+
+type deleteOptionSetData struct {
+	ID int `form:"id" binding:"required"`
+}
+
+// DeleteOptionSet 删除选项集
+func DeleteOptionSet(c *gin.Context) {
+	var data deleteOptionSetData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if _, ok := getEditableOptionSet(c, user, data.ID); !ok {
+		return
+	}
+	// 判断选项集是否被问题绑定
+	inUse, err := service.IsOptionSetInUse(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if inUse {
+		code.AbortWithException(c, code.OptionSetInUse, errors.New("选项集已被问卷使用"))
+		return
+	}
+	// 删除选项集
+	err = service.DeleteOptionSet(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type getOptionSetData struct {
+	ID      int `form:"id" binding:"required"`
+	Version int `form:"version"` // 版本号 0为最新版本
+}
+
+// GetOptionSet 获取选项集详情
+func GetOptionSet(c *gin.Context) {
+	var data getOptionSetData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	if _, err := service.GetUserSession(c); err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	// 获取选项集
+	set, err := service.GetOptionSetByID(data.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.OptionSetNotExist, errors.New("选项集不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if data.Version < 0 || data.Version > set.Version {
+		code.AbortWithException(c, code.ParamError, errors.New("选项集版本不存在"))
+		return
+	}
+	items, err := service.GetOptionSetTree(set, data.Version)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	version := data.Version
+	if version == 0 {
+		version = set.Version
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"option_set": set,
+		"version":    version,
+		"items":      items,
+	})
+}
+
+type getOptionSetsData struct {
+	PageNum  int    `form:"page_num" binding:"required"`
+	PageSize int    `form:"page_size" binding:"required"`
+	Keyword  string `form:"keyword"`
+}
+
+// GetOptionSets 查询选项集列表
+func GetOptionSets(c *gin.Context) {
+	var data getOptionSetsData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	if _, err := service.GetUserSession(c); err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	sets, total, err := service.GetOptionSets(data.Keyword, data.PageNum, data.PageSize)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"option_set_list": sets,
+		"total_page_num":  math.Ceil(float64(total) / float64(data.PageSize)),
+	})
+}
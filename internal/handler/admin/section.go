@@ -0,0 +1,51 @@
+package admin
+
+import (
+	"errors"
+	"strconv"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/pkg/code"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkSections 检查问卷分页设置，题目需按分页顺序排列，题目序号跨分页连续
+func checkSections(c *gin.Context, config dao.QuestionConfig, publish bool) bool {
+	for i, section := range config.Sections {
+		if section.SerialNum != i+1 {
+			code.AbortWithException(c, code.SurveyError, errors.New("分页序号不按顺序递增"))
+			return false
+		}
+	}
+	questionCount := make(map[int]int)
+	for i, question := range config.QuestionList {
+		if question.Section < 0 || question.Section > len(config.Sections) ||
+			(len(config.Sections) > 0 && question.Section == 0) {
+			code.AbortWithException(c, code.SurveyError,
+				errors.New("问题"+strconv.Itoa(question.SerialNum)+"所属分页不存在"))
+			return false
+		}
+		// 同一分页的题目必须相邻，保证题目序号在分页之间连续
+		if i > 0 && question.Section < config.QuestionList[i-1].Section {
+			code.AbortWithException(c, code.SurveyError,
+				errors.New("问题"+strconv.Itoa(question.SerialNum)+"不在所属分页的题目范围内"))
+			return false
+		}
+		if i == 0 && len(config.Sections) > 0 && question.SerialNum != 1 {
+			code.AbortWithException(c, code.SurveyError, errors.New("题目序号需从1开始"))
+			return false
+		}
+		questionCount[question.Section]++
+	}
+	if publish {
+		for _, section := range config.Sections {
+			if questionCount[section.SerialNum] == 0 {
+				code.AbortWithException(c, code.SurveyIncomplete,
+					errors.New("分页"+strconv.Itoa(section.SerialNum)+"没有问题"))
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,266 @@
+package admin
+
+import (
+	"errors"
+	"math"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type studyData struct {
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	Waves       []model.StudyWave `json:"waves" binding:"required,min=1,dive"` // 轮次 按先后顺序排列
+	Items       []model.StudyItem `json:"items" binding:"dive"`                // 跨轮次对应的题目
+}
+
+// getEditableStudy 获取当前管理员可修改的研究，超级管理员和研究创建者有权限
+func getEditableStudy(c *gin.Context, user *model.User, id int) (*model.Study, bool) {
+	study, err := service.GetStudyByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.StudyNotExist, errors.New("研究不存在"))
+		return nil, false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, false
+	}
+	if user.AdminType != 2 && study.UserID != user.ID {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return nil, false
+	}
+	return study, true
+}
+
+// checkStudyData 检查研究的轮次和对应题目，当前管理员需有权限管理每个轮次的问卷
+func checkStudyData(c *gin.Context, data studyData) bool {
+	for _, wave := range data.Waves {
+		if _, _, ok := getManagedSurvey(c, wave.SurveyID); !ok {
+			return false
+		}
+	}
+	if err := service.CheckStudy(data.Waves, data.Items); err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return false
+	}
+	return true
+}
+
+// checkStudyAccess 检查当前管理员是否仍有权限管理研究每个轮次的问卷，查看答卷结果前调用
+func checkStudyAccess(c *gin.Context, study *model.Study) bool {
+	waves, err := service.GetStudyWaves(study.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return false
+	}
+	for _, wave := range waves {
+		if _, _, ok := getManagedSurvey(c, wave.SurveyID); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateStudy 创建纵向研究
+func CreateStudy(c *gin.Context) {
+	var data studyData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if !checkStudyData(c, data) {
+		return
+	}
+	id, err := service.CreateStudy(user.ID, data.Name, data.Description, data.Waves, data.Items)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"id": id})
+}
+
+type updateStudyData struct {
+	ID int `json:"id" binding:"required"`
+	studyData
+}
+
+// UpdateStudy 修改纵向研究，轮次和对应题目整体替换
+func UpdateStudy(c *gin.Context) {
+	var data updateStudyData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if _, ok := getEditableStudy(c, user, data.ID); !ok {
+		return
+	}
+	if !checkStudyData(c, data.studyData) {
+		return
+	}
+	err = service.UpdateStudy(data.ID, data.Name, data.Description, data.Waves, data.Items)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type studyIDData struct {
+	ID int `form:"id" binding:"required"`
+}
+
+// DeleteStudy 删除纵向研究
+func DeleteStudy(c *gin.Context) {
+	var data studyIDData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if _, ok := getEditableStudy(c, user, data.ID); !ok {
+		return
+	}
+	err = service.DeleteStudy(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// GetStudy 获取纵向研究详情
+func GetStudy(c *gin.Context) {
+	var data studyIDData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	study, ok := getEditableStudy(c, user, data.ID)
+	if !ok {
+		return
+	}
+	waves, err := service.GetStudyWaves(study.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"study": study, "waves": waves})
+}
+
+type getStudiesData struct {
+	PageNum  int `form:"page_num" binding:"required"`
+	PageSize int `form:"page_size" binding:"required"`
+}
+
+// GetStudies 查询当前管理员创建的纵向研究，超级管理员查询全部
+func GetStudies(c *gin.Context) {
+	var data getStudiesData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	uid := user.ID
+	if user.AdminType == 2 {
+		uid = 0
+	}
+	studies, total, err := service.GetStudies(uid, data.PageNum, data.PageSize)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"study_list":     studies,
+		"total_page_num": math.Ceil(float64(total) / float64(data.PageSize)),
+	})
+}
+
+// GetStudyTrend 获取纵向研究各题目在各轮次的选项占比及变化的显著性
+func GetStudyTrend(c *gin.Context) {
+	var data studyIDData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	study, ok := getEditableStudy(c, user, data.ID)
+	if !ok || !checkStudyAccess(c, study) {
+		return
+	}
+	trend, err := service.GetStudyTrend(study)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, trend)
+}
+
+// ExportStudy 导出纵向研究的趋势和合并了各轮次的答卷
+func ExportStudy(c *gin.Context) {
+	var data studyIDData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	study, ok := getEditableStudy(c, user, data.ID)
+	if !ok || !checkStudyAccess(c, study) {
+		return
+	}
+	url, err := service.ExportStudy(study)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, url)
+}
@@ -9,12 +9,14 @@ import (
 	"QA-System/internal/dao"
 	"QA-System/internal/model"
 	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/i18n"
 	"QA-System/internal/pkg/utils"
 	"QA-System/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -25,6 +27,101 @@ type createSurveyData struct {
 	QuestionConfig dao.QuestionConfig `json:"ques_config"` // 问题设置
 }
 
+// checkSurveyConfig 检查问卷的基本配置和问题设置，创建、修改问卷和使用模板创建问卷时共用，publish 为问卷是否已发布
+func checkSurveyConfig(c *gin.Context, surveyType uint, base dao.BaseConfig, config dao.QuestionConfig,
+	publish bool) bool {
+	// 检查绑定的选项集
+	if !resolveOptionSets(c, config.QuestionList) {
+		return false
+	}
+	// 检查总投票次数大于日投票数
+	if base.SumLimit != 0 && base.DailyLimit != 0 && base.SumLimit < base.DailyLimit {
+		code.AbortWithException(c, code.SurveyError, errors.New("总投票次数小于单日投票次数"))
+		return false
+	}
+	// 检查问卷每个题目的序号没有重复且按照顺序递增
+	questionNumMap := make(map[int]bool)
+	for i, question := range config.QuestionList {
+		if surveyType == 2 && (question.QuestionSetting.QuestionType != 2 && !question.QuestionSetting.Required) {
+			code.AbortWithException(c, code.SurveyError, errors.New("投票题目只能为多选必填题"))
+			return false
+		}
+		if questionNumMap[question.SerialNum] {
+			code.AbortWithException(c, code.SurveyError, errors.New("题目序号"+strconv.Itoa(question.SerialNum)+"重复"))
+			return false
+		}
+		if i > 0 && question.SerialNum != config.QuestionList[i-1].SerialNum+1 {
+			code.AbortWithException(c, code.SurveyError, errors.New("题目序号不按顺序递增"))
+			return false
+		}
+		questionNumMap[question.SerialNum] = true
+		question.SerialNum = i + 1
+
+		// 检测多选题目的最多选项数和最少选项数
+		if ((question.QuestionSetting.QuestionType == 2 && surveyType == 0) ||
+			(question.QuestionSetting.QuestionType == 1 && surveyType == 1)) &&
+			(question.QuestionSetting.MaximumOption < question.QuestionSetting.MinimumOption) {
+			code.AbortWithException(c, code.OptionNumError, errors.New("多选最多选项数小于最少选项数"))
+			return false
+		}
+		// 检查多选选项和最少选项数是否符合要求
+		if ((question.QuestionSetting.QuestionType == 2 && surveyType == 0) ||
+			(question.QuestionSetting.QuestionType == 1 && surveyType == 1)) &&
+			uint(len(question.Options)) < question.QuestionSetting.MinimumOption {
+			code.AbortWithException(c, code.OptionNumError, errors.New("选项数量小于最少选项数"))
+			return false
+		}
+		// 检查最多选项数是否符合要求
+		if ((question.QuestionSetting.QuestionType == 2 && surveyType == 0) ||
+			(question.QuestionSetting.QuestionType == 1 && surveyType == 1)) &&
+			question.QuestionSetting.MaximumOption == 0 {
+			code.AbortWithException(c, code.OptionNumError, errors.New("最多选项数小于等于0"))
+			return false
+		}
+	}
+	// 检查隐藏字段
+	if err := service.CheckHiddenFields(base.HiddenFields); err != nil {
+		code.AbortWithException(c, code.SurveyError, err)
+		return false
+	}
+	// 检查完成页和主题
+	if err := service.CheckCompletion(base, config.QuestionList); err != nil {
+		code.AbortWithException(c, code.SurveyError, err)
+		return false
+	}
+	if err := service.CheckTheme(base.Theme); err != nil {
+		code.AbortWithException(c, code.SurveyError, err)
+		return false
+	}
+	// 检查投票结果公开方式
+	if err := service.CheckResultPolicy(base); err != nil {
+		code.AbortWithException(c, code.SurveyError, err)
+		return false
+	}
+	// 检查匿名投票设置
+	if err := service.CheckAnonymous(base); err != nil {
+		code.AbortWithException(c, code.SurveyError, err)
+		return false
+	}
+	// 检查排序题和评分题的计票设置
+	if err := service.CheckBallotSettings(config.QuestionList); err != nil {
+		code.AbortWithException(c, code.SurveyError, err)
+		return false
+	}
+	// 检查问卷原文语言
+	if base.DefaultLang != "" && !i18n.ValidTag(base.DefaultLang) {
+		code.AbortWithException(c, code.SurveyError, errors.New("问卷语言标签不合法"))
+		return false
+	}
+	// 检查答案引用和计算题公式
+	if err := service.CheckQuestionReferences(config.QuestionList); err != nil {
+		code.AbortWithException(c, code.SurveyError, err)
+		return false
+	}
+	// 检查问卷分页设置
+	return checkSections(c, config, publish)
+}
+
 // CreateSurvey 创建问卷
 func CreateSurvey(c *gin.Context) {
 	var data createSurveyData
@@ -39,6 +136,10 @@ func CreateSurvey(c *gin.Context) {
 		code.AbortWithException(c, code.NotLogin, err)
 		return
 	}
+	// 填充来自题库的题目
+	if !resolveBankQuestions(c, user, data.QuestionConfig.QuestionList) {
+		return
+	}
 	// 解析时间转换为中国时间(UTC+8)
 	ddlTime, err := time.Parse(time.RFC3339, data.BaseConfig.EndTime)
 	if err != nil {
@@ -54,52 +155,10 @@ func CreateSurvey(c *gin.Context) {
 		code.AbortWithException(c, code.SurveyError, errors.New("开始时间晚于截止时间"))
 		return
 	}
-	// 检查总投票次数大于日投票数
-	if data.BaseConfig.SumLimit != 0 && data.BaseConfig.DailyLimit != 0 &&
-		data.BaseConfig.SumLimit < data.BaseConfig.DailyLimit {
-		code.AbortWithException(c, code.SurveyError, errors.New("总投票次数小于单日投票次数"))
+	// 检查问卷设置
+	if !checkSurveyConfig(c, data.SurveyType, data.BaseConfig, data.QuestionConfig, data.Status == 2) {
 		return
 	}
-	// 检查问卷每个题目的序号没有重复且按照顺序递增
-	questionNumMap := make(map[int]bool)
-	for i, question := range data.QuestionConfig.QuestionList {
-		if data.SurveyType == 2 && (question.QuestionSetting.QuestionType != 2 && !question.QuestionSetting.Required) {
-			code.AbortWithException(c, code.SurveyError, errors.New("投票题目只能为多选必填题"))
-			return
-		}
-		if questionNumMap[question.SerialNum] {
-			code.AbortWithException(c, code.SurveyError, errors.New("题目序号"+strconv.Itoa(question.SerialNum)+"重复"))
-			return
-		}
-		if i > 0 && question.SerialNum != data.QuestionConfig.QuestionList[i-1].SerialNum+1 {
-			code.AbortWithException(c, code.SurveyError, errors.New("题目序号不按顺序递增"))
-			return
-		}
-		questionNumMap[question.SerialNum] = true
-		question.SerialNum = i + 1
-
-		// 检测多选题目的最多选项数和最少选项数
-		if ((question.QuestionSetting.QuestionType == 2 && data.SurveyType == 0) ||
-			(question.QuestionSetting.QuestionType == 1 && data.SurveyType == 1)) &&
-			(question.QuestionSetting.MaximumOption < question.QuestionSetting.MinimumOption) {
-			code.AbortWithException(c, code.OptionNumError, errors.New("多选最多选项数小于最少选项数"))
-			return
-		}
-		// 检查多选选项和最少选项数是否符合要求
-		if ((question.QuestionSetting.QuestionType == 2 && data.SurveyType == 0) ||
-			(question.QuestionSetting.QuestionType == 1 && data.SurveyType == 1)) &&
-			uint(len(question.Options)) < question.QuestionSetting.MinimumOption {
-			code.AbortWithException(c, code.OptionNumError, errors.New("选项数量小于最少选项数"))
-			return
-		}
-		// 检查最多选项数是否符合要求
-		if ((question.QuestionSetting.QuestionType == 2 && data.SurveyType == 0) ||
-			(question.QuestionSetting.QuestionType == 1 && data.SurveyType == 1)) &&
-			question.QuestionSetting.MaximumOption == 0 {
-			code.AbortWithException(c, code.OptionNumError, errors.New("最多选项数小于等于0"))
-			return
-		}
-	}
 	// 检测问卷是否填写完整
 	if data.Status == 2 {
 		if data.QuestionConfig.Title == "" || len(data.QuestionConfig.QuestionList) == 0 {
@@ -143,9 +202,8 @@ func CreateSurvey(c *gin.Context) {
 		}
 	}
 	// 创建问卷
-	err = service.CreateSurvey(user.ID, data.QuestionConfig.QuestionList, data.Status, data.SurveyType, data.BaseConfig.
-		DailyLimit, data.BaseConfig.SumLimit, data.BaseConfig.Verify, data.BaseConfig.UndergradOnly, ddlTime, startTime,
-		data.QuestionConfig.Title, data.QuestionConfig.Desc, data.BaseConfig.NeedNotify)
+	_, err = service.CreateSurvey(user.ID, data.Status, data.SurveyType, data.BaseConfig, data.QuestionConfig,
+		ddlTime, startTime)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
@@ -217,7 +275,7 @@ func UpdateSurveyStatus(c *gin.Context) {
 			}
 			questionMap[question.Subject] = true
 			if question.QuestionType == 1 || question.QuestionType == 2 {
-				options, err := service.GetOptionsByQuestionID(question.ID)
+				options, err := service.GetQuestionOptions(question)
 				if err != nil {
 					code.AbortWithException(c, code.ServerError, err)
 					return
@@ -243,6 +301,23 @@ func UpdateSurveyStatus(c *gin.Context) {
 				}
 			}
 		}
+		// 检查每个分页都有问题
+		sections, err := service.GetSectionsBySurveyID(survey.ID)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		sectionQuestionMap := make(map[int]bool)
+		for _, question := range questions {
+			sectionQuestionMap[question.SectionID] = true
+		}
+		for _, section := range sections {
+			if !sectionQuestionMap[section.ID] {
+				code.AbortWithException(c, code.SurveyIncomplete,
+					errors.New("分页"+strconv.Itoa(section.SerialNum)+"没有问题"))
+				return
+			}
+		}
 	}
 	// 修改问卷状态
 	err = service.UpdateSurveyStatus(data.ID, data.Status)
@@ -286,6 +361,10 @@ func UpdateSurvey(c *gin.Context) {
 		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
 		return
 	}
+	// 填充来自题库的题目
+	if !resolveBankQuestions(c, user, data.QuestionConfig.QuestionList) {
+		return
+	}
 	// 判断问卷状态
 	if user.AdminType != 2 {
 		if survey.Status != 1 {
@@ -313,51 +392,12 @@ func UpdateSurvey(c *gin.Context) {
 		code.AbortWithException(c, code.SurveyError, errors.New("开始时间晚于截止时间"))
 		return
 	}
-	if data.BaseConfig.SumLimit != 0 && data.BaseConfig.DailyLimit != 0 &&
-		data.BaseConfig.SumLimit < data.BaseConfig.DailyLimit {
-		code.AbortWithException(c, code.SurveyError, errors.New("总投票次数小于单日投票次数"))
+	// 检查问卷设置
+	if !checkSurveyConfig(c, data.SurveyType, data.BaseConfig, data.QuestionConfig, survey.Status == 2) {
 		return
 	}
-	// 检查问卷每个题目的序号没有重复且按照顺序递增
-	questionNumMap := make(map[int]bool)
-	for i, question := range data.QuestionConfig.QuestionList {
-		if questionNumMap[question.SerialNum] {
-			code.AbortWithException(c, code.SurveyError, errors.New("题目序号"+strconv.Itoa(question.SerialNum)+"重复"))
-			return
-		}
-		if i > 0 && question.SerialNum != data.QuestionConfig.QuestionList[i-1].SerialNum+1 {
-			code.AbortWithException(c, code.SurveyError, errors.New("题目序号不按顺序递增"))
-			return
-		}
-		questionNumMap[question.SerialNum] = true
-		question.SerialNum = i + 1
-
-		// 检测多选题目的最多选项数和最少选项数
-		if ((question.QuestionSetting.QuestionType == 2 && data.SurveyType == 0) ||
-			(question.QuestionSetting.QuestionType == 1 && data.SurveyType == 1)) &&
-			(question.QuestionSetting.MaximumOption < question.QuestionSetting.MinimumOption) {
-			code.AbortWithException(c, code.OptionNumError, errors.New("多选最多选项数小于最少选项数"))
-			return
-		}
-		// 检查多选选项和最少选项数是否符合要求
-		if ((question.QuestionSetting.QuestionType == 2 && data.SurveyType == 0) ||
-			(question.QuestionSetting.QuestionType == 1 && data.SurveyType == 1)) &&
-			uint(len(question.Options)) < question.QuestionSetting.MinimumOption {
-			code.AbortWithException(c, code.OptionNumError, errors.New("选项数量小于最少选项数"))
-			return
-		}
-		// 检查最多选项数是否符合要求
-		if ((question.QuestionSetting.QuestionType == 2 && data.SurveyType == 0) ||
-			(question.QuestionSetting.QuestionType == 1 && data.SurveyType == 1)) &&
-			question.QuestionSetting.MaximumOption == 0 {
-			code.AbortWithException(c, code.OptionNumError, errors.New("最多选项数小于等于0"))
-			return
-		}
-	}
 	// 修改问卷
-	err = service.UpdateSurvey(data.ID, data.QuestionConfig.QuestionList, data.SurveyType, data.BaseConfig.DailyLimit,
-		data.BaseConfig.SumLimit, data.BaseConfig.Verify, data.BaseConfig.UndergradOnly, data.QuestionConfig.Desc,
-		data.QuestionConfig.Title, ddlTime, startTime, data.BaseConfig.NeedNotify)
+	err = service.UpdateSurvey(data.ID, data.SurveyType, data.BaseConfig, data.QuestionConfig, ddlTime, startTime)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
@@ -409,6 +449,11 @@ func DeleteSurvey(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	err = service.DeleteBufferedBallots(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
 	utils.JsonSuccessResponse(c, nil)
 }
 
@@ -449,9 +494,15 @@ func GetSurveyAnswers(c *gin.Context) {
 		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
 		return
 	}
+	// 按隐藏字段筛选
+	hidden, ok := getHiddenFilter(c, survey)
+	if !ok {
+		return
+	}
 	// 获取问卷收集数据
 	var num *int64
-	answers, num, err := service.GetSurveyAnswers(data.ID, data.PageNum, data.PageSize, data.Text, data.Unique)
+	answers, num, err := service.GetSurveyAnswers(data.ID, data.PageNum, data.PageSize, data.Text, data.Unique,
+		hidden)
 	if err != nil {
 		if err.Error() == "页数超出范围" {
 			code.AbortWithException(c, code.PageBeyondError, err)
@@ -560,10 +611,28 @@ func GetSurvey(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	// 获取问卷分页
+	sections, err := service.GetSectionsBySurveyID(survey.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	sectionSerialMap := service.GetSectionSerialMap(sections)
+	// 获取问卷支持的语言
+	languages, err := service.GetSurveyLanguages(survey)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
 	// 构建问卷响应
 	questionListsResponse := make([]map[string]any, 0)
 	for _, question := range questions {
-		options, err := service.GetOptionsByQuestionID(question.ID)
+		options, err := service.GetQuestionOptions(question)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		cascade, err := service.GetQuestionCascade(question)
 		if err != nil {
 			code.AbortWithException(c, code.ServerError, err)
 			return
@@ -576,18 +645,28 @@ func GetSurvey(c *gin.Context) {
 				"content":     option.Content,
 				"img":         option.Img,
 				"description": option.Description,
+				"pinned":      option.Pinned,
 			}
 			optionsResponse = append(optionsResponse, optionResponse)
 		}
 
 		questionSettingResponse := map[string]any{
-			"required":       question.Required,
-			"unique":         question.Unique,
-			"other_option":   question.OtherOption,
-			"question_type":  question.QuestionType,
-			"reg":            question.Reg,
-			"maximum_option": question.MaximumOption,
-			"minimum_option": question.MinimumOption,
+			"required":           question.Required,
+			"unique":             question.Unique,
+			"other_option":       question.OtherOption,
+			"question_type":      question.QuestionType,
+			"reg":                question.Reg,
+			"maximum_option":     question.MaximumOption,
+			"minimum_option":     question.MinimumOption,
+			"option_set_id":      question.OptionSetID,
+			"option_set_version": question.OptionSetVersion,
+			"shuffle_options":    question.ShuffleOptions,
+			"pinned":             question.Pinned,
+			"expression":         question.Expression,
+			"tally_method":       question.TallyMethod,
+			"tie_break":          question.TieBreak,
+			"max_score":          question.MaxScore,
+			"numeric":            question.Numeric,
 		}
 
 		questionListMap := map[string]any{
@@ -598,6 +677,10 @@ func GetSurvey(c *gin.Context) {
 			"img":          question.Img,
 			"ques_setting": questionSettingResponse,
 			"options":      optionsResponse,
+			"cascade":      cascade,
+			"bank_id":      question.BankQuestionID,
+			"bank_ref":     question.BankQuestionID != 0,
+			"section":      sectionSerialMap[question.SectionID],
 		}
 		questionListsResponse = append(questionListsResponse, questionListMap)
 	}
@@ -606,15 +689,24 @@ func GetSurvey(c *gin.Context) {
 		"title":         survey.Title,
 		"desc":          survey.Desc,
 		"question_list": questionListsResponse,
+		"sections":      sections,
 	}
 	baseConfigResponse := map[string]any{
-		"start_time":     survey.StartTime,
-		"end_time":       survey.Deadline,
-		"day_limit":      survey.DailyLimit,
-		"sum_limit":      survey.SumLimit,
-		"verify":         survey.Verify,
-		"undergrad_only": survey.UndergradOnly,
-		"need_notify":    survey.NeedNotify,
+		"start_time":        survey.StartTime,
+		"end_time":          survey.Deadline,
+		"day_limit":         survey.DailyLimit,
+		"sum_limit":         survey.SumLimit,
+		"verify":            survey.Verify,
+		"undergrad_only":    survey.UndergradOnly,
+		"need_notify":       survey.NeedNotify,
+		"shuffle_questions": survey.ShuffleQuestions,
+		"hidden_fields":     survey.HiddenFields,
+		"default_lang":      service.GetSurveyDefaultLang(survey),
+		"languages":         languages,
+		"completion":        survey.Completion,
+		"theme":             survey.Theme,
+		"result_policy":     survey.ResultPolicy,
+		"anonymous":         survey.Anonymous,
 	}
 	response := map[string]any{
 		"id":          survey.ID,
@@ -671,12 +763,13 @@ func DownloadFile(c *gin.Context) {
 }
 
 type getSurveyStatisticsData struct {
-	ID       int64 `form:"id" binding:"required"`
-	PageNum  int   `form:"page_num" binding:"required"`
-	PageSize int   `form:"page_size" binding:"required"`
+	ID        int64  `form:"id" binding:"required"`
+	PageNum   int    `form:"page_num" binding:"required"`
+	PageSize  int    `form:"page_size" binding:"required"`
+	Dimension string `form:"dimension"` // 按隐藏字段分组统计
 }
 
-// GetSurveyStatistics 获取统计问卷选择题数据
+// GetSurveyStatistics 获取统计问卷选择题数据，以及填空题和简答题的答案统计
 func GetSurveyStatistics(c *gin.Context) {
 	var data getSurveyStatisticsData
 	if err := c.ShouldBindQuery(&data); err != nil {
@@ -702,19 +795,51 @@ func GetSurveyStatistics(c *gin.Context) {
 		return
 	}
 
-	answersheets, err := service.GetSurveyAnswersBySurveyID(data.ID)
+	hidden, ok := getHiddenFilter(c, survey)
+	if !ok {
+		return
+	}
+	if data.Dimension != "" && !service.IsHiddenField(survey, data.Dimension) {
+		code.AbortWithException(c, code.ParamError, errors.New("隐藏字段"+data.Dimension+"不存在"))
+		return
+	}
+
+	questions, err := service.GetQuestionsBySurveyID(data.ID)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
 
-	questions, err := service.GetQuestionsBySurveyID(data.ID)
+	groups, err := service.AggregateQuestionStats(data.ID, questions, hidden, data.Dimension)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	total := 0
+	for _, group := range groups {
+		total += group.Total
+	}
+
+	// 按隐藏字段的值分组统计
+	if data.Dimension != "" {
+		utils.JsonSuccessResponse(c, gin.H{
+			"dimension":   data.Dimension,
+			"groups":      groups,
+			"total":       total,
+			"survey_type": survey.Type,
+		})
+		return
+	}
 
-	response := service.GenerateQuestionStats(questions, answersheets)
+	response := make([]service.GetChooseStatisticsResponse, 0)
+	if len(groups) > 0 {
+		response = groups[0].Statistics
+	}
+	weighting, err := service.ApplyWeighting(survey, questions, hidden, response)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
 	start := (data.PageNum - 1) * data.PageSize
 	end := start + data.PageSize
 	// 确保 start 和 end 在有效范围内
@@ -732,12 +857,21 @@ func GetSurveyStatistics(c *gin.Context) {
 	resp := response[start:end]
 	totalSumPage := math.Ceil(float64(len(response)) / float64(data.PageSize))
 
-	utils.JsonSuccessResponse(c, gin.H{
+	result := gin.H{
 		"statistics":     resp,
-		"total":          len(answersheets),
+		"weighting":      weighting,
+		"total":          total,
 		"total_sum_page": totalSumPage,
 		"survey_type":    survey.Type,
-	})
+	}
+	// 填空题和简答题的统计失败时不影响选择题的统计结果
+	analytics, err := service.GetAnswerAnalytics(data.ID, questions, hidden)
+	if err != nil {
+		zap.L().Error("统计填空题和简答题失败", zap.Int64("survey_id", data.ID), zap.Error(err))
+	} else {
+		result["answer_analytics"] = analytics
+	}
+	utils.JsonSuccessResponse(c, result)
 }
 
 type getQuestionPreData struct {
@@ -831,8 +965,8 @@ func DeleteAnswerSheet(c *gin.Context) {
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 	}
-	// 获取问卷
-	err = service.GetAnswerSheetByAnswerID(objectID)
+	// 获取答卷
+	answerSheet, err := service.GetAnswerSheetByAnswerID(objectID)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		code.AbortWithException(c, code.AnswerSheetNotExist, errors.New("答卷不存在"))
 		return
@@ -846,7 +980,7 @@ func DeleteAnswerSheet(c *gin.Context) {
 		return
 	}
 	// 删除答卷
-	err = service.DeleteAnswerSheetByAnswerID(objectID)
+	err = service.DeleteAnswerSheetByAnswerID(answerSheet, user.ID)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
@@ -883,8 +1017,12 @@ func DownloadChooseFile(c *gin.Context) {
 		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
 		return
 	}
+	hidden, ok := getHiddenFilter(c, survey)
+	if !ok {
+		return
+	}
 	// 获取数据
-	answers, err := service.GetSurveyAnswersBySurveyID(data.ID)
+	answers, err := service.GetSurveyAnswersBySurveyID(data.ID, hidden)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
@@ -894,11 +1032,185 @@ func DownloadChooseFile(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-	stats := service.GenerateQuestionStats(questions, answers)
-	url, err := service.HandleChooseStatistics(survey, stats)
+	groups, err := service.AggregateQuestionStats(data.ID, questions, hidden, "")
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	stats := make([]service.GetChooseStatisticsResponse, 0)
+	if len(groups) > 0 {
+		stats = groups[0].Statistics
+	}
+	weighting, err := service.ApplyWeighting(survey, questions, hidden, stats)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 排序题等计票需要逐份答卷
+	tallies, err := service.GetSurveyTallies(survey, answers)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	url, err := service.HandleChooseStatistics(survey, stats, tallies, weighting)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
 	utils.JsonSuccessResponse(c, url)
 }
+
+type getTallyData struct {
+	ID int64 `form:"id" binding:"required"`
+}
+
+// GetSurveyTally 获取问卷各题的计票结果
+func GetSurveyTally(c *gin.Context) {
+	var data getTallyData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	// 获取问卷
+	survey, err := service.GetSurveyByID(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 判断权限
+	if (user.AdminType != 2) && (user.AdminType != 1 || survey.UserID != user.ID) &&
+		!service.UserInManage(user.ID, survey.ID) {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return
+	}
+	hidden, ok := getHiddenFilter(c, survey)
+	if !ok {
+		return
+	}
+	answers, err := service.GetSurveyAnswersBySurveyID(data.ID, hidden)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	tallies, err := service.GetSurveyTallies(survey, answers)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, tallies)
+}
+
+type streamStatisticsData struct {
+	ID int64 `form:"id" binding:"required"`
+}
+
+// StreamSurveyStatistics 以 Server-Sent Events 实时推送投票统计，不受问卷的结果公开设置限制
+func StreamSurveyStatistics(c *gin.Context) {
+	var data streamStatisticsData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	_, survey, ok := getManagedSurvey(c, data.ID)
+	if !ok {
+		return
+	}
+	if survey.Type != 1 {
+		code.AbortWithException(c, code.SurveyTypeError, errors.New("问卷为调研问卷"))
+		return
+	}
+	visible := func(*model.Survey) bool { return true }
+	service.StreamVoteStats(c, survey.ID, visible, func(_ *model.Survey, stats []service.VoteStatistics) any {
+		return gin.H{"statistics": stats}
+	})
+}
+
+type streamAnswersData struct {
+	ID      int64  `form:"id" binding:"required"`
+	LastSeq string `form:"last_seq"` // 最后收到的事件ID，断线续传时使用
+}
+
+// StreamAnswerSheets 以 Server-Sent Events 推送问卷的新答卷
+//
+// 未指定 last_seq 时使用浏览器重连时携带的 Last-Event-ID。
+func StreamAnswerSheets(c *gin.Context) {
+	var data streamAnswersData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	_, survey, ok := getManagedSurvey(c, data.ID)
+	if !ok {
+		return
+	}
+	lastSeq := data.LastSeq
+	if lastSeq == "" {
+		lastSeq = c.GetHeader("Last-Event-ID")
+	}
+	var after int64
+	if lastSeq != "" {
+		after, err = strconv.ParseInt(lastSeq, 10, 64)
+		if err != nil {
+			code.AbortWithException(c, code.ParamError, err)
+			return
+		}
+	}
+	service.StreamAnswerSheets(c, survey.ID, after)
+}
+
+type verifyLedgerData struct {
+	ID int64 `form:"id" binding:"required"`
+}
+
+// VerifyLedger 校验问卷答卷的哈希链
+func VerifyLedger(c *gin.Context) {
+	var data verifyLedgerData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	// 获取问卷
+	survey, err := service.GetSurveyByID(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 判断权限
+	if (user.AdminType != 2) && (user.AdminType != 1 || survey.UserID != user.ID) &&
+		!service.UserInManage(user.ID, survey.ID) {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return
+	}
+	report, err := service.VerifyLedger(survey.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, report)
+}
+
+// getHiddenFilter 获取请求中 hidden[字段名]=值 形式的隐藏字段筛选条件
+func getHiddenFilter(c *gin.Context, survey *model.Survey) (map[string]string, bool) {
+	hidden := c.QueryMap("hidden")
+	for name := range hidden {
+		if !service.IsHiddenField(survey, name) {
+			code.AbortWithException(c, code.ParamError, errors.New("隐藏字段"+name+"不存在"))
+			return nil, false
+		}
+	}
+	return hidden, true
+}
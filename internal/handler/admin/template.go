@@ -0,0 +1,200 @@
+package admin
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type getTemplatesData struct {
+	Category string `form:"category"` // 模板分类 为空时获取全部
+}
+
+// GetTemplates 获取模板列表
+func GetTemplates(c *gin.Context) {
+	var data getTemplatesData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	if _, err := service.GetUserSession(c); err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	templates, err := service.GetTemplates(data.Category)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"template_list": templates})
+}
+
+type templateKeyData struct {
+	Key string `form:"key" binding:"required"`
+}
+
+// GetTemplate 获取模板详情
+func GetTemplate(c *gin.Context) {
+	var data templateKeyData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	if _, err := service.GetUserSession(c); err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	detail, err := service.GetTemplateDetail(data.Key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.TemplateNotExist, errors.New("模板不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, detail)
+}
+
+type promoteTemplateData struct {
+	SurveyID    int64  `json:"survey_id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+}
+
+// PromoteTemplate 将已有问卷提升为组织模板
+func PromoteTemplate(c *gin.Context) {
+	var data promoteTemplateData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if user.AdminType != 2 {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return
+	}
+	// 获取问卷
+	survey, err := service.GetSurveyByID(data.SurveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	id, err := service.PromoteTemplate(user.ID, survey, data.Name, data.Description, data.Category)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"key": strconv.Itoa(id)})
+}
+
+// DeleteTemplate 删除组织模板
+func DeleteTemplate(c *gin.Context) {
+	var data templateKeyData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if user.AdminType != 2 {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return
+	}
+	detail, err := service.GetTemplateDetail(data.Key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.TemplateNotExist, errors.New("模板不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 内置模板随程序发布，不允许删除
+	if detail.Builtin {
+		code.AbortWithException(c, code.NoPermission, errors.New("内置模板不允许删除"))
+		return
+	}
+	id, err := strconv.Atoi(detail.Key)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	err = service.DeleteTemplate(id)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type useTemplateData struct {
+	Key   string `json:"key" binding:"required"`
+	Title string `json:"title"` // 新问卷标题 为空时使用模板标题
+}
+
+// CreateSurveyFromTemplate 使用模板创建未发布的问卷
+func CreateSurveyFromTemplate(c *gin.Context) {
+	var data useTemplateData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	detail, err := service.GetTemplateDetail(data.Key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.TemplateNotExist, errors.New("模板不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	config := detail.Content.QuestionConfig
+	// 与创建问卷相同的检查，组织模板来自已有问卷，可能不满足新增的检查
+	if !checkSurveyConfig(c, detail.SurveyType, detail.Content.BaseConfig, config, false) {
+		return
+	}
+	if data.Title != "" {
+		config.Title = data.Title
+	}
+	// 模板不包含时间，草稿默认从当前时间开放一周，发布前由管理员修改
+	startTime := time.Now()
+	ddlTime := startTime.AddDate(0, 0, 7)
+	id, err := service.CreateSurvey(user.ID, 1, detail.SurveyType, detail.Content.BaseConfig, config, ddlTime,
+		startTime)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"id": id})
+}
@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"errors"
+	"mime/multipart"
+
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/i18n"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type translationData struct {
+	ID   int64  `form:"id" binding:"required"`
+	Lang string `form:"lang" binding:"required"`
+}
+
+// ExportTranslation 导出问卷翻译文件
+func ExportTranslation(c *gin.Context) {
+	var data translationData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if !i18n.ValidTag(data.Lang) {
+		code.AbortWithException(c, code.ParamError, errors.New("语言标签不合法"))
+		return
+	}
+	_, survey, ok := getManagedSurvey(c, data.ID)
+	if !ok {
+		return
+	}
+	url, err := service.ExportTranslation(survey, data.Lang)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, url)
+}
+
+// ImportTranslation 导入问卷翻译文件
+func ImportTranslation(c *gin.Context) {
+	var data translationData
+	err := c.ShouldBind(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if !i18n.ValidTag(data.Lang) {
+		code.AbortWithException(c, code.ParamError, errors.New("语言标签不合法"))
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if fileHeader.Size > 10*humanize.MiByte {
+		code.AbortWithException(c, code.FileSizeError, errors.New("翻译文件过大"))
+		return
+	}
+	_, survey, ok := getManagedSurvey(c, data.ID)
+	if !ok {
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	defer func(file multipart.File) {
+		err := file.Close()
+		if err != nil {
+			zap.L().Error("Failed to close file", zap.Error(err))
+		}
+	}(file)
+	num, err := service.ImportTranslation(survey, data.Lang, file)
+	if err != nil {
+		code.AbortWithException(c, code.SurveyError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"num": num})
+}
+
+// DeleteTranslation 删除问卷某一语言的译文
+func DeleteTranslation(c *gin.Context) {
+	var data translationData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if _, _, ok := getManagedSurvey(c, data.ID); !ok {
+		return
+	}
+	err = service.DeleteTranslation(data.ID, data.Lang)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
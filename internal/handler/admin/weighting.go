@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type setWeightingData struct {
+	SurveyID int64                   `json:"survey_id" binding:"required"`
+	Method   string                  `json:"method" binding:"required,oneof=poststratify raking"`
+	Targets  []model.WeightingTarget `json:"targets" binding:"required,dive"`
+}
+
+// SetWeighting 设置问卷的加权变量和总体分布
+func SetWeighting(c *gin.Context) {
+	var data setWeightingData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	_, survey, ok := getManagedSurvey(c, data.SurveyID)
+	if !ok {
+		return
+	}
+	weighting := model.Weighting{Method: data.Method, Targets: data.Targets}
+	if err := service.CheckWeighting(survey, weighting); err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if err := service.SetWeighting(survey.ID, weighting); err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type weightingData struct {
+	SurveyID int64 `form:"id" binding:"required"`
+}
+
+// GetWeighting 获取问卷的加权设置及按全部有效答卷计算的加权结果概况
+func GetWeighting(c *gin.Context) {
+	var data weightingData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	_, survey, ok := getManagedSurvey(c, data.SurveyID)
+	if !ok {
+		return
+	}
+	weighting, err := service.GetWeighting(survey.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	var summary *service.WeightingSummary
+	if weighting != nil {
+		summary, err = service.GetWeightingSummary(survey, weighting)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+	}
+	utils.JsonSuccessResponse(c, gin.H{"weighting": weighting, "summary": summary})
+}
+
+// DeleteWeighting 删除问卷的加权设置
+func DeleteWeighting(c *gin.Context) {
+	var data weightingData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	_, survey, ok := getManagedSurvey(c, data.SurveyID)
+	if !ok {
+		return
+	}
+	if err := service.DeleteWeighting(survey.ID); err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
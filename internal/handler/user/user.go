@@ -3,9 +3,9 @@ package user
 import (
 	"errors"
 	"image"
+	"math"
 	"mime/multipart"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -23,12 +23,17 @@ import (
 	"github.com/zjutjh/WeJH-SDK/oauth"
 	"github.com/zjutjh/WeJH-SDK/oauth/oauthException"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type submitSurveyData struct {
 	ID            int64               `json:"id" binding:"required"`
 	Token         string              `json:"token"`
+	OrderKey      string              `json:"order_key"` // 获取问卷时返回的题目顺序标识 打乱顺序的问卷必填
 	QuestionsList []dao.QuestionsList `json:"questions_list"`
+	HiddenFields  map[string]string   `json:"hidden_fields"` // 隐藏字段 未提供时从链接参数中获取
+	Lang          string              `json:"lang"`          // 获取问卷时返回的语言
+	Visit         string              `json:"visit"`         // 获取问卷时返回的访问标识
 }
 
 // SubmitSurvey 提交问卷
@@ -59,7 +64,14 @@ func SubmitSurvey(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-	if len(questions) != len(data.QuestionsList) {
+	// 计算题由服务端计算，不需要答题者作答
+	answerableNum := 0
+	for _, question := range questions {
+		if question.QuestionType != 8 {
+			answerableNum++
+		}
+	}
+	if answerableNum != len(data.QuestionsList) {
 		code.AbortWithException(c, code.SurveyError, errors.New("问卷问题和上传问题数量不一致"))
 		return
 	}
@@ -77,18 +89,51 @@ func SubmitSurvey(c *gin.Context) {
 		code.AbortWithException(c, code.SurveyNotOpen, errors.New("问卷未开放"))
 		return
 	}
+	// 获取答题者使用的语言的译文
+	lang, err := service.MatchSurveyLanguage(survey, data.Lang, c.GetHeader("Accept-Language"))
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	translation, err := service.GetTranslation(survey, lang)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
 	// 逐个判断问题答案
-	for _, q := range data.QuestionsList {
+	for i, q := range data.QuestionsList {
 		question, err := service.GetQuestionByID(q.QuestionID)
 		if err != nil {
 			code.AbortWithException(c, code.ServerError, err)
 			return
 		}
+		// 将译文选项还原为原文
+		q.Answer, err = service.NormalizeAnswer(question, q.Answer, translation)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		data.QuestionsList[i].Answer = q.Answer
 		if question.SurveyID != survey.ID {
 			code.AbortWithException(c, code.ServerError,
 				errors.New("问题"+strconv.Itoa(question.SerialNum)+"不属于该问卷"))
 			return
 		}
+		if question.QuestionType == 8 {
+			code.AbortWithException(c, code.SurveyError,
+				errors.New("问题"+strconv.Itoa(question.SerialNum)+"为计算题，不能作答"))
+			return
+		}
+		if question.QuestionType == 3 && question.Numeric && q.Answer != "" && !service.IsNumber(q.Answer) {
+			code.AbortWithException(c, code.SurveyError,
+				errors.New("问题"+strconv.Itoa(question.SerialNum)+"的答案必须为数字"))
+			return
+		}
+		// 判断排序题和评分题的选票是否有效
+		if err := service.CheckBallotAnswer(question, q.Answer); err != nil {
+			code.AbortWithException(c, code.BallotError, err)
+			return
+		}
 		// 判断必填字段是否为空
 		if question.Required && q.Answer == "" {
 			code.AbortWithException(c, code.ServerError,
@@ -107,6 +152,11 @@ func SubmitSurvey(c *gin.Context) {
 				return
 			}
 		}
+		// 判断绑定选项集的问题答案是否在选项集内
+		if err := service.CheckOptionSetAnswer(question, q.Answer); err != nil {
+			code.AbortWithException(c, code.OptionSetAnswerError, err)
+			return
+		}
 	}
 	flagSum, flagDay := false, false
 
@@ -136,12 +186,47 @@ func SubmitSurvey(c *gin.Context) {
 		}
 	}
 
+	// 按获取问卷时的答题者标识重新计算答题者看到的题目和选项顺序，不打乱顺序时所有答题者的顺序相同
+	respondentKey := service.GetRespondentKey(stuId, "")
+	shuffled, err := service.IsOrderShuffled(survey, questions)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if shuffled {
+		respondentKey, err = utils.ParseOrderJWT(data.OrderKey, survey.ID)
+		if err != nil {
+			code.AbortWithException(c, code.ParamError, err)
+			return
+		}
+		if err := service.CheckRespondentKey(respondentKey, stuId); err != nil {
+			code.AbortWithException(c, code.ParamError, err)
+			return
+		}
+	}
+	order, err := service.GetRespondentOrder(survey, respondentKey)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+
+	hidden := service.CollectHiddenFields(survey, data.HiddenFields, c.Query)
+
 	submitTime := time.Now().Format(time.DateTime)
-	err = service.SubmitSurvey(data.ID, data.QuestionsList, submitTime)
+	var answerSheet dao.AnswerSheet
+	var receipt string
+	if survey.Anonymous {
+		// 匿名选票成批写入，答卷时间和答卷ID在写入时生成
+		receipt, err = service.SubmitAnonymousBallot(survey, data.QuestionsList, order, lang)
+	} else {
+		answerSheet, err = service.SubmitSurvey(data.ID, data.QuestionsList, submitTime, order, hidden, lang,
+			stuId)
+	}
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	service.SubmitVisit(data.ID, data.Visit)
 
 	if survey.Verify {
 		if survey.DailyLimit > 0 {
@@ -158,19 +243,63 @@ func SubmitSurvey(c *gin.Context) {
 				return
 			}
 		}
-		// 记录授权
-		if err = service.CreateOauthRecord(userInfo, time.Now(), data.ID); err != nil {
+		// 记录授权，匿名投票只记录日期，避免与答卷时间对应
+		recordTime := time.Now()
+		if survey.Anonymous {
+			recordTime = time.Date(recordTime.Year(), recordTime.Month(), recordTime.Day(), 0, 0, 0, 0, time.Local)
+		}
+		if err = service.CreateOauthRecord(userInfo, recordTime, data.ID); err != nil {
 			code.AbortWithException(c, code.ServerError, err)
 			return
 		}
 	}
+	resultVisible, err := service.IsResultVisible(survey, stuId)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
 	utils.JsonSuccessResponse(c, gin.H{
-		"time": submitTime,
+		"time":       submitTime,
+		"completion": service.GetCompletion(survey, answerSheet, translation, resultVisible),
+		"receipt":    receipt,
 	})
 }
 
+type checkReceiptData struct {
+	ID      int64  `form:"id" binding:"required"`
+	Receipt string `form:"receipt" binding:"required"`
+}
+
+// CheckReceipt 根据回执码查询匿名选票是否已计入
+func CheckReceipt(c *gin.Context) {
+	var data checkReceiptData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	survey, err := service.GetSurveyByID(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !survey.Anonymous {
+		code.AbortWithException(c, code.SurveyTypeError, errors.New("问卷不是匿名投票"))
+		return
+	}
+	status, err := service.CheckReceipt(survey.ID, data.Receipt)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"status": status})
+}
+
 type getSurveyData struct {
-	ID int64 `form:"id" binding:"required"`
+	ID      int64  `form:"id" binding:"required"`
+	Token   string `form:"token"`   // 统一验证后的令牌，用于确定题目顺序
+	Session string `form:"session"` // 会话标识，未验证时用于确定题目顺序
+	Lang    string `form:"lang"`    // 语言 未指定时根据 Accept-Language 选择
 }
 
 // GetSurvey 用户获取问卷
@@ -207,10 +336,59 @@ func GetSurvey(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	// 获取问卷分页
+	sections, err := service.GetSectionsBySurveyID(survey.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	sectionSerialMap := service.GetSectionSerialMap(sections)
+	// 选择问卷语言并获取译文
+	lang, err := service.MatchSurveyLanguage(survey, data.Lang, c.GetHeader("Accept-Language"))
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	languages, err := service.GetSurveyLanguages(survey)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	translation, err := service.GetTranslation(survey, lang)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 确定答题者标识，同一答题者每次看到的题目和选项顺序相同
+	session := data.Session
+	if session == "" {
+		session = uuid.NewString()
+	}
+	var studentID string
+	if survey.Verify && data.Token != "" {
+		if userInfo, err := utils.ParseJWT(data.Token); err == nil {
+			studentID = userInfo.StudentID
+		}
+	}
+	key := service.GetRespondentKey(studentID, session)
+	// 从链接参数中获取隐藏字段，提交时原样带回
+	hidden := service.CollectHiddenFields(survey, nil, c.Query)
+	questions = service.ArrangeQuestions(survey, questions, sections, key)
 	// 构建问卷响应
 	questionListsResponse := make([]map[string]any, 0)
 	for _, question := range questions {
-		options, err := service.GetOptionsByQuestionID(question.ID)
+		options, err := service.GetQuestionOptions(question)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		options = service.ArrangeOptions(question, options, key)
+		// 级联选择的条目来自共享的选项集，不做翻译
+		for i := range options {
+			options[i] = translation.Option(question, options[i])
+		}
+		question := translation.Question(question)
+		cascade, err := service.GetQuestionCascade(question)
 		if err != nil {
 			code.AbortWithException(c, code.ServerError, err)
 			return
@@ -234,6 +412,8 @@ func GetSurvey(c *gin.Context) {
 			"reg":            question.Reg,
 			"maximum_option": question.MaximumOption,
 			"minimum_option": question.MinimumOption,
+			"max_score":      question.MaxScore,
+			"numeric":        question.Numeric,
 		}
 
 		questionListMap := map[string]any{
@@ -244,14 +424,21 @@ func GetSurvey(c *gin.Context) {
 			"img":          question.Img,
 			"ques_setting": questionSettingResponse,
 			"options":      optionsResponse,
+			"cascade":      cascade,
+			"section":      sectionSerialMap[question.SectionID],
+			"pipes":        service.GetQuestionPipes(question),
 		}
 		questionListsResponse = append(questionListsResponse, questionListMap)
 	}
 
+	for i := range sections {
+		sections[i] = translation.Section(sections[i])
+	}
 	questionsConfigResponse := map[string]any{
-		"title":         survey.Title,
-		"desc":          survey.Desc,
+		"title":         translation.Text("title", survey.Title),
+		"desc":          translation.Text("desc", survey.Desc),
 		"question_list": questionListsResponse,
+		"sections":      sections,
 	}
 	baseConfigResponse := map[string]any{
 		"start_time":     survey.StartTime,
@@ -260,18 +447,52 @@ func GetSurvey(c *gin.Context) {
 		"sum_limit":      survey.SumLimit,
 		"verify":         survey.Verify,
 		"undergrad_only": survey.UndergradOnly,
+		"theme":          survey.Theme,
+		"anonymous":      survey.Anonymous,
 	}
 	response := map[string]any{
-		"id":          survey.ID,
-		"status":      survey.Status,
-		"survey_type": survey.Type,
-		"base_config": baseConfigResponse,
-		"ques_config": questionsConfigResponse,
+		"id":            survey.ID,
+		"status":        survey.Status,
+		"survey_type":   survey.Type,
+		"base_config":   baseConfigResponse,
+		"ques_config":   questionsConfigResponse,
+		"session":       session,
+		"order_key":     utils.NewOrderJWT(survey.ID, key),
+		"visit":         service.NewVisit(survey.ID),
+		"hidden_fields": hidden,
+		"lang":          lang,
+		"languages":     languages,
 	}
 
 	utils.JsonSuccessResponse(c, response)
 }
 
+type visitEventData struct {
+	ID      int64  `json:"id" binding:"required"`
+	Visit   string `json:"visit" binding:"required"` // 获取问卷时返回的访问标识
+	Event   string `json:"event" binding:"required,oneof=start draft"`
+	Section int    `json:"section"` // 保存草稿时所在的分页序号 问卷未分页时为0
+}
+
+// RecordVisitEvent 记录答题者开始作答和保存草稿，用于统计问卷的访问漏斗
+func RecordVisitEvent(c *gin.Context) {
+	var data visitEventData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if err := service.CheckVisitEvent(data.ID, data.Event, data.Section); err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if err := service.RecordVisitEvent(data.ID, data.Visit, data.Event, data.Section); err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
 // UploadImg 上传图片
 func UploadImg(c *gin.Context) {
 	// 获取文件
@@ -411,20 +632,6 @@ func Oauth(c *gin.Context) {
 	})
 }
 
-type getOptionCount struct {
-	SerialNum int    `json:"serial_num"` // 选项序号
-	Content   string `json:"content"`    // 选项内容
-	Count     int    `json:"count"`      // 选项数量
-	Rank      int    `json:"rank"`       // 选项排名
-}
-
-type getSurveyStatisticsResponse struct {
-	SerialNum    int              `json:"serial_num"`    // 问题序号
-	Question     string           `json:"question"`      // 问题内容
-	QuestionType int              `json:"question_type"` // 问题类型  1:单选 2:多选
-	Options      []getOptionCount `json:"options"`       // 选项内容
-}
-
 // GetSurveyStatistics 获取投票统计
 func GetSurveyStatistics(c *gin.Context) {
 	var data getSurveyData
@@ -433,198 +640,150 @@ func GetSurveyStatistics(c *gin.Context) {
 		code.AbortWithException(c, code.ParamError, err)
 		return
 	}
-	survey, err := service.GetSurveyByID(data.ID)
-	if err != nil {
-		code.AbortWithException(c, code.ServerError, err)
+	survey, ok := getVisibleVoteSurvey(c, data)
+	if !ok {
 		return
 	}
-	if survey.Type != 1 {
-		code.AbortWithException(c, code.SurveyTypeError, errors.New("问卷为调研问卷"))
+	questions, err := service.GetQuestionsBySurveyID(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-	answerSheets, err := service.GetSurveyAnswersBySurveyID(data.ID)
+	response, err := service.GetVoteStats(data.ID, questions)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	utils.JsonSuccessResponse(c, gin.H{"statistics": formatStatistics(response, survey.ResultPolicy.Display)})
+}
 
-	questions, err := service.GetQuestionsBySurveyID(data.ID)
+// StreamSurveyStatistics 以 Server-Sent Events 实时推送投票统计，需问卷开启实时结果推送
+func StreamSurveyStatistics(c *gin.Context) {
+	var data getSurveyData
+	err := c.ShouldBindQuery(&data)
 	if err != nil {
-		code.AbortWithException(c, code.ServerError, err)
+		code.AbortWithException(c, code.ParamError, err)
 		return
 	}
-
-	// 如果 answerSheets 为空，则返回所有问题和选项统计为 0
-	if len(answerSheets) == 0 {
-		response := make([]getSurveyStatisticsResponse, 0, len(questions))
-		for _, q := range questions {
-			options, err := service.GetOptionsByQuestionID(q.ID)
-			if err != nil {
-				code.AbortWithException(c, code.ServerError, err)
-				return
-			}
-
-			qOptions := make([]getOptionCount, 0, len(options)+1)
-			for _, option := range options {
-				qOptions = append(qOptions, getOptionCount{
-					SerialNum: option.SerialNum,
-					Content:   option.Content,
-					Count:     0,
-					Rank:      1,
-				})
-			}
-
-			// 如果支持 "其他" 选项，添加一项
-			if q.OtherOption {
-				qOptions = append(qOptions, getOptionCount{
-					SerialNum: 0,
-					Content:   "其他",
-					Count:     0,
-					Rank:      1,
-				})
-			}
-
-			response = append(response, getSurveyStatisticsResponse{
-				SerialNum:    q.SerialNum,
-				Question:     q.Subject,
-				QuestionType: q.QuestionType,
-				Options:      qOptions,
-			})
-		}
-		utils.JsonSuccessResponse(c, gin.H{"statistics": response})
+	survey, ok := getVisibleVoteSurvey(c, data)
+	if !ok {
 		return
 	}
-
-	// 问题编号对应的问题
-	questionMap := make(map[int]model.Question)
-	// 问题编号对应的选项们
-	optionsMap := make(map[int][]model.Option)
-	// 问题编号与选项内容对应的选项
-	optionAnswerMap := make(map[int]map[string]model.Option)
-	// 问题编号与选项序号对应的选项
-	optionSerialNumMap := make(map[int]map[int]model.Option)
-	for _, question := range questions {
-		questionMap[question.ID] = question
-		optionAnswerMap[question.ID] = make(map[string]model.Option)
-		optionSerialNumMap[question.ID] = make(map[int]model.Option)
-		options, err := service.GetOptionsByQuestionID(question.ID)
+	if !survey.ResultPolicy.StreamPublic {
+		code.AbortWithException(c, code.ResultNotPublic, errors.New("实时结果未公开"))
+		return
+	}
+	// 推送期间管理员可能修改公开方式，每次推送前按最新设置重新判断
+	studentID := getResultStudentID(data.Token)
+	visible := func(survey *model.Survey) bool {
+		ok, err := service.IsStreamVisible(survey, studentID)
 		if err != nil {
-			code.AbortWithException(c, code.ServerError, err)
-			return
-		}
-		optionsMap[question.ID] = options
-		for _, option := range options {
-			optionAnswerMap[question.ID][option.Content] = option
-			optionSerialNumMap[question.ID][option.SerialNum] = option
-		}
-	}
-
-	// 问题编号对应的选项编号对应的选项数量
-	optionCounts := make(map[int]map[int]int)
-	for _, sheet := range answerSheets {
-		for _, answer := range sheet.Answers {
-			options := optionsMap[answer.QuestionID]
-			question := questionMap[answer.QuestionID]
-			// 初始化选项统计（确保每个选项的计数存在且为 0）
-			if _, initialized := optionCounts[question.ID]; !initialized {
-				counts := ensureMap(optionCounts, question.ID)
-				for _, option := range options {
-					counts[option.SerialNum] = 0
-				}
-			}
-			if question.QuestionType == 1 {
-				answerOptions := strings.Split(answer.Content, "┋")
-				questionOptions := optionAnswerMap[answer.QuestionID]
-				for _, answerOption := range answerOptions {
-					// 查找选项
-					if questionOptions != nil {
-						option, exists := questionOptions[answerOption]
-						if exists {
-							// 如果找到选项，处理逻辑
-							ensureMap(optionCounts, answer.QuestionID)[option.SerialNum]++
-							continue
-						}
-					}
-					// 如果选项不存在，处理为 "其他" 选项
-					ensureMap(optionCounts, answer.QuestionID)[0]++
-				}
-			}
+			zap.L().Error("判断实时结果是否公开失败", zap.Int64("survey_id", survey.ID), zap.Error(err))
 		}
+		return ok
 	}
+	service.StreamVoteStats(c, survey.ID, visible, func(survey *model.Survey, stats []service.VoteStatistics) any {
+		return gin.H{"statistics": formatStatistics(stats, survey.ResultPolicy.Display)}
+	})
+}
 
-	response := make([]getSurveyStatisticsResponse, 0, len(optionCounts))
-	for qid, options := range optionCounts {
-		q := questionMap[qid]
-		var qOptions []getOptionCount
-		if q.OtherOption {
-			qOptions = make([]getOptionCount, 0, len(options)+1)
-			// 添加其他选项
-			qOptions = append(qOptions, getOptionCount{
-				SerialNum: 0,
-				Content:   "其他",
-				Count:     options[0],
-			})
-		} else {
-			qOptions = make([]getOptionCount, 0, len(options))
-		}
-		// 按序号排序
-		sortedSerialNums := make([]int, 0, len(options))
-		for oSerialNum := range options {
-			sortedSerialNums = append(sortedSerialNums, oSerialNum)
-		}
-		sort.Ints(sortedSerialNums)
-		for _, oSerialNum := range sortedSerialNums {
-			count := options[oSerialNum]
-			op := optionSerialNumMap[qid][oSerialNum]
-			qOptions = append(qOptions, getOptionCount{
-				SerialNum: op.SerialNum,
-				Content:   op.Content,
-				Count:     count,
-			})
-		}
-
-		// 创建一个副本用于排序
-		sortedQOptions := make([]getOptionCount, len(qOptions))
-		copy(sortedQOptions, qOptions)
-
-		// 按选项数量排序
-		sort.Slice(sortedQOptions, func(i, j int) bool {
-			// 按数量降序排列，数量相同时按序号升序排列
-			if sortedQOptions[i].Count == sortedQOptions[j].Count {
-				return sortedQOptions[i].SerialNum < sortedQOptions[j].SerialNum
-			}
-			return sortedQOptions[i].Count > sortedQOptions[j].Count
-		})
+// getVisibleVoteSurvey 获取投票问卷，并判断投票结果是否对当前用户公开
+func getVisibleVoteSurvey(c *gin.Context, data getSurveyData) (*model.Survey, bool) {
+	survey, err := service.GetSurveyByID(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, false
+	}
+	if survey.Type != 1 {
+		code.AbortWithException(c, code.SurveyTypeError, errors.New("问卷为调研问卷"))
+		return nil, false
+	}
+	visible, err := service.IsResultVisible(survey, getResultStudentID(data.Token))
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, false
+	}
+	if !visible {
+		code.AbortWithException(c, code.ResultNotPublic, errors.New("投票结果暂未公开"))
+		return nil, false
+	}
+	return survey, true
+}
 
-		// 补充 rank
-		rankMap := make(map[int]int) // 用于记录选项的排名
-		currentRank := 1
-		for i := 0; i < len(sortedQOptions); i++ {
-			if i > 0 && sortedQOptions[i].Count < sortedQOptions[i-1].Count {
-				// 当前排名等于前面所有项目数量
-				currentRank = i + 1
-			}
-			rankMap[sortedQOptions[i].SerialNum] = currentRank
-		}
+// getResultStudentID 获取查看投票结果的学生学号，未登录或令牌无效时为空
+func getResultStudentID(token string) string {
+	if token == "" {
+		return ""
+	}
+	userInfo, err := utils.ParseJWT(token)
+	if err != nil {
+		return ""
+	}
+	return userInfo.StudentID
+}
 
-		// 将排名写回原始的 qOptions
-		for i := range qOptions {
-			qOptions[i].Rank = rankMap[qOptions[i].SerialNum]
+// formatStatistics 按问卷的结果展示方式隐藏选项的具体票数
+func formatStatistics(statistics []service.VoteStatistics, display int) any {
+	if display == service.ResultShowCount {
+		return statistics
+	}
+	response := make([]map[string]any, 0, len(statistics))
+	for _, statistic := range statistics {
+		total := 0
+		for _, option := range statistic.Options {
+			total += option.Count
 		}
-
-		response = append(response, getSurveyStatisticsResponse{
-			SerialNum:    q.SerialNum,
-			Question:     q.Subject,
-			QuestionType: q.QuestionType,
-			Options:      qOptions,
+		options := make([]map[string]any, 0, len(statistic.Options))
+		for _, option := range statistic.Options {
+			item := map[string]any{
+				"serial_num": option.SerialNum,
+				"content":    option.Content,
+			}
+			if display == service.ResultShowRank {
+				item["rank"] = option.Rank
+			} else {
+				percent := 0.0
+				if total > 0 {
+					percent = math.Round(float64(option.Count)*1000/float64(total)) / 10
+				}
+				item["percent"] = percent
+			}
+			options = append(options, item)
+		}
+		response = append(response, map[string]any{
+			"serial_num":    statistic.SerialNum,
+			"question":      statistic.Question,
+			"question_type": statistic.QuestionType,
+			"options":       options,
 		})
 	}
-	utils.JsonSuccessResponse(c, gin.H{"statistics": response})
+	return response
 }
 
-func ensureMap(m map[int]map[int]int, key int) map[int]int {
-	if m[key] == nil {
-		m[key] = make(map[int]int)
+type getDrawData struct {
+	ID int `form:"id" binding:"required"`
+}
+
+// GetDraw 公开查询抽奖结果，中奖者的学号和姓名会部分隐藏
+func GetDraw(c *gin.Context) {
+	var data getDrawData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	draw, err := service.GetDrawByID(data.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.DrawNotExist, errors.New("抽奖不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	detail, err := service.GetDrawDetail(draw, true)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
 	}
-	return m[key]
+	utils.JsonSuccessResponse(c, detail)
 }
@@ -33,7 +33,13 @@ func ErrHandler() gin.HandlerFunc {
 					zap.L().Error("Unknown Error Occurred", zap.Error(err))
 				}
 
-				utils.JsonErrorResponse(c, apiErr.Code, apiErr.Msg)
+				// 根据 Accept-Language 返回对应语言的错误信息
+				lang := code.MatchLang(c.GetHeader("Accept-Language"))
+				if fields := code.FieldErrors(c, lang); len(fields) != 0 {
+					utils.JsonResponse(c, http.StatusOK, apiErr.Code, apiErr.Localize(lang), gin.H{"fields": fields})
+					return
+				}
+				utils.JsonErrorResponse(c, apiErr.Code, apiErr.Localize(lang))
 				return
 			}
 		}
@@ -48,5 +54,6 @@ func HandleNotFound(c *gin.Context) {
 		zap.String("path", c.Request.URL.Path),
 		zap.String("method", c.Request.Method),
 	)
-	utils.JsonResponse(c, http.StatusNotFound, err.Code, err.Msg, nil)
+	lang := code.MatchLang(c.GetHeader("Accept-Language"))
+	utils.JsonResponse(c, http.StatusNotFound, err.Code, err.Localize(lang), nil)
 }
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// BankQuestion 题库题目模型
+type BankQuestion struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`        // 创建者ID
+	Shared        bool      `json:"shared"`         // 是否共享给所有管理员
+	Img           string    `json:"img"`            // 图片
+	Subject       string    `json:"subject"`        // 题目
+	Description   string    `json:"description"`    // 题目描述
+	Required      bool      `json:"required"`       // 是否必填
+	Unique        bool      `json:"unique"`         // 是否唯一
+	OtherOption   bool      `json:"other_option"`   // 是否有其他选项
+	QuestionType  int       `json:"question_type"`  // 题目类型 同 Question.QuestionType
+	MaximumOption uint      `json:"maximum_option"` // 多选最多所选选项数 0为不限制
+	MinimumOption uint      `json:"minimum_option"` // 多选最少所选选项数 0为不限制
+	Reg           string    `json:"reg"`            // 正则表达式
+	CreatedAt     time.Time `json:"created_at"`     // 创建时间
+	UpdatedAt     time.Time `json:"updated_at"`     // 更新时间
+}
+
+// BankOption 题库选项模型
+type BankOption struct {
+	ID             int    `json:"id"`               // 选项ID
+	BankQuestionID int    `json:"bank_question_id"` // 题库题目ID
+	SerialNum      int    `json:"serial_num"`       // 选项序号
+	Content        string `json:"content"`          // 选项内容
+	Description    string `json:"description"`      // 选项描述
+	Img            string `json:"img"`              // 选项图片
+}
+
+// BankTag 题库标签模型
+type BankTag struct {
+	ID             int    `json:"id"`
+	BankQuestionID int    `json:"bank_question_id"` // 题库题目ID
+	Name           string `json:"name"`             // 标签名
+}
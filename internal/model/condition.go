@@ -0,0 +1,15 @@
+package model
+
+// AnswerCondition 答案条件，用于抽奖和统计分析筛选答卷
+type AnswerCondition struct {
+	SerialNum int    `json:"serial_num"` // 题目序号
+	Answer    string `json:"answer"`     // 答案 多选题包含该选项即满足
+}
+
+// AnswerDimension 答卷的分析维度，用于交叉分析和加权
+type AnswerDimension struct {
+	Type      string `json:"type" binding:"required,oneof=question record hidden"`
+	SerialNum int    `json:"serial_num"` // 题目序号 按题目分析时使用
+	Attribute string `json:"attribute"`  // 统一验证信息 college、gender 或 user_type
+	Field     string `json:"field"`      // 隐藏字段名
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Draw 抽奖模型，创建时公布种子的摘要，开奖时公开种子
+type Draw struct {
+	ID            int        `json:"id"`
+	SurveyID      int64      `json:"survey_id"`                               // 问卷ID
+	UserID        int        `json:"user_id"`                                 // 创建抽奖的管理员ID
+	Name          string     `json:"name"`                                    // 抽奖名称 如 一等奖
+	Pool          string     `json:"pool"`                                    // 抽奖范围 answer:答卷 record:统一验证记录
+	Count         int        `json:"count"`                                   // 中奖人数
+	OnePerStudent bool       `json:"one_per_student"`                         // 每位学生最多中奖一次
+	Filter        DrawFilter `json:"filter" gorm:"type:text;serializer:json"` // 筛选条件
+	SeedHash      string     `json:"seed_hash" gorm:"type:char(64)"`          // 种子的 SHA-256 摘要
+	Seed          string     `json:"-" gorm:"type:char(64)"`                  // 种子 开奖前不公开
+	Salt          string     `json:"salt"`                                    // 开奖时加入的公开随机数 如彩票开奖号码
+	PoolHash      string     `json:"pool_hash" gorm:"type:char(64)"`          // 参与抽奖的候选列表摘要
+	Candidates    int        `json:"candidates"`                              // 参与抽奖的候选数
+	Status        int        `json:"status"`                                  // 状态 0:未开奖 1:已开奖
+	CreatedAt     time.Time  `json:"created_at"`                              // 创建时间
+	DrawnAt       *time.Time `json:"drawn_at"`                                // 开奖时间
+
+	CandidateKeys []string `json:"-" gorm:"type:longtext;serializer:json"` // 开奖时的候选标识 按字典序排列
+}
+
+// DrawFilter 抽奖的筛选条件
+type DrawFilter struct {
+	SubmittedAfter  string            `json:"submitted_after"`  // 提交时间不早于 格式 2006-01-02 15:04:05
+	SubmittedBefore string            `json:"submitted_before"` // 提交时间早于
+	Answers         []AnswerCondition `json:"answers"`          // 答案条件 需全部满足 仅用于答卷范围
+}
+
+// DrawWinner 中奖记录
+type DrawWinner struct {
+	ID        int    `json:"id"`
+	DrawID    int    `json:"draw_id" gorm:"index"` // 抽奖ID
+	Rank      int    `json:"rank"`                 // 中奖顺序
+	Key       string `json:"key"`                  // 候选标识 答卷ID或统一验证记录ID
+	StudentID string `json:"student_id"`           // 学号
+	Name      string `json:"name"`                 // 姓名
+	College   string `json:"college"`              // 学院
+}
@@ -8,4 +8,5 @@ type Option struct {
 	Content     string `json:"content"`     // 选项内容
 	Description string `json:"description"` // 选项描述
 	Img         string `json:"img"`         // 选项图片
+	Pinned      bool   `json:"pinned"`      // 打乱选项顺序时是否固定位置，如“其他”“以上都不是”
 }
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// OptionSet 选项集模型
+type OptionSet struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`     // 创建者ID
+	Name        string    `json:"name"`        // 选项集名称
+	Description string    `json:"description"` // 选项集描述
+	Version     int       `json:"version"`     // 当前版本号
+	CreatedAt   time.Time `json:"created_at"`  // 创建时间
+	UpdatedAt   time.Time `json:"updated_at"`  // 更新时间
+}
+
+// OptionSetItem 选项集条目模型，每次修改选项集都会生成一套新版本的条目
+type OptionSetItem struct {
+	ID          int    `json:"id"`
+	OptionSetID int    `json:"option_set_id"` // 选项集ID
+	Version     int    `json:"version"`       // 所属版本号
+	ParentID    int    `json:"parent_id"`     // 父条目ID 0为第一级
+	SerialNum   int    `json:"serial_num"`    // 同级序号
+	Content     string `json:"content"`       // 条目内容
+}
@@ -2,17 +2,28 @@ package model
 
 // Question 问题模型
 type Question struct {
-	ID            int    `json:"id"`
-	SurveyID      int64  `json:"survey_id"`      // 问卷ID
-	SerialNum     int    `json:"serial_num"`     // 题目序号
-	Img           string `json:"img"`            // 图片
-	Subject       string `json:"subject"`        // 题目
-	Description   string `json:"description"`    // 题目描述
-	Required      bool   `json:"required"`       // 是否必填
-	Unique        bool   `json:"unique"`         // 是否唯一
-	OtherOption   bool   `json:"other_option"`   // 是否有其他选项
-	QuestionType  int    `json:"question_type"`  // 题目类型 调研问卷为 1:单选(投票问卷为1投票) 2:多选 3:填空 4:简答 5:图片 6: 文件。
-	MaximumOption uint   `json:"maximum_option"` // 多选最多所选选项数 0为不限制
-	MinimumOption uint   `json:"minimum_option"` // 多选最少所选选项数 0为不限制
-	Reg           string `json:"reg"`            // 正则表达式
+	ID               int    `json:"id"`
+	SurveyID         int64  `json:"survey_id"`          // 问卷ID
+	SerialNum        int    `json:"serial_num"`         // 题目序号
+	Img              string `json:"img"`                // 图片
+	Subject          string `json:"subject"`            // 题目
+	Description      string `json:"description"`        // 题目描述
+	Required         bool   `json:"required"`           // 是否必填
+	Unique           bool   `json:"unique"`             // 是否唯一
+	OtherOption      bool   `json:"other_option"`       // 是否有其他选项
+	QuestionType     int    `json:"question_type"`      // 题目类型 调研问卷为 1:单选(投票问卷为1投票) 2:多选 3:填空 4:简答 5:图片 6: 文件 7:级联选择 8:计算 9:排序 10:评分。
+	MaximumOption    uint   `json:"maximum_option"`     // 多选最多所选选项数 0为不限制
+	MinimumOption    uint   `json:"minimum_option"`     // 多选最少所选选项数 0为不限制
+	Reg              string `json:"reg"`                // 正则表达式
+	BankQuestionID   int    `json:"bank_question_id"`   // 引用的题库题目ID 0为未引用
+	OptionSetID      int    `json:"option_set_id"`      // 绑定的选项集ID 0为使用自身选项
+	OptionSetVersion int    `json:"option_set_version"` // 绑定的选项集版本 0为跟随最新版本
+	SectionID        int    `json:"section_id"`         // 所属分页ID 0为不分页
+	ShuffleOptions   bool   `json:"shuffle_options"`    // 是否为每位答题者打乱选项顺序
+	Pinned           bool   `json:"pinned"`             // 打乱题目顺序时是否固定位置
+	Expression       string `json:"expression"`         // 计算题的计算公式
+	TallyMethod      string `json:"tally_method"`       // 排序题的计票方法 instant_runoff 或 borda
+	TieBreak         string `json:"tie_break"`          // 计票的平票规则 serial、count_back 或 random
+	MaxScore         int    `json:"max_score"`          // 评分题的最高分
+	Numeric          bool   `json:"numeric"`            // 填空题的答案是否为数值
 }
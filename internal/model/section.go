@@ -0,0 +1,11 @@
+package model
+
+// Section 问卷分页模型
+type Section struct {
+	ID          int    `json:"id"`
+	SurveyID    int64  `json:"survey_id"`   // 问卷ID
+	SerialNum   int    `json:"serial_num"`  // 分页序号
+	Title       string `json:"title"`       // 分页标题
+	Description string `json:"description"` // 分页介绍
+	Shuffle     bool   `json:"shuffle"`     // 是否打乱本页题目顺序
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Study 纵向研究，把多次发放的同类问卷作为轮次关联起来，比较对应题目的结果变化
+type Study struct {
+	ID          int         `json:"id"`
+	UserID      int         `json:"user_id"`                                    // 创建者ID
+	Name        string      `json:"name"`                                       // 研究名称
+	Description string      `json:"description"`                                // 研究描述
+	Items       []StudyItem `json:"items" gorm:"type:longtext;serializer:json"` // 跨轮次对应的题目
+	CreatedAt   time.Time   `json:"created_at"`                                 // 创建时间
+	UpdatedAt   time.Time   `json:"updated_at"`                                 // 更新时间
+}
+
+// StudyWave 研究的一个轮次
+type StudyWave struct {
+	ID        int    `json:"-"`
+	StudyID   int    `json:"-" gorm:"index"`                            // 研究ID
+	SurveyID  int64  `json:"survey_id" gorm:"index" binding:"required"` // 问卷ID
+	SerialNum int    `json:"serial_num"`                                // 轮次顺序
+	Label     string `json:"label" binding:"required"`                  // 轮次名称 如 2024秋
+}
+
+// StudyItem 各轮次中含义相同的一道选择题，各轮次的选项按内容或映射对应到统一的选项
+type StudyItem struct {
+	Title     string          `json:"title" binding:"required"`         // 统一的题目名称
+	Options   []string        `json:"options" binding:"required,min=1"` // 统一的选项
+	Questions []StudyQuestion `json:"questions" binding:"required,dive"`
+}
+
+// StudyQuestion 研究题目在某一轮次中对应的问题
+type StudyQuestion struct {
+	SurveyID  int64             `json:"survey_id" binding:"required"`  // 轮次的问卷ID
+	SerialNum int               `json:"serial_num" binding:"required"` // 问题序号
+	OptionMap map[string]string `json:"option_map"`                    // 选项内容到统一选项的映射 未列出的选项按内容对应
+}
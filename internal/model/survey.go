@@ -19,6 +19,39 @@ type Survey struct {
 	Type          uint      `json:"type"`                 // 问卷类型 0:调研 1:投票
 	Num           int       `json:"num"`                  // 问卷填写数量
 	NeedNotify    bool      `json:"need_notify"`          // 是否需要通知
+
+	ShuffleQuestions bool     `json:"shuffle_questions"`                              // 是否为每位答题者打乱题目顺序
+	HiddenFields     []string `json:"hidden_fields" gorm:"type:text;serializer:json"` // 从链接参数中获取的隐藏字段
+	DefaultLang      string   `json:"default_lang" gorm:"type:varchar(35)"`           // 问卷原文的语言
+
+	Completion SurveyCompletion `json:"completion" gorm:"type:text;serializer:json"` // 提交后的完成页设置
+	Theme      SurveyTheme      `json:"theme" gorm:"type:text;serializer:json"`      // 问卷主题
+
+	ResultPolicy SurveyResultPolicy `json:"result_policy" gorm:"type:text;serializer:json"` // 投票结果公开方式
+
+	Anonymous bool `json:"anonymous"` // 是否为匿名投票 答卷与投票者身份分开保存
+}
+
+// SurveyCompletion 问卷完成页设置
+type SurveyCompletion struct {
+	Message     string `json:"message"`      // 感谢语 富文本
+	RedirectURL string `json:"redirect_url"` // 提交后跳转的链接 可使用 {{Q3}}、{{answer_id}} 和隐藏字段占位符
+	ShowResults bool   `json:"show_results"` // 是否展示统计结果
+}
+
+// SurveyResultPolicy 投票结果公开方式
+type SurveyResultPolicy struct {
+	Visibility int `json:"visibility" binding:"oneof=0 1 2 3"` // 公开范围 0:始终公开 1:不公开 2:截止后公开 3:投票后公开
+	Display    int `json:"display" binding:"oneof=0 1 2"`      // 展示方式 0:票数 1:仅排名 2:仅百分比
+
+	StreamPublic bool `json:"stream_public"` // 是否公开实时结果推送 用于大屏展示
+}
+
+// SurveyTheme 问卷主题
+type SurveyTheme struct {
+	PrimaryColor string `json:"primary_color"` // 主题色 如 #1677ff
+	HeaderImg    string `json:"header_img"`    // 头图
+	Logo         string `json:"logo"`          // 标志
 }
 
 // SurveyResp 问卷响应模型
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Template 组织问卷模板模型，由超级管理员从已有问卷提升而来
+type Template struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`                         // 提升模板的管理员ID
+	SurveyID    int64     `json:"survey_id"`                       // 来源问卷ID
+	Name        string    `json:"name"`                            // 模板名称
+	Description string    `json:"description"`                     // 模板描述
+	Category    string    `json:"category"`                        // 模板分类
+	SurveyType  uint      `json:"survey_type"`                     // 问卷类型 同 Survey.Type
+	Content     string    `json:"-" gorm:"type:longtext;not null"` // 模板内容 JSON
+	CreatedAt   time.Time `json:"created_at"`                      // 创建时间
+}
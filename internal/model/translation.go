@@ -0,0 +1,10 @@
+package model
+
+// Translation 问卷翻译模型
+type Translation struct {
+	ID       int    `json:"id"`
+	SurveyID int64  `json:"survey_id" gorm:"index:idx_translation_survey_lang"`             // 问卷ID
+	Lang     string `json:"lang" gorm:"type:varchar(35);index:idx_translation_survey_lang"` // 语言标签
+	Key      string `json:"key" gorm:"type:varchar(64)"`                                    // 翻译条目 如 title、q3.subject、q3.o2
+	Content  string `json:"content" gorm:"type:text"`                                       // 译文
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Weighting 问卷的加权设置，按总体中各群体的占比调整答卷的权重
+type Weighting struct {
+	ID        int               `json:"id"`
+	SurveyID  int64             `json:"survey_id" gorm:"uniqueIndex"`             // 问卷ID
+	Method    string            `json:"method"`                                   // poststratify:事后分层 raking:迭代比例拟合
+	Targets   []WeightingTarget `json:"targets" gorm:"type:text;serializer:json"` // 加权变量在总体中的分布
+	UpdatedAt time.Time         `json:"updated_at"`                               // 更新时间
+}
+
+// WeightingTarget 加权变量在总体中的分布
+type WeightingTarget struct {
+	Dimension AnswerDimension    `json:"dimension"` // 加权变量 题目只能为单选题
+	Shares    map[string]float64 `json:"shares"`    // 各取值在总体中的人数或占比，按合计归一化
+}
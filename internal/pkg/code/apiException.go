@@ -54,6 +54,16 @@ var (
 	VoteSumLimitError            = NewError(200531, log.LevelInfo, "总投票次数已达上限")
 	NotUnderGraduateError        = NewError(200532, log.LevelInfo, "当前问卷仅允许本科生提交")
 	WrongOauthUsernameOrPassword = NewError(200534, log.LevelInfo, "统一登录账号或密码错误")
+	BankQuestionNotExist         = NewError(200535, log.LevelInfo, "题库题目不存在")
+	OptionSetNotExist            = NewError(200536, log.LevelInfo, "选项集不存在")
+	OptionSetInUse               = NewError(200537, log.LevelInfo, "选项集已被问卷使用，无法删除")
+	OptionSetAnswerError         = NewError(200538, log.LevelInfo, "所选内容不在可选范围内，请重新选择")
+	TemplateNotExist             = NewError(200539, log.LevelInfo, "模板不存在")
+	ResultNotPublic              = NewError(200540, log.LevelInfo, "投票结果暂未公开")
+	BallotError                  = NewError(200541, log.LevelInfo, "选票填写不符合要求")
+	DrawNotExist                 = NewError(200542, log.LevelInfo, "抽奖不存在")
+	DrawFinished                 = NewError(200543, log.LevelInfo, "抽奖已开奖")
+	StudyNotExist                = NewError(200544, log.LevelInfo, "研究不存在")
 	NotFound                     = NewError(200404, log.LevelInfo, http.StatusText(http.StatusNotFound))
 )
 
@@ -74,6 +84,9 @@ func NewError(code int, level log.Level, msg string) *Error {
 // AbortWithException 用于返回自定义错误信息
 func AbortWithException(c *gin.Context, apiError *Error, err error) {
 	logError(c, apiError, err)
+	if apiError == ParamError {
+		saveFieldErrors(c, err)
+	}
 	_ = c.AbortWithError(200, apiError) //nolint:errcheck
 }
 
@@ -0,0 +1,74 @@
+package code
+
+import "QA-System/internal/pkg/i18n"
+
+// languages 错误信息支持的语言，第一个为 Error.Msg 使用的语言
+var languages = []string{i18n.DefaultLang, "en"}
+
+// messages 各语言的错误信息，缺少的条目使用 Error.Msg
+//
+// 部分错误共用同一个错误码，因此按错误而不是错误码编排。
+var messages = map[string]map[*Error]string{
+	"en": {
+		ServerError:                  "System error, please try again later!",
+		ParamError:                   "Invalid parameters",
+		UserNotFind:                  "The user does not exist",
+		NotLogin:                     "Not logged in",
+		NoThatPasswordOrWrong:        "Wrong password",
+		HttpTimeout:                  "Request timed out, please try again later!",
+		RequestError:                 "System error, please try again later!",
+		StatusOpenError:              "The survey is not unpublished, please take it down and try again!",
+		SurveyNumError:               "The survey already has responses and cannot be modified!",
+		TimeBeyondError:              "The survey is not open for responses at this time!",
+		SurveyError:                  "The survey settings are invalid!",
+		UniqueError:                  "The answer to a unique question is duplicated, please fill it in again!",
+		UserExist:                    "The user already exists",
+		PictureError:                 "Only image files are allowed",
+		FileSizeError:                "The file is too large",
+		NotSuperAdmin:                "Sorry, you are not allowed to register accounts",
+		NoPermission:                 "Sorry, you are not allowed to perform this operation",
+		SurveyNotExist:               "The survey does not exist",
+		PermissionExist:              "The user already has permission, please do not repeat the operation!",
+		PermissionBelong:             "The survey belongs to this user, no need to grant permission!",
+		PermissionNotExist:           "The user has no permission, please do not operate!",
+		SurveyIncomplete:             "The survey is incomplete, please check again!",
+		SurveyContentRepeat:          "Duplicate questions or options, please fill them in again!",
+		NewPasswordSame:              "The new password is the same as the old one",
+		SurveyNotOpen:                "The survey is not open",
+		UserNotFound:                 "The user does not exist",
+		VoteLimitError:               "The daily voting limit has been reached",
+		OptionNumError:               "The number of options does not meet the requirements",
+		PageBeyondError:              "Page number out of range, please choose again",
+		SurveyTypeError:              "Invalid survey type",
+		OauthTimeError:               "Unified login is unavailable at night, please try during the day",
+		StatusRepeatError:            "The survey is already in this status, please choose again",
+		AnswerSheetNotExist:          "The answer sheet does not exist, please choose again",
+		VoteSumLimitError:            "The total voting limit has been reached",
+		NotUnderGraduateError:        "Only undergraduates can submit this survey",
+		WrongOauthUsernameOrPassword: "Wrong unified login account or password",
+		BankQuestionNotExist:         "The question bank item does not exist",
+		OptionSetNotExist:            "The option set does not exist",
+		OptionSetInUse:               "The option set is used by surveys and cannot be deleted",
+		OptionSetAnswerError:         "The selection is not among the available options, please choose again",
+		TemplateNotExist:             "The template does not exist",
+		ResultNotPublic:              "The voting results are not public yet",
+		BallotError:                  "The ballot is not filled in correctly",
+		DrawNotExist:                 "The prize draw does not exist",
+		DrawFinished:                 "The prize draw has already been drawn",
+		StudyNotExist:                "The study does not exist",
+		NotFound:                     "Not Found",
+	},
+}
+
+// MatchLang 根据 Accept-Language 请求头选择错误信息的语言
+func MatchLang(acceptLanguage string) string {
+	return i18n.Match(languages, i18n.ParseAcceptLanguage(acceptLanguage), languages[0])
+}
+
+// Localize 获取错误在指定语言下的信息
+func (e *Error) Localize(lang string) string {
+	if msg, ok := messages[lang][e]; ok {
+		return msg
+	}
+	return e.Msg
+}
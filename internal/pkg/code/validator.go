@@ -0,0 +1,132 @@
+package code
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"QA-System/internal/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError 参数校验失败的字段
+type FieldError struct {
+	Field   string `json:"field"`   // 字段名，与请求中的参数名一致
+	Rule    string `json:"rule"`    // 未通过的校验规则
+	Param   string `json:"param"`   // 校验规则的参数
+	Message string `json:"message"` // 错误信息
+}
+
+const fieldErrorsKey = "code_field_errors"
+
+// validationMessages 各语言的校验规则错误信息，{field} 和 {param} 会被替换为字段名和规则参数
+var validationMessages = map[string]map[string]string{
+	i18n.DefaultLang: {
+		"required": "{field}为必填字段",
+		"oneof":    "{field}必须是[{param}]中的一个",
+		"datetime": "{field}的格式必须为{param}",
+		"min":      "{field}不能小于{param}",
+		"max":      "{field}不能大于{param}",
+		"len":      "{field}的长度必须为{param}",
+		"gt":       "{field}必须大于{param}",
+		"gte":      "{field}必须大于或等于{param}",
+		"lt":       "{field}必须小于{param}",
+		"lte":      "{field}必须小于或等于{param}",
+		"email":    "{field}必须是有效的邮箱地址",
+		"type":     "{field}的类型必须为{param}",
+		"":         "{field}不符合{rule}规则",
+	},
+	"en": {
+		"required": "{field} is required",
+		"oneof":    "{field} must be one of [{param}]",
+		"datetime": "{field} must be in the format {param}",
+		"min":      "{field} must be at least {param}",
+		"max":      "{field} must be at most {param}",
+		"len":      "{field} must have a length of {param}",
+		"gt":       "{field} must be greater than {param}",
+		"gte":      "{field} must be greater than or equal to {param}",
+		"lt":       "{field} must be less than {param}",
+		"lte":      "{field} must be less than or equal to {param}",
+		"email":    "{field} must be a valid email address",
+		"type":     "{field} must be of type {param}",
+		"":         "{field} does not satisfy the {rule} rule",
+	},
+}
+
+// InitValidator 使参数校验错误中的字段名与请求中的参数名一致
+func InitValidator() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return ""
+	})
+}
+
+// FieldErrors 获取请求中参数校验失败的字段，错误信息使用指定语言
+func FieldErrors(c *gin.Context, lang string) []FieldError {
+	value, ok := c.Get(fieldErrorsKey)
+	if !ok {
+		return nil
+	}
+	fields, ok := value.([]FieldError)
+	if !ok {
+		return nil
+	}
+	templates, ok := validationMessages[lang]
+	if !ok {
+		templates = validationMessages[i18n.DefaultLang]
+	}
+	result := make([]FieldError, 0, len(fields))
+	for _, field := range fields {
+		template, ok := templates[field.Rule]
+		if !ok {
+			template = templates[""]
+		}
+		field.Message = strings.NewReplacer("{field}", field.Field, "{param}", field.Param,
+			"{rule}", field.Rule).Replace(template)
+		result = append(result, field)
+	}
+	return result
+}
+
+// saveFieldErrors 从参数绑定错误中提取校验失败的字段并保存到请求上下文
+func saveFieldErrors(c *gin.Context, err error) {
+	fields := make([]FieldError, 0)
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{Field: fieldPath(fe.Namespace()), Rule: fe.Tag(), Param: fe.Param()})
+		}
+	case errors.As(err, &typeErr):
+		fields = append(fields, FieldError{Field: typeErr.Field, Rule: "type", Param: typeErr.Type.String()})
+	}
+	if len(fields) != 0 {
+		c.Set(fieldErrorsKey, fields)
+	}
+}
+
+// fieldPath 去掉校验错误命名空间中的结构体名，如 createSurveyData.base_config.start_time
+func fieldPath(namespace string) string {
+	_, path, ok := strings.Cut(namespace, ".")
+	if !ok {
+		return namespace
+	}
+	return path
+}
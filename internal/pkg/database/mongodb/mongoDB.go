@@ -7,6 +7,7 @@ import (
 
 	"QA-System/internal/global/config"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -19,6 +20,15 @@ var QA string
 // Record mongodb存储记录的集合名
 var Record string
 
+// Ledger mongodb存储答卷哈希链的集合名
+var Ledger string
+
+// Checkpoint mongodb存储哈希链签名检查点的集合名
+var Checkpoint string
+
+// Visit mongodb存储问卷访问的集合名
+var Visit string
+
 // Init 初始化 MongoDB 连接
 func Init() *mongo.Database {
 	// Get MongoDB connection information from the configuration file
@@ -29,6 +39,9 @@ func Init() *mongo.Database {
 	db := config.Config.GetString("mongodb.db")
 	QA = config.Config.GetString("mongodb.qa-collection")
 	Record = config.Config.GetString("mongodb.record-collection")
+	Ledger = getCollectionName("mongodb.ledger-collection", "ledger")
+	Checkpoint = getCollectionName("mongodb.checkpoint-collection", "ledger_checkpoint")
+	Visit = getCollectionName("mongodb.visit-collection", "visit")
 
 	// 构建 MongoDB 连接字符串
 	var dsn string
@@ -54,7 +67,38 @@ func Init() *mongo.Database {
 
 	mdb := client.Database(db)
 
+	// 同一问卷的哈希链序号唯一，保证并发追加时不会分叉
+	_, err = mdb.Collection(Ledger).Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "survey_id", Value: 1}, {Key: "seq", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		zap.L().Fatal("Failed to create ledger index:" + err.Error())
+	}
+
+	// 按答卷时间范围统计问卷的答卷
+	_, err = mdb.Collection(QA).Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "surveyid", Value: 1}, {Key: "submittedat", Value: 1}},
+	})
+	if err != nil {
+		zap.L().Fatal("Failed to create answer time index:" + err.Error())
+	}
+
+	_, err = mdb.Collection(Visit).Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "surveyid", Value: 1}},
+	})
+	if err != nil {
+		zap.L().Fatal("Failed to create visit index:" + err.Error())
+	}
+
 	// 日志记录
 	zap.L().Info("Connected to MongoDB")
 	return mdb
 }
+
+func getCollectionName(key, fallback string) string {
+	if name := config.Config.GetString(key); name != "" {
+		return name
+	}
+	return fallback
+}
@@ -14,5 +14,18 @@ func autoMigrate(db *gorm.DB) error {
 		&model.Option{},
 		&model.Manage{},
 		&model.Pre{},
+		&model.BankQuestion{},
+		&model.BankOption{},
+		&model.BankTag{},
+		&model.OptionSet{},
+		&model.OptionSetItem{},
+		&model.Template{},
+		&model.Section{},
+		&model.Translation{},
+		&model.Draw{},
+		&model.DrawWinner{},
+		&model.Weighting{},
+		&model.Study{},
+		&model.StudyWave{},
 	)
 }
@@ -0,0 +1,120 @@
+// Package describe 计算数值样本的描述统计和直方图
+package describe
+
+import (
+	"math"
+	"sort"
+)
+
+const maxBins = 20 // 直方图最多的区间数
+
+// Summary 样本的描述统计
+type Summary struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"` // 样本标准差 样本数少于2时为0
+	Min    float64 `json:"min"`
+	P10    float64 `json:"p10"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+	Max    float64 `json:"max"`
+}
+
+// Bin 直方图的区间，除最后一个区间外不包含上界
+type Bin struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
+}
+
+// Summarize 计算样本的描述统计，分位数按相邻值线性插值，样本为空时返回零值
+//
+// 均值和标准差按最大绝对值缩放后计算，样本值接近 float64 上限时也不会溢出。
+func Summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	scale := math.Max(math.Abs(sorted[0]), math.Abs(sorted[n-1]))
+	if scale == 0 {
+		scale = 1
+	}
+	mean := 0.0
+	for _, v := range sorted {
+		mean += v / scale / float64(n)
+	}
+	summary := Summary{
+		Count:  n,
+		Mean:   mean * scale,
+		Min:    sorted[0],
+		P10:    percentile(sorted, 0.1),
+		P25:    percentile(sorted, 0.25),
+		Median: percentile(sorted, 0.5),
+		P75:    percentile(sorted, 0.75),
+		P90:    percentile(sorted, 0.9),
+		Max:    sorted[n-1],
+	}
+	if n > 1 {
+		squares := 0.0
+		for _, v := range sorted {
+			d := v/scale - mean
+			squares += d * d / float64(n-1)
+		}
+		summary.StdDev = math.Min(math.Sqrt(squares)*scale, math.MaxFloat64)
+	}
+	return summary
+}
+
+// Histogram 将样本按最小值到最大值等宽分组，区间数按 Sturges 公式确定
+func Histogram(values []float64) []Bin {
+	bins := make([]Bin, 0)
+	if len(values) == 0 {
+		return bins
+	}
+	lower, upper := values[0], values[0]
+	for _, v := range values {
+		lower = math.Min(lower, v)
+		upper = math.Max(upper, v)
+	}
+	if lower == upper {
+		return append(bins, Bin{Lower: lower, Upper: upper, Count: len(values)})
+	}
+	k := int(math.Ceil(math.Log2(float64(len(values))))) + 1
+	if k > maxBins {
+		k = maxBins
+	}
+	// 分别缩放上下界，两者之差超出 float64 范围时宽度也是有限值
+	width := upper/float64(k) - lower/float64(k)
+	for i := 0; i < k; i++ {
+		bins = append(bins, Bin{Lower: lower + width*float64(i), Upper: lower + width*float64(i+1)})
+	}
+	bins[k-1].Upper = upper
+	for _, v := range values {
+		// 先在浮点数上限定范围再取整，避免溢出或精度误差得到越界的下标
+		pos := v/width - lower/width
+		i := k - 1
+		if pos < 0 {
+			i = 0
+		} else if pos < float64(k-1) {
+			i = int(pos)
+		}
+		bins[i].Count++
+	}
+	return bins
+}
+
+// percentile 计算已排序样本的分位数
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	// 加权求和，避免相邻值之差溢出
+	f := pos - float64(i)
+	return sorted[i]*(1-f) + sorted[i+1]*f
+}
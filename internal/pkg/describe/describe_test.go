@@ -0,0 +1,88 @@
+package describe
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	cases := []struct {
+		name   string
+		values []float64
+		want   Summary
+	}{
+		{
+			name:   "普通样本",
+			values: []float64{4, 1, 3, 2},
+			want: Summary{Count: 4, Mean: 2.5, StdDev: math.Sqrt(5.0 / 3), Min: 1, P10: 1.3, P25: 1.75,
+				Median: 2.5, P75: 3.25, P90: 3.7, Max: 4},
+		},
+		{
+			name:   "单个样本",
+			values: []float64{7},
+			want:   Summary{Count: 1, Mean: 7, Min: 7, P10: 7, P25: 7, Median: 7, P75: 7, P90: 7, Max: 7},
+		},
+		{
+			name:   "正负极值",
+			values: []float64{1e308, -1e308},
+			want: Summary{Count: 2, Mean: 0, StdDev: math.Sqrt2 * 1e308, Min: -1e308, P10: -8e307, P25: -5e307,
+				Median: 0, P75: 5e307, P90: 8e307, Max: 1e308},
+		},
+		{
+			name:   "同号极值",
+			values: []float64{1e308, 1e308},
+			want: Summary{Count: 2, Mean: 1e308, Min: 1e308, P10: 1e308, P25: 1e308, Median: 1e308, P75: 1e308,
+				P90: 1e308, Max: 1e308},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Summarize(tc.values)
+			if _, err := json.Marshal(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Count != tc.want.Count || !near(got.Mean, tc.want.Mean) || !near(got.StdDev, tc.want.StdDev) ||
+				!near(got.Min, tc.want.Min) || !near(got.P10, tc.want.P10) || !near(got.P25, tc.want.P25) ||
+				!near(got.Median, tc.want.Median) || !near(got.P75, tc.want.P75) || !near(got.P90, tc.want.P90) ||
+				!near(got.Max, tc.want.Max) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	cases := []struct {
+		name   string
+		values []float64
+		counts []int
+	}{
+		{name: "空样本", values: nil, counts: []int{}},
+		{name: "相同的值", values: []float64{3, 3, 3}, counts: []int{3}},
+		{name: "最大值归入最后一个区间", values: []float64{0, 1, 2, 3}, counts: []int{1, 1, 2}},
+		{name: "正负极值", values: []float64{1e308, -1e308, 0}, counts: []int{1, 1, 1}},
+		{name: "最大的有限值", values: []float64{math.MaxFloat64, -math.MaxFloat64}, counts: []int{1, 1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bins := Histogram(tc.values)
+			if _, err := json.Marshal(bins); err != nil {
+				t.Fatal(err)
+			}
+			if len(bins) != len(tc.counts) {
+				t.Fatalf("got %d bins, want %d", len(bins), len(tc.counts))
+			}
+			for i, bin := range bins {
+				if bin.Count != tc.counts[i] {
+					t.Errorf("bin %d: got count %d, want %d", i, bin.Count, tc.counts[i])
+				}
+			}
+		})
+	}
+}
+
+// near 判断两个数在相对误差范围内相等
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
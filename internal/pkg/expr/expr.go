@@ -0,0 +1,395 @@
+// Package expr 实现计算题使用的安全表达式
+//
+// 表达式只支持数字、题目变量(如 Q3)、四则运算、取余、乘方、括号以及少量内置函数，
+// 不会执行任何外部代码。
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxLength = 500 // 表达式最大长度
+	maxDepth  = 50  // 表达式最大嵌套深度
+)
+
+// Expr 解析后的表达式
+type Expr struct {
+	root node
+	vars []string
+}
+
+// Parse 解析表达式
+func Parse(s string) (*Expr, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, errors.New("表达式为空")
+	}
+	if len(s) > maxLength {
+		return nil, errors.New("表达式过长")
+	}
+	p := &parser{src: []rune(s), varSet: make(map[string]bool)}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("表达式第%d个字符无法解析", p.pos+1)
+	}
+	return &Expr{root: root, vars: p.vars}, nil
+}
+
+// Vars 获取表达式引用的变量，按首次出现的顺序返回
+func (e *Expr) Vars() []string {
+	return e.vars
+}
+
+// Eval 使用给定的变量值计算表达式
+func (e *Expr) Eval(vars map[string]float64) (float64, error) {
+	v, err := e.root.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.New("计算结果无效")
+	}
+	return v, nil
+}
+
+type node interface {
+	eval(vars map[string]float64) (float64, error)
+}
+
+type numNode float64
+
+func (n numNode) eval(map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+type varNode string
+
+func (n varNode) eval(vars map[string]float64) (float64, error) {
+	v, ok := vars[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("变量%s没有值", string(n))
+	}
+	return v, nil
+}
+
+type unaryNode struct {
+	x node
+}
+
+func (n unaryNode) eval(vars map[string]float64) (float64, error) {
+	v, err := n.x.eval(vars)
+	return -v, err
+}
+
+type binaryNode struct {
+	op   rune
+	l, r node
+}
+
+func (n binaryNode) eval(vars map[string]float64) (float64, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		if r == 0 {
+			return 0, errors.New("除数为0")
+		}
+		return l / r, nil
+	case '%':
+		if r == 0 {
+			return 0, errors.New("除数为0")
+		}
+		return math.Mod(l, r), nil
+	default:
+		return math.Pow(l, r), nil
+	}
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+func (n callNode) eval(vars map[string]float64) (float64, error) {
+	args := make([]float64, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, v)
+	}
+	switch n.name {
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "sqrt":
+		if args[0] < 0 {
+			return 0, errors.New("不能对负数开方")
+		}
+		return math.Sqrt(args[0]), nil
+	case "round":
+		if len(args) == 1 {
+			return math.Round(args[0]), nil
+		}
+		p := math.Pow(10, math.Round(args[1]))
+		return math.Round(args[0]*p) / p, nil
+	case "min":
+		return minOf(args), nil
+	case "max":
+		return -minOf(negate(args)), nil
+	case "sum":
+		return sumOf(args), nil
+	default:
+		return sumOf(args) / float64(len(args)), nil
+	}
+}
+
+// functions 内置函数及其参数个数范围
+var functions = map[string][2]int{
+	"abs":   {1, 1},
+	"sqrt":  {1, 1},
+	"round": {1, 2},
+	"min":   {1, math.MaxInt},
+	"max":   {1, math.MaxInt},
+	"sum":   {1, math.MaxInt},
+	"avg":   {1, math.MaxInt},
+}
+
+func minOf(args []float64) float64 {
+	m := args[0]
+	for _, v := range args[1:] {
+		m = math.Min(m, v)
+	}
+	return m
+}
+
+func negate(args []float64) []float64 {
+	neg := make([]float64, 0, len(args))
+	for _, v := range args {
+		neg = append(neg, -v)
+	}
+	return neg
+}
+
+func sumOf(args []float64) float64 {
+	var s float64
+	for _, v := range args {
+		s += v
+	}
+	return s
+}
+
+type parser struct {
+	src    []rune
+	pos    int
+	vars   []string
+	varSet map[string]bool
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() rune {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// parseExpr 解析加减运算
+func (p *parser) parseExpr(depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, errors.New("表达式嵌套过深")
+	}
+	l, err := p.parseTerm(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseTerm(depth)
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: op, l: l, r: r}
+	}
+}
+
+// parseTerm 解析乘除和取余运算
+func (p *parser) parseTerm(depth int) (node, error) {
+	l, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: op, l: l, r: r}
+	}
+}
+
+// parseUnary 解析负号
+func (p *parser) parseUnary(depth int) (node, error) {
+	if p.peek() == '-' {
+		p.pos++
+		if depth+1 > maxDepth {
+			return nil, errors.New("表达式嵌套过深")
+		}
+		x, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{x: x}, nil
+	}
+	return p.parsePower(depth)
+}
+
+// parsePower 解析乘方，乘方为右结合
+func (p *parser) parsePower(depth int) (node, error) {
+	base, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != '^' {
+		return base, nil
+	}
+	p.pos++
+	if depth+1 > maxDepth {
+		return nil, errors.New("表达式嵌套过深")
+	}
+	exp, err := p.parseUnary(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: '^', l: base, r: exp}, nil
+}
+
+// parsePrimary 解析数字、变量、函数调用和括号
+func (p *parser) parsePrimary(depth int) (node, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		x, err := p.parseExpr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, errors.New("表达式括号不匹配")
+		}
+		p.pos++
+		return x, nil
+	case unicode.IsDigit(c) || c == '.':
+		return p.parseNumber()
+	case unicode.IsLetter(c):
+		return p.parseIdent(depth)
+	case c == 0:
+		return nil, errors.New("表达式不完整")
+	default:
+		return nil, fmt.Errorf("表达式包含非法字符%c", c)
+	}
+}
+
+func (p *parser) parseNumber() (node, error) {
+	start := p.pos
+	for p.pos < len(p.src) && (unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	v, err := strconv.ParseFloat(string(p.src[start:p.pos]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("数字%s格式错误", string(p.src[start:p.pos]))
+	}
+	return numNode(v), nil
+}
+
+func (p *parser) parseIdent(depth int) (node, error) {
+	start := p.pos
+	for p.pos < len(p.src) && (unicode.IsLetter(p.src[p.pos]) || unicode.IsDigit(p.src[p.pos])) {
+		p.pos++
+	}
+	name := string(p.src[start:p.pos])
+	if p.peek() == '(' {
+		return p.parseCall(strings.ToLower(name), depth)
+	}
+	upper := strings.ToUpper(name)
+	if len(upper) < 2 || upper[0] != 'Q' {
+		return nil, fmt.Errorf("未知变量%s", name)
+	}
+	serialNum, err := strconv.Atoi(upper[1:])
+	if err != nil {
+		return nil, fmt.Errorf("未知变量%s", name)
+	}
+	// 答案按 Q 加题目序号保存，Q01 与 Q1 引用同一题目
+	upper = "Q" + strconv.Itoa(serialNum)
+	if !p.varSet[upper] {
+		p.varSet[upper] = true
+		p.vars = append(p.vars, upper)
+	}
+	return varNode(upper), nil
+}
+
+func (p *parser) parseCall(name string, depth int) (node, error) {
+	arity, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("未知函数%s", name)
+	}
+	p.pos++ // 跳过左括号
+	args := make([]node, 0)
+	if p.peek() != ')' {
+		for {
+			arg, err := p.parseExpr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+		}
+	}
+	if p.peek() != ')' {
+		return nil, errors.New("函数" + name + "括号不匹配")
+	}
+	p.pos++
+	if len(args) < arity[0] || len(args) > arity[1] {
+		return nil, fmt.Errorf("函数%s参数个数错误", name)
+	}
+	return callNode{name: name, args: args}, nil
+}
@@ -0,0 +1,80 @@
+// Package i18n 实现语言标签的校验和协商
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLang 未设置默认语言时使用的语言
+const DefaultLang = "zh-CN"
+
+var tagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ValidTag 判断语言标签是否合法，如 zh-CN、en、en-US
+func ValidTag(tag string) bool {
+	return len(tag) <= 35 && tagPattern.MatchString(tag)
+}
+
+// ParseAcceptLanguage 解析 Accept-Language 请求头，按权重从高到低返回语言标签
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	items := make([]weighted, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			items = append(items, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+	tags := make([]string, 0, len(items))
+	for _, item := range items {
+		tags = append(tags, item.tag)
+	}
+	return tags
+}
+
+// Match 从可用语言中选出与请求语言最匹配的语言，均不匹配时返回 fallback
+//
+// 请求语言依次尝试完全匹配和主语言匹配，如 en-GB 可以匹配 en 或 en-US。
+func Match(available []string, requested []string, fallback string) string {
+	for _, tag := range requested {
+		for _, lang := range available {
+			if strings.EqualFold(lang, tag) {
+				return lang
+			}
+		}
+		base := primary(tag)
+		for _, lang := range available {
+			if strings.EqualFold(primary(lang), base) {
+				return lang
+			}
+		}
+	}
+	return fallback
+}
+
+func primary(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	return strings.ToLower(base)
+}
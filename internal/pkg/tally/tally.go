@@ -0,0 +1,341 @@
+// Package tally 实现投票的计票方法
+//
+// 候选项使用选项序号表示，所有方法在票数相同时都按确定的规则决出先后，
+// 同样的选票总能得到同样的结果。
+package tally
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// 计票方法
+const (
+	MethodPlurality     = "plurality"      // 相对多数 单选
+	MethodApproval      = "approval"       // 认可投票 多选
+	MethodInstantRunoff = "instant_runoff" // 即时决选 排序
+	MethodBorda         = "borda"          // 波达计数 排序
+	MethodScore         = "score"          // 平均分 评分
+)
+
+// 平票规则
+const (
+	TieBreakSerial    = "serial"     // 序号小的候选项优先
+	TieBreakCountBack = "count_back" // 即时决选依次比较此前各轮票数，其余方法比较首选票数，仍相同时按序号
+	TieBreakRandom    = "random"     // 按固定种子生成的随机顺序
+)
+
+// Score 候选项的得分
+type Score struct {
+	Candidate int     `json:"candidate"` // 候选项序号
+	Value     float64 `json:"value"`     // 票数、积分或平均分
+	Rank      int     `json:"rank"`      // 排名 平票决出先后后不会并列
+}
+
+// Round 即时决选的一轮计票
+type Round struct {
+	Number     int     `json:"number"`     // 轮次
+	Scores     []Score `json:"scores"`     // 本轮仍在竞争的候选项票数
+	Exhausted  int     `json:"exhausted"`  // 排序的候选项均已淘汰的选票数
+	Eliminated int     `json:"eliminated"` // 本轮淘汰的候选项 0为无
+	Elected    int     `json:"elected"`    // 本轮当选的候选项 0为无
+	TieBroken  bool    `json:"tie_broken"` // 本轮是否使用了平票规则
+}
+
+// Result 计票结果
+type Result struct {
+	Method    string  `json:"method"`
+	TieBreak  string  `json:"tie_break"`
+	Ballots   int     `json:"ballots"`          // 有效选票数
+	Winner    int     `json:"winner"`           // 当选的候选项 0为无
+	Scores    []Score `json:"scores"`           // 按排名排列的最终得分
+	Rounds    []Round `json:"rounds,omitempty"` // 即时决选的逐轮报告
+	TieBroken bool    `json:"tie_broken"`       // 是否使用了平票规则决定排名
+}
+
+// Config 计票配置
+type Config struct {
+	TieBreak string
+	Seed     int64 // 随机平票规则使用的种子
+}
+
+// Plurality 统计单选票数，每张选票为所选的候选项
+func Plurality(candidates []int, ballots [][]int, config Config) Result {
+	result := Approval(candidates, ballots, config)
+	result.Method = MethodPlurality
+	return result
+}
+
+// Approval 统计认可票数，每张选票为认可的候选项集合
+func Approval(candidates []int, ballots [][]int, config Config) Result {
+	values := make(map[int]float64)
+	valid := 0
+	for _, ballot := range ballots {
+		counted := false
+		seen := make(map[int]bool)
+		for _, candidate := range ballot {
+			if !contains(candidates, candidate) || seen[candidate] {
+				continue
+			}
+			seen[candidate] = true
+			values[candidate]++
+			counted = true
+		}
+		if counted {
+			valid++
+		}
+	}
+	result := rank(candidates, values, firstPreferences(candidates, ballots), config)
+	result.Method = MethodApproval
+	result.Ballots = valid
+	return result
+}
+
+// Borda 计算波达积分，排在第 i 位(从 0 开始)的候选项得 n-1-i 分，未排序的候选项不得分
+func Borda(candidates []int, ballots [][]int, config Config) Result {
+	values := make(map[int]float64)
+	valid := 0
+	for _, ballot := range ballots {
+		ranked := normalizeBallot(candidates, ballot)
+		if len(ranked) == 0 {
+			continue
+		}
+		valid++
+		for i, candidate := range ranked {
+			values[candidate] += float64(len(candidates) - 1 - i)
+		}
+	}
+	result := rank(candidates, values, firstPreferences(candidates, ballots), config)
+	result.Method = MethodBorda
+	result.Ballots = valid
+	return result
+}
+
+// ScoreVote 计算平均分，每张选票为各候选项的分数，未评分的候选项不计入平均
+func ScoreVote(candidates []int, ballots []map[int]float64, config Config) Result {
+	sums := make(map[int]float64)
+	counts := make(map[int]int)
+	valid := 0
+	for _, ballot := range ballots {
+		counted := false
+		for _, candidate := range candidates {
+			if score, ok := ballot[candidate]; ok {
+				sums[candidate] += score
+				counts[candidate]++
+				counted = true
+			}
+		}
+		if counted {
+			valid++
+		}
+	}
+	values := make(map[int]float64)
+	for _, candidate := range candidates {
+		if counts[candidate] > 0 {
+			values[candidate] = sums[candidate] / float64(counts[candidate])
+		}
+	}
+	result := rank(candidates, values, nil, config)
+	result.Method = MethodScore
+	result.Ballots = valid
+	return result
+}
+
+// InstantRunoff 进行即时决选，每轮统计各选票排序最靠前的未淘汰候选项，
+// 有候选项过半数时当选，否则淘汰票数最少的候选项
+func InstantRunoff(candidates []int, ballots [][]int, config Config) Result {
+	result := Result{Method: MethodInstantRunoff, TieBreak: tieBreakName(config), Scores: make([]Score, 0)}
+	normalized := make([][]int, 0, len(ballots))
+	for _, ballot := range ballots {
+		if ranked := normalizeBallot(candidates, ballot); len(ranked) > 0 {
+			normalized = append(normalized, ranked)
+		}
+	}
+	result.Ballots = len(normalized)
+	priority := priorities(candidates, config)
+	active := make(map[int]bool)
+	for _, candidate := range candidates {
+		active[candidate] = true
+	}
+	history := make([]map[int]float64, 0)
+	eliminated := make([]int, 0)
+	lastCounts := make(map[int]float64) // 候选项最后一次参与计票时的票数
+	for number := 1; len(active) > 0; number++ {
+		counts := make(map[int]float64)
+		exhausted := 0
+		for _, ballot := range normalized {
+			top := 0
+			for _, candidate := range ballot {
+				if active[candidate] {
+					top = candidate
+					break
+				}
+			}
+			if top == 0 {
+				exhausted++
+				continue
+			}
+			counts[top]++
+		}
+		history = append(history, counts)
+		round := Round{Number: number, Exhausted: exhausted, Scores: make([]Score, 0, len(active))}
+		remaining := make([]int, 0, len(active))
+		for _, candidate := range candidates {
+			if active[candidate] {
+				remaining = append(remaining, candidate)
+			}
+		}
+		ranking := order(remaining, func(a, b int) int {
+			return compareHistory(history, a, b, config.TieBreak == TieBreakCountBack)
+		}, priority)
+		for i, candidate := range ranking {
+			lastCounts[candidate] = counts[candidate]
+			round.Scores = append(round.Scores, Score{Candidate: candidate, Value: counts[candidate], Rank: i + 1})
+		}
+		total := float64(len(normalized) - exhausted)
+		leader := ranking[0]
+		if counts[leader]*2 > total || len(ranking) == 1 {
+			// 仍有选票时才产生当选者
+			if total > 0 {
+				round.Elected = leader
+				result.Winner = leader
+			}
+			result.Rounds = append(result.Rounds, round)
+			// 最终排名为当选者、其余未淘汰的候选项，然后按淘汰的逆序排列
+			final := append([]int{}, ranking...)
+			for i := len(eliminated) - 1; i >= 0; i-- {
+				final = append(final, eliminated[i])
+			}
+			for i, candidate := range final {
+				result.Scores = append(result.Scores, Score{Candidate: candidate, Value: lastCounts[candidate], Rank: i + 1})
+			}
+			break
+		}
+		// 淘汰排在最后的候选项，与倒数第二名票数相同时由平票规则决定
+		loser := ranking[len(ranking)-1]
+		round.Eliminated = loser
+		round.TieBroken = counts[loser] == counts[ranking[len(ranking)-2]]
+		result.TieBroken = result.TieBroken || round.TieBroken
+		eliminated = append(eliminated, loser)
+		delete(active, loser)
+		result.Rounds = append(result.Rounds, round)
+	}
+	return result
+}
+
+// rank 按得分从高到低排列候选项，得分相同时使用平票规则
+func rank(candidates []int, values, firsts map[int]float64, config Config) Result {
+	priority := priorities(candidates, config)
+	ranking := order(candidates, func(a, b int) int {
+		if c := compareFloat(values[a], values[b]); c != 0 {
+			return c
+		}
+		if config.TieBreak == TieBreakCountBack && firsts != nil {
+			return compareFloat(firsts[a], firsts[b])
+		}
+		return 0
+	}, priority)
+	result := Result{TieBreak: tieBreakName(config), Scores: make([]Score, 0, len(ranking))}
+	for i, candidate := range ranking {
+		result.Scores = append(result.Scores, Score{Candidate: candidate, Value: values[candidate], Rank: i + 1})
+		if i > 0 && values[candidate] == values[ranking[i-1]] {
+			result.TieBroken = true
+		}
+	}
+	if len(ranking) > 0 && values[ranking[0]] > 0 {
+		result.Winner = ranking[0]
+	}
+	return result
+}
+
+// order 按 compare 从高到低排列候选项，compare 无法区分时按 priority 排列
+func order(candidates []int, compare func(a, b int) int, priority map[int]int) []int {
+	sorted := append([]int{}, candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if c := compare(sorted[i], sorted[j]); c != 0 {
+			return c > 0
+		}
+		return priority[sorted[i]] < priority[sorted[j]]
+	})
+	return sorted
+}
+
+// compareHistory 比较两个候选项最近一轮的票数，countBack 时依次向前比较此前各轮
+func compareHistory(history []map[int]float64, a, b int, countBack bool) int {
+	for i := len(history) - 1; i >= 0; i-- {
+		if c := compareFloat(history[i][a], history[i][b]); c != 0 {
+			return c
+		}
+		if !countBack {
+			break
+		}
+	}
+	return 0
+}
+
+// priorities 获取平票时候选项的先后顺序，值越小越优先
+func priorities(candidates []int, config Config) map[int]int {
+	sorted := append([]int{}, candidates...)
+	sort.Ints(sorted)
+	if config.TieBreak == TieBreakRandom {
+		r := rand.New(rand.NewSource(config.Seed)) //nolint:gosec
+		r.Shuffle(len(sorted), func(i, j int) {
+			sorted[i], sorted[j] = sorted[j], sorted[i]
+		})
+	}
+	priority := make(map[int]int)
+	for i, candidate := range sorted {
+		priority[candidate] = i
+	}
+	return priority
+}
+
+func firstPreferences(candidates []int, ballots [][]int) map[int]float64 {
+	firsts := make(map[int]float64)
+	for _, ballot := range ballots {
+		if ranked := normalizeBallot(candidates, ballot); len(ranked) > 0 {
+			firsts[ranked[0]]++
+		}
+	}
+	return firsts
+}
+
+// normalizeBallot 去掉选票中不存在和重复的候选项
+func normalizeBallot(candidates []int, ballot []int) []int {
+	ranked := make([]int, 0, len(ballot))
+	seen := make(map[int]bool)
+	for _, candidate := range ballot {
+		if contains(candidates, candidate) && !seen[candidate] {
+			seen[candidate] = true
+			ranked = append(ranked, candidate)
+		}
+	}
+	return ranked
+}
+
+func tieBreakName(config Config) string {
+	if config.TieBreak == "" {
+		return TieBreakSerial
+	}
+	return config.TieBreak
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func contains(arr []int, item int) bool {
+	for _, a := range arr {
+		if a == item {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
 	"time"
 
@@ -56,8 +58,9 @@ func ParseJWT(token string) (oauth.UserInfo, error) {
 		return oauth.UserInfo{}, err
 	}
 
+	// 统一验证的身份必须包含学号
 	userClaims, ok := t.Claims.(*UserClaims)
-	if !ok || !t.Valid {
+	if !ok || !t.Valid || userClaims.StudentID == "" {
 		return oauth.UserInfo{}, errors.New("invalid token")
 	}
 
@@ -71,3 +74,57 @@ func ParseJWT(token string) (oauth.UserInfo, error) {
 	}
 	return userInfo, nil
 }
+
+// orderAudience 题目顺序标识的受众，与身份令牌区分
+const orderAudience = "order"
+
+// orderTokenTTL 题目顺序标识的有效期
+const orderTokenTTL = 24 * time.Hour
+
+// OrderClaims 获取问卷时生成题目和选项顺序的答题者标识
+type OrderClaims struct {
+	SurveyID int64  `json:"surveyId"`
+	Key      string `json:"key"`
+	jwt.RegisteredClaims
+}
+
+// orderSigningKey 由 JWT 密钥派生题目顺序标识的签名密钥，顺序标识不能被当作身份令牌使用
+func orderSigningKey() []byte {
+	mac := hmac.New(sha256.New, []byte(global.Config.GetString("jwt.key")))
+	mac.Write([]byte(orderAudience))
+	return mac.Sum(nil)
+}
+
+// NewOrderJWT 生成答题者标识的签名，提交时据此还原答题者看到的顺序
+func NewOrderJWT(surveyID int64, respondentKey string) string {
+	now := time.Now()
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, OrderClaims{
+		SurveyID: surveyID,
+		Key:      respondentKey,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{orderAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(orderTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	s, err := t.SignedString(orderSigningKey())
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// ParseOrderJWT 解析答题者标识的签名，问卷不一致或已过期时返回错误
+func ParseOrderJWT(token string, surveyID int64) (string, error) {
+	t, err := jwt.ParseWithClaims(token, &OrderClaims{}, func(_ *jwt.Token) (any, error) {
+		return orderSigningKey(), nil
+	}, jwt.WithAudience(orderAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", err
+	}
+	claims, ok := t.Claims.(*OrderClaims)
+	if !ok || !t.Valid || claims.SurveyID != surveyID || claims.Key == "" {
+		return "", errors.New("invalid order token")
+	}
+	return claims.Key, nil
+}
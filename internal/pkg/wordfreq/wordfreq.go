@@ -0,0 +1,81 @@
+// Package wordfreq 对中文文本分词并统计词频，用于生成词云
+//
+// 分词使用 gse 内置的简体中文词典和停用词表，词典在服务启动时加载。
+package wordfreq
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/go-ego/gse"
+)
+
+// Word 词及其出现次数
+type Word struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+// englishStopWords 内置停用词表不包含的常见英文虚词
+var englishStopWords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "from", "has", "have", "in", "is", "it",
+	"its", "of", "on", "or", "so", "that", "the", "this", "to", "was", "were", "will", "with",
+}
+
+var (
+	seg     gse.Segmenter
+	loadErr error
+	once    sync.Once
+)
+
+// Load 加载词典和停用词表，stopWords 为额外停用词表的文件路径，每行一个词，为空时只使用内置停用词
+//
+// 只有第一次调用生效，未加载时 Count 会以空路径自动调用。
+func Load(stopWords string) error {
+	once.Do(func() {
+		if loadErr = seg.LoadDictEmbed("zh_s"); loadErr != nil {
+			return
+		}
+		if loadErr = seg.LoadStopEmbed(); loadErr != nil {
+			return
+		}
+		seg.AddStopArr(englishStopWords...)
+		if stopWords != "" {
+			loadErr = seg.LoadStop(stopWords)
+		}
+	})
+	return loadErr
+}
+
+// Count 统计文本中出现次数最多的 limit 个词，忽略停用词、标点和单字，英文不区分大小写
+func Count(texts []string, limit int) ([]Word, error) {
+	if err := Load(""); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, text := range texts {
+		for _, word := range seg.Trim(seg.Cut(text, true)) {
+			word = strings.ToLower(strings.TrimSpace(word))
+			if utf8.RuneCountInString(word) < 2 || seg.IsStop(word) {
+				continue
+			}
+			counts[word]++
+		}
+	}
+	words := make([]Word, 0, len(counts))
+	for text, count := range counts {
+		words = append(words, Word{Text: text, Count: count})
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].Count != words[j].Count {
+			return words[i].Count > words[j].Count
+		}
+		return words[i].Text < words[j].Text
+	})
+	if limit > 0 && len(words) > limit {
+		words = words[:limit]
+	}
+	return words, nil
+}
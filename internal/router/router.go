@@ -21,6 +21,10 @@ func Init(r *gin.Engine) {
 			user.POST("/submit", u.SubmitSurvey)
 			user.GET("/get", u.GetSurvey)
 			user.GET("/statistic", u.GetSurveyStatistics)
+			user.GET("/statistic/stream", u.StreamSurveyStatistics)
+			user.GET("/receipt", u.CheckReceipt)
+			user.GET("/draw", u.GetDraw)
+			user.POST("/event", u.RecordVisitEvent)
 			user.POST("/upload/img", u.UploadImg)
 			user.POST("/upload/file", u.UploadFile)
 			user.POST("/oauth", u.Oauth)
@@ -36,7 +40,9 @@ func Init(r *gin.Engine) {
 			admin.PUT("/update/status", a.UpdateSurveyStatus)
 			admin.PUT("/update/questions", a.UpdateSurvey)
 			admin.GET("/list/answers", a.GetSurveyAnswers)
+			admin.GET("/list/answers/stream", a.StreamAnswerSheets)
 			admin.GET("/statics/answers", a.GetSurveyStatistics)
+			admin.GET("/statics/stream", a.StreamSurveyStatistics)
 			admin.DELETE("/delete", a.DeleteSurvey)
 			admin.DELETE("/delete/answersheet", a.DeleteAnswerSheet)
 
@@ -47,6 +53,54 @@ func Init(r *gin.Engine) {
 			admin.GET("/single/question", a.GetSurvey)
 			admin.GET("/download", a.DownloadFile)
 			admin.GET("/download/chooseStatics", a.DownloadChooseFile)
+			admin.GET("/tally", a.GetSurveyTally)
+			admin.GET("/ledger/verify", a.VerifyLedger)
+
+			admin.POST("/analysis/crosstab", a.GetCrossTab)
+			admin.POST("/analysis/crosstab/export", a.ExportCrossTab)
+			admin.GET("/analysis/timeline", a.GetSubmissionTimeline)
+			admin.GET("/analysis/heatmap", a.GetSubmissionHeatmap)
+			admin.GET("/analysis/funnel", a.GetFunnel)
+			admin.POST("/weighting", a.SetWeighting)
+			admin.GET("/weighting", a.GetWeighting)
+			admin.DELETE("/weighting", a.DeleteWeighting)
+
+			admin.POST("/study/create", a.CreateStudy)
+			admin.PUT("/study/update", a.UpdateStudy)
+			admin.DELETE("/study/delete", a.DeleteStudy)
+			admin.GET("/study/list", a.GetStudies)
+			admin.GET("/study/get", a.GetStudy)
+			admin.GET("/study/trend", a.GetStudyTrend)
+			admin.GET("/study/export", a.ExportStudy)
+
+			admin.POST("/draw/create", a.CreateDraw)
+			admin.POST("/draw/run", a.RunDraw)
+			admin.GET("/draw/list", a.GetDraws)
+			admin.GET("/draw/get", a.GetDraw)
+			admin.GET("/draw/export", a.ExportDraw)
+			admin.DELETE("/draw/delete", a.DeleteDraw)
+
+			admin.POST("/bank/create", a.CreateBankQuestion)
+			admin.PUT("/bank/update", a.UpdateBankQuestion)
+			admin.DELETE("/bank/delete", a.DeleteBankQuestion)
+			admin.GET("/bank/list", a.GetBankQuestions)
+			admin.GET("/bank/get", a.GetBankQuestion)
+
+			admin.POST("/optionset/create", a.CreateOptionSet)
+			admin.PUT("/optionset/update", a.UpdateOptionSet)
+			admin.DELETE("/optionset/delete", a.DeleteOptionSet)
+			admin.GET("/optionset/list", a.GetOptionSets)
+			admin.GET("/optionset/get", a.GetOptionSet)
+
+			admin.GET("/template/list", a.GetTemplates)
+			admin.GET("/template/get", a.GetTemplate)
+			admin.POST("/template/promote", a.PromoteTemplate)
+			admin.DELETE("/template/delete", a.DeleteTemplate)
+			admin.POST("/template/use", a.CreateSurveyFromTemplate)
+
+			admin.GET("/translation/export", a.ExportTranslation)
+			admin.POST("/translation/import", a.ImportTranslation)
+			admin.DELETE("/translation/delete", a.DeleteTranslation)
 		}
 	}
 }
@@ -94,41 +94,36 @@ func CheckPermission(id int, surveyID int64) error {
 }
 
 // CreateSurvey 创建问卷
-func CreateSurvey(id int, question_list []dao.QuestionList, status int, surveyType, limit uint,
-	sumLimit uint, verify, undergradOnly bool, ddl, startTime time.Time, title string, desc string,
-	neednot bool) error {
-	var survey model.Survey
+func CreateSurvey(id int, status int, surveyType uint, base dao.BaseConfig, config dao.QuestionConfig,
+	ddl, startTime time.Time) (int64, error) {
+	survey := newSurvey(surveyType, base, config, ddl, startTime)
 	survey.ID = idgen.NextId()
 	survey.UserID = id
 	survey.Status = status
-	survey.Deadline = ddl
-	survey.Type = surveyType
-	survey.DailyLimit = limit
-	survey.SumLimit = sumLimit
-	survey.Verify = verify
-	survey.UndergradOnly = undergradOnly
-	survey.StartTime = startTime
-	survey.Title = title
-	survey.Desc = desc
-	survey.NeedNotify = neednot
 	survey, err := d.CreateSurvey(ctx, survey)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	_, err = createQuestionsAndOptions(question_list, survey.ID)
-	return err
+	sectionIDs, err := createSections(config.Sections, survey.ID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = createQuestionsAndOptions(config.QuestionList, sectionIDs, survey.ID)
+	return survey.ID, err
 }
 
 // UpdateSurveyStatus 更新问卷状态
 func UpdateSurveyStatus(id int64, status int) error {
 	err := d.UpdateSurveyStatus(ctx, id, status)
+	if err == nil && status == 3 {
+		CloseBallots(id)
+	}
 	return err
 }
 
 // UpdateSurvey 更新问卷
-func UpdateSurvey(id int64, question_list []dao.QuestionList, surveyType,
-	limit uint, sumLimit uint, verify, undergradOnly bool, desc string, title string, ddl, startTime time.Time,
-	needNotify bool) error {
+func UpdateSurvey(id int64, surveyType uint, base dao.BaseConfig, config dao.QuestionConfig,
+	ddl, startTime time.Time) error {
 	// 遍历原有问题，删除对应选项
 	var oldQuestions []model.Question
 	var old_imgs []string
@@ -168,13 +163,22 @@ func UpdateSurvey(id int64, question_list []dao.QuestionList, surveyType,
 		}
 	}
 	// 修改问卷信息
-	err = d.UpdateSurvey(ctx, id, surveyType, limit, sumLimit, verify, undergradOnly, desc, title, ddl, startTime,
-		needNotify)
+	survey := newSurvey(surveyType, base, config, ddl, startTime)
+	survey.ID = id
+	err = d.UpdateSurvey(ctx, survey)
+	if err != nil {
+		return err
+	}
+	// 重新添加分页、问题和选项
+	err = d.DeleteSectionsBySurveyID(ctx, id)
+	if err != nil {
+		return err
+	}
+	sectionIDs, err := createSections(config.Sections, id)
 	if err != nil {
 		return err
 	}
-	// 重新添加问题和选项
-	imgs, err := createQuestionsAndOptions(question_list, id)
+	imgs, err := createQuestionsAndOptions(config.QuestionList, sectionIDs, id)
 	if err != nil {
 		return err
 	}
@@ -192,6 +196,28 @@ func UpdateSurvey(id int64, question_list []dao.QuestionList, surveyType,
 	return nil
 }
 
+func newSurvey(surveyType uint, base dao.BaseConfig, config dao.QuestionConfig, ddl, startTime time.Time) model.Survey {
+	return model.Survey{
+		Title:            config.Title,
+		Desc:             config.Desc,
+		StartTime:        startTime,
+		Deadline:         ddl,
+		DailyLimit:       base.DailyLimit,
+		SumLimit:         base.SumLimit,
+		Verify:           base.Verify,
+		UndergradOnly:    base.UndergradOnly,
+		Type:             surveyType,
+		NeedNotify:       base.NeedNotify,
+		ShuffleQuestions: base.ShuffleQuestions,
+		HiddenFields:     base.HiddenFields,
+		DefaultLang:      base.DefaultLang,
+		Completion:       base.Completion,
+		Theme:            base.Theme,
+		ResultPolicy:     base.ResultPolicy,
+		Anonymous:        base.Anonymous,
+	}
+}
+
 // UserInManage 用户是否在管理中
 func UserInManage(uid int, sid int64) bool {
 	_, err := d.GetManageByUIDAndSID(ctx, uid, sid)
@@ -206,7 +232,7 @@ func DeleteSurvey(id int64) error {
 		return err
 	}
 	var answerSheets []dao.AnswerSheet
-	answerSheets, _, err = d.GetAnswerSheetBySurveyID(ctx, id, 0, 0, "", false)
+	answerSheets, _, err = d.GetAnswerSheetBySurveyID(ctx, id, 0, 0, "", false, nil)
 	if err != nil {
 		return err
 	}
@@ -247,7 +273,31 @@ func DeleteSurvey(id int64) error {
 	if err != nil {
 		return err
 	}
-	// 删除问题、选项、问卷、管理
+	err = appendLedger(dao.LedgerEntry{SurveyID: id, Type: LedgerPurge})
+	if err != nil {
+		return err
+	}
+	err = d.DeleteStatsCounters(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = d.DeleteDrawsBySurveyID(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = d.DeleteVisitsBySurveyID(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = d.DeleteWeighting(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = d.DeleteStudyWavesBySurveyID(ctx, id)
+	if err != nil {
+		return err
+	}
+	// 删除问题、选项、分页、译文、问卷、管理
 	for _, question := range questions {
 		err = d.DeleteOption(ctx, question.ID)
 		if err != nil {
@@ -266,6 +316,14 @@ func DeleteSurvey(id int64) error {
 	if err != nil {
 		return err
 	}
+	err = d.DeleteSectionsBySurveyID(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = d.DeleteTranslationsBySurveyID(ctx, id)
+	if err != nil {
+		return err
+	}
 	err = d.DeleteSurvey(ctx, id)
 	if err != nil {
 		return err
@@ -275,11 +333,13 @@ func DeleteSurvey(id int64) error {
 }
 
 // GetSurveyAnswers 获取问卷答案
-func GetSurveyAnswers(id int64, num int, size int, text string, unique bool) (dao.AnswersResonse, *int64, error) {
+func GetSurveyAnswers(id int64, num int, size int, text string, unique bool, hidden map[string]string) (
+	dao.AnswersResonse, *int64, error) {
 	var answerSheets []dao.AnswerSheet
 	data := make([]dao.QuestionAnswers, 0)
 	times := make([]string, 0)
 	aids := make([]primitive.ObjectID, 0)
+	hiddenFields := make([]map[string]string, 0)
 	var total *int64
 	// 获取问题
 	questions, err := d.GetQuestionsBySurveyID(ctx, id)
@@ -295,7 +355,7 @@ func GetSurveyAnswers(id int64, num int, size int, text string, unique bool) (da
 		data = append(data, q)
 	}
 	// 获取答卷
-	answerSheets, total, err = d.GetAnswerSheetBySurveyID(ctx, id, num, size, text, unique)
+	answerSheets, total, err = d.GetAnswerSheetBySurveyID(ctx, id, num, size, text, unique, hidden)
 	if err != nil {
 		return dao.AnswersResonse{}, nil, err
 	}
@@ -303,6 +363,7 @@ func GetSurveyAnswers(id int64, num int, size int, text string, unique bool) (da
 	for _, answerSheet := range answerSheets {
 		times = append(times, answerSheet.Time)
 		aids = append(aids, answerSheet.AnswerID)
+		hiddenFields = append(hiddenFields, answerSheet.HiddenFields)
 		for _, answer := range answerSheet.Answers {
 			question, err := d.GetQuestionByID(ctx, answer.QuestionID)
 			if err != nil {
@@ -315,7 +376,8 @@ func GetSurveyAnswers(id int64, num int, size int, text string, unique bool) (da
 			}
 		}
 	}
-	return dao.AnswersResonse{QuestionAnswers: data, AnswerIDs: aids, Time: times}, total, nil
+	return dao.AnswersResonse{QuestionAnswers: data, AnswerIDs: aids, Time: times, HiddenFields: hiddenFields},
+		total, nil
 }
 
 // GetSurveyByUserID 获取用户的所有问卷
@@ -417,22 +479,33 @@ func GetAllSurveyAnswers(id int64) (dao.AnswersResonse, error) {
 	answerSheets := make([]dao.AnswerSheet, 0)
 	questions := make([]model.Question, 0)
 	times := make([]string, 0)
+	hiddenFields := make([]map[string]string, 0)
 	questions, err := d.GetQuestionsBySurveyID(ctx, id)
 	if err != nil {
 		return dao.AnswersResonse{}, err
 	}
+	// 引用了前面答案的题目额外导出一列答题者看到的题面
+	pipedIndex := make(map[int]int)
 	for _, question := range questions {
 		var q dao.QuestionAnswers
 		q.Title = question.Subject
 		q.QuestionType = question.QuestionType
 		data = append(data, q)
+		if len(GetQuestionPipes(question)) != 0 {
+			pipedIndex[question.ID] = len(data)
+			data = append(data, dao.QuestionAnswers{
+				Title:        question.Subject + "（答题者看到的题面）",
+				QuestionType: question.QuestionType,
+			})
+		}
 	}
-	answerSheets, _, err = d.GetAnswerSheetBySurveyID(ctx, id, 0, 0, "", true)
+	answerSheets, _, err = d.GetAnswerSheetBySurveyID(ctx, id, 0, 0, "", true, nil)
 	if err != nil {
 		return dao.AnswersResonse{}, err
 	}
 	for _, answerSheet := range answerSheets {
 		times = append(times, answerSheet.Time)
+		hiddenFields = append(hiddenFields, answerSheet.HiddenFields)
 		for _, answer := range answerSheet.Answers {
 			question, err := d.GetQuestionByID(ctx, answer.QuestionID)
 			if err != nil {
@@ -443,14 +516,17 @@ func GetAllSurveyAnswers(id int64) (dao.AnswersResonse, error) {
 					data[i].Answers = append(data[i].Answers, answer.Content)
 				}
 			}
+			if i, ok := pipedIndex[answer.QuestionID]; ok {
+				data[i].Answers = append(data[i].Answers, answer.Subject)
+			}
 		}
 	}
-	return dao.AnswersResonse{QuestionAnswers: data, Time: times}, nil
+	return dao.AnswersResonse{QuestionAnswers: data, Time: times, HiddenFields: hiddenFields}, nil
 }
 
-// GetSurveyAnswersBySurveyID 根据问卷编号获取问卷答案
-func GetSurveyAnswersBySurveyID(sid int64) ([]dao.AnswerSheet, error) {
-	answerSheets, _, err := d.GetAnswerSheetBySurveyID(ctx, sid, 0, 0, "", true)
+// GetSurveyAnswersBySurveyID 根据问卷编号获取问卷答案，可按隐藏字段筛选
+func GetSurveyAnswersBySurveyID(sid int64, hidden map[string]string) ([]dao.AnswerSheet, error) {
+	answerSheets, _, err := d.GetAnswerSheetBySurveyID(ctx, sid, 0, 0, "", true, hidden)
 	return answerSheets, err
 }
 
@@ -526,7 +602,7 @@ func getDelFiles(answerSheets []dao.AnswerSheet) ([]string, error) {
 	return files, nil
 }
 
-func createQuestionsAndOptions(question_list []dao.QuestionList, sid int64) ([]string, error) {
+func createQuestionsAndOptions(question_list []dao.QuestionList, sectionIDs map[int]int, sid int64) ([]string, error) {
 	imgs := make([]string, 0)
 	for _, question_list := range question_list {
 		var q model.Question
@@ -542,11 +618,28 @@ func createQuestionsAndOptions(question_list []dao.QuestionList, sid int64) ([]s
 		q.MaximumOption = question_list.QuestionSetting.MaximumOption
 		q.MinimumOption = question_list.QuestionSetting.MinimumOption
 		q.Reg = question_list.QuestionSetting.Reg
+		if question_list.BankRef {
+			q.BankQuestionID = question_list.BankID
+		}
+		q.OptionSetID = question_list.QuestionSetting.OptionSetID
+		q.OptionSetVersion = question_list.QuestionSetting.OptionSetVersion
+		q.SectionID = sectionIDs[question_list.Section]
+		q.ShuffleOptions = question_list.QuestionSetting.ShuffleOptions
+		q.Pinned = question_list.QuestionSetting.Pinned
+		q.Expression = question_list.QuestionSetting.Expression
+		q.TallyMethod = question_list.QuestionSetting.TallyMethod
+		q.TieBreak = question_list.QuestionSetting.TieBreak
+		q.MaxScore = question_list.QuestionSetting.MaxScore
+		q.Numeric = question_list.QuestionSetting.Numeric
 		imgs = append(imgs, question_list.Img)
 		q, err := d.CreateQuestion(ctx, q)
 		if err != nil {
 			return nil, err
 		}
+		// 绑定选项集的问题不保存自身选项
+		if q.OptionSetID != 0 {
+			continue
+		}
 		for _, option := range question_list.Options {
 			var o model.Option
 			o.Content = option.Content
@@ -554,6 +647,7 @@ func createQuestionsAndOptions(question_list []dao.QuestionList, sid int64) ([]s
 			o.SerialNum = option.SerialNum
 			o.Img = option.Img
 			o.Description = option.Description
+			o.Pinned = option.Pinned
 			imgs = append(imgs, option.Img)
 			err := d.CreateOption(ctx, o)
 			if err != nil {
@@ -599,11 +693,21 @@ func HandleDownloadFile(answers dao.AnswersResonse, survey *model.Survey) (strin
 	maxWidths := make(map[int]int)
 	maxWidths[0] = 7
 	maxWidths[1] = 20
+	// 隐藏字段列位于提交时间之后
+	offset := 2 + len(survey.HiddenFields)
+	for k, name := range survey.HiddenFields {
+		maxWidths[k+2] = len(name)
+		for _, fields := range answers.HiddenFields {
+			if len(fields[name]) > maxWidths[k+2] {
+				maxWidths[k+2] = len(fields[name])
+			}
+		}
+	}
 	for i, qa := range questionAnswers {
-		maxWidths[i+2] = len(qa.Title)
+		maxWidths[i+offset] = len(qa.Title)
 		for _, answer := range qa.Answers {
-			if len(answer) > maxWidths[i+2] {
-				maxWidths[i+2] = len(answer)
+			if len(answer) > maxWidths[i+offset] {
+				maxWidths[i+offset] = len(answer)
 			}
 		}
 	}
@@ -620,6 +724,9 @@ func HandleDownloadFile(answers dao.AnswersResonse, survey *model.Survey) (strin
 	rowData := make([]any, 0)
 	rowData = append(rowData, excelize.Cell{Value: "序号", StyleID: styleID},
 		excelize.Cell{Value: "提交时间", StyleID: styleID})
+	for _, name := range survey.HiddenFields {
+		rowData = append(rowData, excelize.Cell{Value: name, StyleID: styleID})
+	}
 	for _, qa := range questionAnswers {
 		rowData = append(rowData, excelize.Cell{Value: qa.Title, StyleID: styleID})
 	}
@@ -629,13 +736,20 @@ func HandleDownloadFile(answers dao.AnswersResonse, survey *model.Survey) (strin
 	// 写入数据
 	for i, t := range times {
 		row := []any{i + 1, t}
+		for _, name := range survey.HiddenFields {
+			value := ""
+			if i < len(answers.HiddenFields) {
+				value = answers.HiddenFields[i][name]
+			}
+			row = append(row, value)
+		}
 		for j, qa := range questionAnswers {
 			if len(qa.Answers) <= i {
 				continue
 			}
 			answer := qa.Answers[i]
 			row = append(row, answer)
-			colName, err := excelize.ColumnNumberToName(j + 3)
+			colName, err := excelize.ColumnNumberToName(j + offset + 1)
 			if err != nil {
 				return "", errors.New("转换列名失败原因: " + err.Error())
 			}
@@ -715,24 +829,43 @@ func DeleteOauthRecord(sid int64) error {
 	return d.DeleteRecordSheets(ctx, sid)
 }
 
-// DeleteAnswerSheetByAnswerID 根据问卷ID删除问卷
-func DeleteAnswerSheetByAnswerID(answerID primitive.ObjectID) error {
-	err := d.DeleteAnswerSheetByAnswerID(ctx, answerID)
-	return err
+// DeleteAnswerSheetByAnswerID 删除答卷并记入哈希链，operator 为执行删除的管理员ID
+func DeleteAnswerSheetByAnswerID(answerSheet *dao.AnswerSheet, operator int) error {
+	digest, err := digestAnswerSheet(*answerSheet)
+	if err != nil {
+		return err
+	}
+	err = d.DeleteAnswerSheetByAnswerID(ctx, answerSheet.AnswerID)
+	if err != nil {
+		return err
+	}
+	if answerSheet.Unique {
+		updateStatsCounters(*answerSheet, -1)
+	}
+	return appendLedger(dao.LedgerEntry{
+		SurveyID: answerSheet.SurveyID,
+		Type:     LedgerDelete,
+		AnswerID: answerSheet.AnswerID,
+		Digest:   digest,
+		Operator: operator,
+	})
 }
 
-// GetAnswerSheetByAnswerID 根据答卷ID删除答卷
-func GetAnswerSheetByAnswerID(answerID primitive.ObjectID) error {
-	err := d.GetAnswerSheetByAnswerID(ctx, answerID)
-	return err
+// GetAnswerSheetByAnswerID 根据答卷ID获取答卷
+func GetAnswerSheetByAnswerID(answerID primitive.ObjectID) (*dao.AnswerSheet, error) {
+	return d.GetAnswerSheetByAnswerID(ctx, answerID)
 }
 
 // GetOptionCount 选项数据
 type GetOptionCount struct {
-	SerialNum int    `json:"serial_num"` // 选项序号
-	Content   string `json:"content"`    // 选项内容
-	Count     int    `json:"count"`      // 选项数量
-	Percent   string `json:"percent"`    // 占比百分比，保留两位小数
+	SerialNum int      `json:"serial_num"` // 选项序号
+	Content   string   `json:"content"`    // 选项内容
+	Count     int      `json:"count"`      // 选项数量
+	Percent   string   `json:"percent"`    // 占比百分比，保留两位小数
+	CI        Interval `json:"ci"`         // 占比的 95% 置信区间，多选题的每个选项按一次计入样本量
+
+	WeightedPercent string    `json:"weighted_percent,omitempty"` // 加权后的占比 问卷设置加权时才有
+	WeightedCI      *Interval `json:"weighted_ci,omitempty"`      // 加权占比的 95% 置信区间，按有效样本量计算
 }
 
 // GetChooseStatisticsResponse 问题模型
@@ -743,27 +876,70 @@ type GetChooseStatisticsResponse struct {
 	Options      []GetOptionCount `json:"options"`       // 选项内容
 }
 
-// GenerateQuestionStats 生成问卷题目统计结果
-func GenerateQuestionStats(questions []model.Question, answerSheets []dao.AnswerSheet) []GetChooseStatisticsResponse {
-	questionMap := make(map[int]model.Question)
-	optionsMap := make(map[int][]model.Option)
-	optionAnswerMap := make(map[int]map[string]model.Option)
-	optionSerialNumMap := make(map[int]map[int]model.Option)
+// questionOptions 统计时使用的问题和选项
+type questionOptions struct {
+	questionMap        map[int]model.Question
+	optionsMap         map[int][]model.Option
+	optionAnswerMap    map[int]map[string]model.Option
+	optionSerialNumMap map[int]map[int]model.Option
+}
+
+// loadQuestionOptions 获取问题的选项，获取失败的问题没有选项，并返回第一个错误
+func loadQuestionOptions(questions []model.Question) (questionOptions, error) {
+	q := newQuestionOptions()
+	var firstErr error
 	for _, question := range questions {
-		questionMap[question.ID] = question
-		optionAnswerMap[question.ID] = make(map[string]model.Option)
-		optionSerialNumMap[question.ID] = make(map[int]model.Option)
-		options, err := GetOptionsByQuestionID(question.ID)
+		options, err := GetQuestionOptions(question)
 		if err != nil {
 			log.Println("Error fetching options for questionID:", question.ID)
-			continue
-		}
-		optionsMap[question.ID] = options
-		for _, option := range options {
-			optionAnswerMap[question.ID][option.Content] = option
-			optionSerialNumMap[question.ID][option.SerialNum] = option
+			if firstErr == nil {
+				firstErr = err
+			}
+			options = nil
 		}
+		q.add(question, options)
+	}
+	return q, firstErr
+}
+
+func newQuestionOptions() questionOptions {
+	return questionOptions{
+		questionMap:        make(map[int]model.Question),
+		optionsMap:         make(map[int][]model.Option),
+		optionAnswerMap:    make(map[int]map[string]model.Option),
+		optionSerialNumMap: make(map[int]map[int]model.Option),
 	}
+}
+
+// add 加入问题及其选项，options 为空时问题没有选项
+func (q questionOptions) add(question model.Question, options []model.Option) {
+	q.questionMap[question.ID] = question
+	q.optionAnswerMap[question.ID] = make(map[string]model.Option)
+	q.optionSerialNumMap[question.ID] = make(map[int]model.Option)
+	if options == nil {
+		return
+	}
+	q.optionsMap[question.ID] = options
+	for _, option := range options {
+		q.optionAnswerMap[question.ID][option.Content] = option
+		q.optionSerialNumMap[question.ID][option.SerialNum] = option
+	}
+}
+
+// GenerateQuestionStats 生成问卷题目统计结果
+//
+// 该方法在内存中逐份统计答卷，作为 AggregateQuestionStats 的参照实现，由 stats-verify 命令比对两者结果。
+func GenerateQuestionStats(questions []model.Question, answerSheets []dao.AnswerSheet) []GetChooseStatisticsResponse {
+	// 获取失败的问题只统计为其他选项，与之前的行为一致
+	loaded, _ := loadQuestionOptions(questions) //nolint:errcheck
+	return generateQuestionStats(loaded, answerSheets)
+}
+
+// generateQuestionStats 在内存中逐份统计答卷的单选题和多选题
+func generateQuestionStats(loaded questionOptions, answerSheets []dao.AnswerSheet) []GetChooseStatisticsResponse {
+	questionMap := loaded.questionMap
+	optionsMap := loaded.optionsMap
+	optionAnswerMap := loaded.optionAnswerMap
 
 	optionCounts := make(map[int]map[int]int)
 	for _, sheet := range answerSheets {
@@ -801,6 +977,13 @@ func GenerateQuestionStats(questions []model.Question, answerSheets []dao.Answer
 			}
 		}
 	}
+	return formatQuestionStats(loaded, optionCounts)
+}
+
+// formatQuestionStats 根据各题选项的次数生成统计结果，选项序号 0 为其他选项
+func formatQuestionStats(loaded questionOptions, optionCounts map[int]map[int]int) []GetChooseStatisticsResponse {
+	questionMap := loaded.questionMap
+	optionSerialNumMap := loaded.optionSerialNumMap
 	response := make([]GetChooseStatisticsResponse, 0, len(optionCounts))
 	for qid, optionCountMap := range optionCounts {
 		q := questionMap[qid]
@@ -819,6 +1002,7 @@ func GenerateQuestionStats(questions []model.Question, answerSheets []dao.Answer
 				Content:   "其他",
 				Count:     optionCountMap[0],
 				Percent:   fmt.Sprintf("%.2f%%", float64(optionCountMap[0])*100/float64(total)),
+				CI:        shareInterval(optionCountMap[0], total),
 			})
 		}
 
@@ -843,6 +1027,7 @@ func GenerateQuestionStats(questions []model.Question, answerSheets []dao.Answer
 				Content:   op.Content,
 				Count:     count,
 				Percent:   percent,
+				CI:        shareInterval(count, total),
 			})
 		}
 
@@ -860,17 +1045,24 @@ func GenerateQuestionStats(questions []model.Question, answerSheets []dao.Answer
 	return response
 }
 
-// HandleChooseStatistics 导出投票结果
-func HandleChooseStatistics(survey *model.Survey, response []GetChooseStatisticsResponse) (string, error) {
+// HandleChooseStatistics 导出投票结果，包括各题的计票结果
+func HandleChooseStatistics(survey *model.Survey, response []GetChooseStatisticsResponse,
+	tallies []QuestionTally, weighting *WeightingSummary) (string, error) {
 	sheets := make([]excel.Sheet, 0, len(response))
 
 	for _, stat := range response {
 		sheetName := fmt.Sprintf("第%d题", stat.SerialNum)
-		headers := []string{"选项内容", "票数", "百分比"}
+		headers := []string{"选项内容", "票数", "百分比", "95%置信区间"}
+		if weighting != nil {
+			headers = append(headers, "加权百分比", "加权95%置信区间")
+		}
 		var rows [][]any
 
 		for _, opt := range stat.Options {
-			row := []any{opt.Content, opt.Count, opt.Percent}
+			row := []any{opt.Content, opt.Count, opt.Percent, opt.CI.String()}
+			if opt.WeightedCI != nil {
+				row = append(row, opt.WeightedPercent, opt.WeightedCI.String())
+			}
 			rows = append(rows, row)
 		}
 
@@ -881,9 +1073,16 @@ func HandleChooseStatistics(survey *model.Survey, response []GetChooseStatistics
 		}
 		sheets = append(sheets, sheet)
 	}
+	sheets = append(sheets, getTallySheets(tallies)...)
+	if weighting != nil {
+		sheets = append(sheets, getWeightingSheet(weighting))
+	}
+	return createExcelFile(sheets, survey.Title+".xlsx")
+}
 
+// createExcelFile 在 public/xlsx 下创建 Excel 文件并返回下载链接，同名的旧文件会被覆盖
+func createExcelFile(sheets []excel.Sheet, fileName string) (string, error) {
 	fileData := excel.File{Sheets: sheets}
-	fileName := survey.Title + ".xlsx"
 	filePath := "./public/xlsx/"
 
 	// 创建目录
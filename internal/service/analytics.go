@@ -0,0 +1,195 @@
+package service
+
+import (
+	"math"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/describe"
+	"QA-System/internal/pkg/wordfreq"
+)
+
+const defaultTopWords = 100 // 默认返回的高频词数量
+
+// NumericAnalytics 数值填空题的统计
+type NumericAnalytics struct {
+	describe.Summary
+	Invalid   int            `json:"invalid"` // 不是数字的答案数
+	Histogram []describe.Bin `json:"histogram"`
+}
+
+// TextAnalytics 文本答案的统计
+type TextAnalytics struct {
+	Count           int              `json:"count"`
+	Words           []wordfreq.Word  `json:"words"`  // 按出现次数从高到低排列的高频词
+	Length          describe.Summary `json:"length"` // 答案字数
+	LengthHistogram []describe.Bin   `json:"length_histogram"`
+}
+
+// AnswerAnalytics 填空题和简答题的答案统计
+type AnswerAnalytics struct {
+	SerialNum    int               `json:"serial_num"`
+	Question     string            `json:"question"`
+	QuestionType int               `json:"question_type"`     // 3:填空 4:简答
+	Numeric      *NumericAnalytics `json:"numeric,omitempty"` // 数值填空题
+	Text         *TextAnalytics    `json:"text,omitempty"`    // 其余填空题和简答题
+}
+
+// GetAnswerAnalytics 统计问卷填空题和简答题的有效答案，按题目序号排列
+//
+// 设置为数值或正则表达式只能匹配数字的填空题计算描述统计和直方图，其余统计词频和字数。
+func GetAnswerAnalytics(sid int64, questions []model.Question, hidden map[string]string) ([]AnswerAnalytics, error) {
+	ids := questionIDsOfTypes(questions, 3, 4)
+	analytics := make([]AnswerAnalytics, 0, len(ids))
+	if len(ids) == 0 {
+		return analytics, nil
+	}
+	contents, err := d.GetAnswerContents(ctx, sid, hidden, ids)
+	if err != nil {
+		return nil, err
+	}
+	answers := make(map[int][]string)
+	for _, content := range contents {
+		answers[content.QuestionID] = append(answers[content.QuestionID], content.Content)
+	}
+	limit := global.Config.GetInt("analysis.topWords")
+	if limit <= 0 {
+		limit = defaultTopWords
+	}
+	for _, question := range questions {
+		if question.QuestionType != 3 && question.QuestionType != 4 {
+			continue
+		}
+		item := AnswerAnalytics{
+			SerialNum:    question.SerialNum,
+			Question:     question.Subject,
+			QuestionType: question.QuestionType,
+		}
+		if IsNumericQuestion(question) {
+			item.Numeric = numericAnalytics(answers[question.ID])
+		} else {
+			item.Text, err = textAnalytics(answers[question.ID], limit)
+			if err != nil {
+				return nil, err
+			}
+		}
+		analytics = append(analytics, item)
+	}
+	sort.SliceStable(analytics, func(i, j int) bool {
+		return analytics[i].SerialNum < analytics[j].SerialNum
+	})
+	return analytics, nil
+}
+
+// IsNumericQuestion 判断填空题的答案是否为数值，即设置为数值或正则表达式只能匹配数字
+func IsNumericQuestion(question model.Question) bool {
+	if question.QuestionType != 3 {
+		return false
+	}
+	return question.Numeric || numericPattern(question.Reg)
+}
+
+// maxNumber 数值答案的最大绝对值，超出后 float64 不能精确表示整数
+const maxNumber = 1e15
+
+// IsNumber 判断答案是否为绝对值不超过 maxNumber 的数字
+func IsNumber(s string) bool {
+	_, ok := parseNumber(s)
+	return ok
+}
+
+func numericAnalytics(answers []string) *NumericAnalytics {
+	values := make([]float64, 0, len(answers))
+	invalid := 0
+	for _, answer := range answers {
+		if v, ok := parseNumber(answer); ok {
+			values = append(values, v)
+		} else {
+			invalid++
+		}
+	}
+	return &NumericAnalytics{
+		Summary:   describe.Summarize(values),
+		Invalid:   invalid,
+		Histogram: describe.Histogram(values),
+	}
+}
+
+func textAnalytics(answers []string, limit int) (*TextAnalytics, error) {
+	words, err := wordfreq.Count(answers, limit)
+	if err != nil {
+		return nil, err
+	}
+	lengths := make([]float64, 0, len(answers))
+	for _, answer := range answers {
+		lengths = append(lengths, float64(utf8.RuneCountInString(answer)))
+	}
+	return &TextAnalytics{
+		Count:           len(answers),
+		Words:           words,
+		Length:          describe.Summarize(lengths),
+		LengthHistogram: describe.Histogram(lengths),
+	}, nil
+}
+
+func parseNumber(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(v) || math.Abs(v) > maxNumber {
+		return 0, false
+	}
+	return v, true
+}
+
+// numericPattern 判断正则表达式是否只能匹配由数字、小数点和正负号组成的文本，且至少包含数字
+func numericPattern(reg string) bool {
+	if reg == "" {
+		return false
+	}
+	re, err := syntax.Parse(reg, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	hasDigit := false
+	var walk func(re *syntax.Regexp) bool
+	walk = func(re *syntax.Regexp) bool {
+		switch re.Op {
+		case syntax.OpLiteral:
+			for _, r := range re.Rune {
+				if !numericRune(r) {
+					return false
+				}
+				hasDigit = hasDigit || ('0' <= r && r <= '9')
+			}
+		case syntax.OpCharClass:
+			for i := 0; i+1 < len(re.Rune); i += 2 {
+				for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+					if !numericRune(r) {
+						return false
+					}
+					hasDigit = hasDigit || ('0' <= r && r <= '9')
+				}
+			}
+		case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText:
+		case syntax.OpCapture, syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat,
+			syntax.OpConcat, syntax.OpAlternate:
+			for _, sub := range re.Sub {
+				if !walk(sub) {
+					return false
+				}
+			}
+		default:
+			return false
+		}
+		return true
+	}
+	return walk(re) && hasDigit
+}
+
+func numericRune(r rune) bool {
+	return ('0' <= r && r <= '9') || r == '.' || r == '-' || r == '+'
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"QA-System/internal/dao"
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBallotBatchSize     = 20               // 默认每批写入的匿名选票数量
+	defaultBallotFlushInterval = 30 * time.Minute // 默认匿名选票的写入间隔，也是答卷时间的精度
+	defaultBallotMinFlush      = 5                // 默认定时写入时最少的暂存选票数量
+)
+
+// 回执查询结果
+const (
+	ReceiptCounted  = "counted"   // 选票已计入答卷
+	ReceiptPending  = "pending"   // 选票等待成批写入
+	ReceiptNotFound = "not_found" // 回执不存在
+)
+
+// CheckAnonymous 检查匿名投票设置，匿名投票需要统一验证来记录是否已投票，
+// 且不能保存可能识别投票者的隐藏字段或答卷ID
+func CheckAnonymous(base dao.BaseConfig) error {
+	if !base.Anonymous {
+		return nil
+	}
+	if !base.Verify {
+		return errors.New("匿名投票需要开启统一验证")
+	}
+	if len(base.HiddenFields) > 0 {
+		return errors.New("匿名投票不能设置隐藏字段")
+	}
+	for _, match := range redirectPattern.FindAllStringSubmatch(base.Completion.RedirectURL, -1) {
+		if match[1] == "answer_id" {
+			return errors.New("匿名投票的跳转链接不能引用答卷ID")
+		}
+	}
+	return nil
+}
+
+// SubmitAnonymousBallot 暂存匿名选票并返回回执码
+//
+// 选票不记录题目顺序、隐藏字段和提交时间，暂存的选票达到一批后打乱顺序写入，
+// 答卷ID和答卷时间在写入时生成，无法与统一验证记录对应。
+func SubmitAnonymousBallot(survey *model.Survey, data []dao.QuestionsList, order *RespondentOrder,
+	lang string) (string, error) {
+	answerSheet, _, err := newAnswerSheet(survey.ID, data, order, lang)
+	if err != nil {
+		return "", err
+	}
+	for i := range answerSheet.Answers {
+		answerSheet.Answers[i].Position = 0
+		answerSheet.Answers[i].OptionOrder = nil
+	}
+	receipt, err := newReceipt()
+	if err != nil {
+		return "", err
+	}
+	answerSheet.Receipt = receiptDigest(receipt)
+	if err := d.BufferBallot(ctx, answerSheet); err != nil {
+		return "", err
+	}
+	if err := d.IncreaseSurveyNum(ctx, survey.ID); err != nil {
+		return "", err
+	}
+	count, err := d.CountBufferedBallots(ctx, survey.ID)
+	if err != nil {
+		return "", err
+	}
+	if count >= int64(getBallotBatchSize()) {
+		if err := FlushBallots(survey.ID); err != nil {
+			return "", err
+		}
+	}
+	return receipt, nil
+}
+
+// FlushBallots 将问卷暂存的匿名选票打乱顺序后写入答卷
+func FlushBallots(sid int64) error {
+	sheets, err := d.TakeBufferedBallots(ctx, sid)
+	if err != nil || len(sheets) == 0 {
+		return err
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, sid)
+	if err != nil {
+		return restoreBallots(sheets, err)
+	}
+	qids := make([]int, 0)
+	for _, question := range questions {
+		if question.QuestionType == 3 && question.Unique {
+			qids = append(qids, question.ID)
+		}
+	}
+	if err := shuffleBallots(sheets); err != nil {
+		return restoreBallots(sheets, err)
+	}
+	batchTime := time.Now().Truncate(getBallotFlushInterval())
+	receipts := make([]string, 0, len(sheets))
+	for i := range sheets {
+		sheets[i].AnswerID = primitive.NewObjectID()
+		sheets[i].Time = batchTime.Format(time.DateTime)
+		sheets[i].SubmittedAt = batchTime
+		replaced, err := d.SaveAnswerSheet(ctx, sheets[i], qids)
+		if err != nil {
+			if removeErr := d.RemovePendingReceipts(ctx, sid, receipts); removeErr != nil {
+				zap.L().Error("移除匿名选票回执失败", zap.Int64("survey_id", sid), zap.Error(removeErr))
+			}
+			return restoreBallots(sheets[i:], err)
+		}
+		receipts = append(receipts, sheets[i].Receipt)
+		if err := recordSubmission(sheets[i]); err != nil {
+			zap.L().Error("匿名选票记入哈希链失败", zap.Int64("survey_id", sid), zap.Error(err))
+		}
+		// 更新计数器时通知新答卷推送，需在记入哈希链之后
+		updateStatsCounters(sheets[i], 1)
+		if replaced != nil {
+			updateStatsCounters(*replaced, -1)
+		}
+	}
+	if err := d.RemovePendingReceipts(ctx, sid, receipts); err != nil {
+		return err
+	}
+	return FromSurveyIDToMsg(sid)
+}
+
+// CheckReceipt 根据回执码查询选票是否已计入
+func CheckReceipt(sid int64, receipt string) (string, error) {
+	digest := receiptDigest(receipt)
+	counted, err := d.HasReceipt(ctx, sid, digest)
+	if err != nil {
+		return "", err
+	}
+	if counted {
+		return ReceiptCounted, nil
+	}
+	pending, err := d.IsReceiptPending(ctx, sid, digest)
+	if err != nil {
+		return "", err
+	}
+	if pending {
+		return ReceiptPending, nil
+	}
+	return ReceiptNotFound, nil
+}
+
+// DeleteBufferedBallots 删除问卷暂存的匿名选票
+func DeleteBufferedBallots(sid int64) error {
+	return d.DeleteBufferedBallots(ctx, sid)
+}
+
+// StartBallotWorker 定时写入暂存的匿名选票，使不足一批的选票也能计入结果
+//
+// 暂存的选票少于最少数量时继续等待，避免一批中只有个别选票而能与统一验证记录对应，
+// 投票截止后不再有新选票，剩余的选票全部写入。
+func StartBallotWorker() {
+	go func() {
+		ticker := time.NewTicker(getBallotFlushInterval())
+		defer ticker.Stop()
+		for range ticker.C {
+			sids, err := d.GetBufferedSurveys(ctx)
+			if err != nil {
+				zap.L().Error("获取暂存匿名选票的问卷失败", zap.Error(err))
+				continue
+			}
+			for _, sid := range sids {
+				if err := flushBallotsOnTimer(sid); err != nil {
+					zap.L().Error("写入匿名选票失败", zap.Int64("survey_id", sid), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// CloseBallots 投票截止时写入问卷剩余的全部匿名选票
+func CloseBallots(sid int64) {
+	if err := FlushBallots(sid); err != nil {
+		zap.L().Error("写入匿名选票失败", zap.Int64("survey_id", sid), zap.Error(err))
+	}
+}
+
+// flushBallotsOnTimer 暂存的选票达到最少数量或投票已截止时写入
+func flushBallotsOnTimer(sid int64) error {
+	count, err := d.CountBufferedBallots(ctx, sid)
+	if err != nil {
+		return err
+	}
+	if count < int64(getBallotMinFlush()) {
+		survey, err := d.GetSurveyByID(ctx, sid)
+		if err != nil {
+			return err
+		}
+		if !votingClosed(survey) {
+			return nil
+		}
+	}
+	return FlushBallots(sid)
+}
+
+// votingClosed 判断问卷是否已截止
+func votingClosed(survey *model.Survey) bool {
+	return survey.Status == 3 || (!survey.Deadline.IsZero() && time.Now().After(survey.Deadline))
+}
+
+// restoreBallots 将未写入的选票放回暂存区
+func restoreBallots(sheets []dao.AnswerSheet, cause error) error {
+	for _, sheet := range sheets {
+		sheet.AnswerID = primitive.NilObjectID
+		sheet.Time = ""
+		if err := d.BufferBallot(ctx, sheet); err != nil {
+			return errors.Join(cause, err)
+		}
+	}
+	return cause
+}
+
+// shuffleBallots 使用安全随机数打乱选票顺序
+func shuffleBallots(sheets []dao.AnswerSheet) error {
+	for i := len(sheets) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return err
+		}
+		sheets[i], sheets[j.Int64()] = sheets[j.Int64()], sheets[i]
+	}
+	return nil
+}
+
+// newReceipt 生成形如 XXXX-XXXX-XXXX-XXXX 的回执码
+func newReceipt() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := base32.StdEncoding.EncodeToString(buf)
+	parts := make([]string, 0, 4)
+	for i := 0; i < len(code); i += 4 {
+		parts = append(parts, code[i:i+4])
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// receiptDigest 计算回执码的摘要，只保存摘要使答卷无法反推回执码
+func receiptDigest(receipt string) string {
+	normalized := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(receipt), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// getBallotBatchSize 每批写入的选票数量，不少于定时写入的最少数量
+func getBallotBatchSize() int {
+	size := global.Config.GetInt("anonymous.batchSize")
+	if size <= 0 {
+		size = defaultBallotBatchSize
+	}
+	return max(size, getBallotMinFlush())
+}
+
+func getBallotMinFlush() int {
+	if size := global.Config.GetInt("anonymous.minFlush"); size > 0 {
+		return size
+	}
+	return defaultBallotMinFlush
+}
+
+func getBallotFlushInterval() time.Duration {
+	if minutes := global.Config.GetInt("anonymous.flushInterval"); minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultBallotFlushInterval
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"strings"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+)
+
+// BankQuestionDetail 题库题目详情
+type BankQuestionDetail struct {
+	model.BankQuestion
+	Options []model.BankOption `json:"options"` // 选项
+	Tags    []string           `json:"tags"`    // 标签
+}
+
+// CanViewBankQuestion 判断管理员是否可以查看题库题目
+func CanViewBankQuestion(user *model.User, question *model.BankQuestion) bool {
+	return question.Shared || question.UserID == user.ID
+}
+
+// CanEditBankQuestion 判断管理员是否可以修改题库题目
+func CanEditBankQuestion(user *model.User, question *model.BankQuestion) bool {
+	return question.UserID == user.ID || (question.Shared && user.AdminType == 2)
+}
+
+// CreateBankQuestion 创建题库题目
+func CreateBankQuestion(uid int, question dao.QuestionList, tags []string, shared bool) (int, error) {
+	bank := newBankQuestion(question)
+	bank.UserID = uid
+	bank.Shared = shared
+	bank, err := d.CreateBankQuestion(ctx, bank)
+	if err != nil {
+		return 0, err
+	}
+	err = createBankOptions(bank.ID, question.Options)
+	if err != nil {
+		return 0, err
+	}
+	err = d.SetBankTags(ctx, bank.ID, normalizeTags(tags))
+	return bank.ID, err
+}
+
+// UpdateBankQuestion 更新题库题目，并同步到引用该题目且尚无人填写的问卷
+func UpdateBankQuestion(id int, question dao.QuestionList, tags []string, shared bool) error {
+	bank := newBankQuestion(question)
+	bank.ID = id
+	bank.Shared = shared
+	err := d.UpdateBankQuestion(ctx, bank)
+	if err != nil {
+		return err
+	}
+	err = d.DeleteBankOptions(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = createBankOptions(id, question.Options)
+	if err != nil {
+		return err
+	}
+	err = d.SetBankTags(ctx, id, normalizeTags(tags))
+	if err != nil {
+		return err
+	}
+	return syncBankReferences(bank, question.Options)
+}
+
+// DeleteBankQuestion 删除题库题目，引用该题目的问卷问题保留现有内容
+func DeleteBankQuestion(id int) error {
+	err := d.UnlinkBankQuestion(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = d.DeleteBankOptions(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = d.SetBankTags(ctx, id, nil)
+	if err != nil {
+		return err
+	}
+	return d.DeleteBankQuestion(ctx, id)
+}
+
+// GetBankQuestionByID 根据ID获取题库题目
+func GetBankQuestionByID(id int) (*model.BankQuestion, error) {
+	return d.GetBankQuestionByID(ctx, id)
+}
+
+// GetBankQuestionDetail 获取题库题目详情
+func GetBankQuestionDetail(id int) (*BankQuestionDetail, error) {
+	question, err := d.GetBankQuestionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return fillBankQuestionDetail(*question)
+}
+
+// GetBankQuestions 分页查询题库题目
+func GetBankQuestions(filter dao.BankQuestionFilter, pageNum, pageSize int) ([]BankQuestionDetail, int64, error) {
+	questions, total, err := d.GetBankQuestions(ctx, filter, pageNum, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	details := make([]BankQuestionDetail, 0, len(questions))
+	for _, question := range questions {
+		detail, err := fillBankQuestionDetail(question)
+		if err != nil {
+			return nil, 0, err
+		}
+		details = append(details, *detail)
+	}
+	return details, total, nil
+}
+
+// ResolveBankQuestions 将问题列表中来自题库的题目替换为题库中的内容
+func ResolveBankQuestions(user *model.User, questionList []dao.QuestionList) error {
+	for i, question := range questionList {
+		if question.BankID == 0 {
+			continue
+		}
+		detail, err := GetBankQuestionDetail(question.BankID)
+		if err != nil {
+			return err
+		}
+		if !CanViewBankQuestion(user, &detail.BankQuestion) {
+			return code.NoPermission
+		}
+		options := make([]dao.Option, 0, len(detail.Options))
+		for _, option := range detail.Options {
+			options = append(options, dao.Option{
+				SerialNum:   option.SerialNum,
+				Content:     option.Content,
+				Description: option.Description,
+				Img:         option.Img,
+			})
+		}
+		questionList[i].Subject = detail.Subject
+		questionList[i].Description = detail.Description
+		questionList[i].Img = detail.Img
+		questionList[i].Options = options
+		// 题目顺序和选项顺序的设置属于问卷，保留问卷中的设置
+		questionList[i].QuestionSetting = dao.QuestionSetting{
+			Required:       detail.Required,
+			Unique:         detail.Unique,
+			OtherOption:    detail.OtherOption,
+			QuestionType:   detail.QuestionType,
+			Reg:            detail.Reg,
+			MaximumOption:  detail.MaximumOption,
+			MinimumOption:  detail.MinimumOption,
+			ShuffleOptions: question.QuestionSetting.ShuffleOptions,
+			Pinned:         question.QuestionSetting.Pinned,
+			Expression:     question.QuestionSetting.Expression,
+			TallyMethod:    question.QuestionSetting.TallyMethod,
+			TieBreak:       question.QuestionSetting.TieBreak,
+			MaxScore:       question.QuestionSetting.MaxScore,
+			Numeric:        question.QuestionSetting.Numeric,
+		}
+	}
+	return nil
+}
+
+func newBankQuestion(question dao.QuestionList) model.BankQuestion {
+	return model.BankQuestion{
+		Img:           question.Img,
+		Subject:       question.Subject,
+		Description:   question.Description,
+		Required:      question.QuestionSetting.Required,
+		Unique:        question.QuestionSetting.Unique,
+		OtherOption:   question.QuestionSetting.OtherOption,
+		QuestionType:  question.QuestionSetting.QuestionType,
+		MaximumOption: question.QuestionSetting.MaximumOption,
+		MinimumOption: question.QuestionSetting.MinimumOption,
+		Reg:           question.QuestionSetting.Reg,
+	}
+}
+
+func createBankOptions(id int, options []dao.Option) error {
+	for _, option := range options {
+		err := d.CreateBankOption(ctx, model.BankOption{
+			BankQuestionID: id,
+			SerialNum:      option.SerialNum,
+			Content:        option.Content,
+			Description:    option.Description,
+			Img:            option.Img,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fillBankQuestionDetail(question model.BankQuestion) (*BankQuestionDetail, error) {
+	options, err := d.GetBankOptionsByQuestionID(ctx, question.ID)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := d.GetBankTagsByQuestionID(ctx, question.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &BankQuestionDetail{BankQuestion: question, Options: options, Tags: tags}, nil
+}
+
+// syncBankReferences 同步引用题库题目的问卷问题，已有填写记录的问卷不做修改
+func syncBankReferences(bank model.BankQuestion, options []dao.Option) error {
+	questions, err := d.GetQuestionsByBankQuestionID(ctx, bank.ID)
+	if err != nil {
+		return err
+	}
+	if len(questions) == 0 {
+		return nil
+	}
+	for _, question := range questions {
+		survey, err := d.GetSurveyByID(ctx, question.SurveyID)
+		if err != nil {
+			return err
+		}
+		if survey.Num != 0 {
+			continue
+		}
+		err = d.UpdateQuestionFromBank(ctx, question.ID, bank)
+		if err != nil {
+			return err
+		}
+		err = d.DeleteOption(ctx, question.ID)
+		if err != nil {
+			return err
+		}
+		for _, option := range options {
+			err = d.CreateOption(ctx, model.Option{
+				QuestionID:  question.ID,
+				SerialNum:   option.SerialNum,
+				Content:     option.Content,
+				Description: option.Description,
+				Img:         option.Img,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = dao.DeleteAllQuestionCache(ctx)
+	if err != nil {
+		return err
+	}
+	return dao.DeleteAllOptionCache(ctx)
+}
+
+func normalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
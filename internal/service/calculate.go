@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/expr"
+)
+
+// pipePattern 题面中引用前面题目答案的占位符，如 {{Q3}}
+var pipePattern = regexp.MustCompile(`\{\{\s*[Qq](\d+)\s*\}\}`)
+
+// Pipe 题面中的答案引用
+type Pipe struct {
+	Token     string `json:"token"`      // 占位符原文
+	SerialNum int    `json:"serial_num"` // 引用的题目序号
+}
+
+// GetQuestionPipes 获取问题题目和描述中的答案引用
+func GetQuestionPipes(question model.Question) []Pipe {
+	pipes := make([]Pipe, 0)
+	for _, match := range pipePattern.FindAllStringSubmatch(question.Subject+"\n"+question.Description, -1) {
+		serialNum, _ := strconv.Atoi(match[1])
+		pipes = append(pipes, Pipe{Token: match[0], SerialNum: serialNum})
+	}
+	return pipes
+}
+
+// ResolvePipes 将文本中的答案引用替换为对应题目的答案
+func ResolvePipes(text string, answers map[int]string) string {
+	return pipePattern.ReplaceAllStringFunc(text, func(token string) string {
+		serialNum, _ := strconv.Atoi(pipePattern.FindStringSubmatch(token)[1])
+		return strings.ReplaceAll(answers[serialNum], "┋", "、")
+	})
+}
+
+// CheckQuestionReferences 检查答案引用和计算题表达式，只允许引用前面的题目
+func CheckQuestionReferences(questionList []dao.QuestionList) error {
+	questionTypes := make(map[int]int)
+	for _, question := range questionList {
+		setting := question.QuestionSetting
+		prefix := "问题" + strconv.Itoa(question.SerialNum)
+		for _, match := range pipePattern.FindAllStringSubmatch(question.Subject+"\n"+question.Description, -1) {
+			serialNum, _ := strconv.Atoi(match[1])
+			questionType, ok := questionTypes[serialNum]
+			if !ok {
+				return errors.New(prefix + "引用的" + match[0] + "不是前面的题目")
+			}
+			if questionType == 5 || questionType == 6 || questionType == 8 {
+				return errors.New(prefix + "不能引用图片、文件或计算题的答案")
+			}
+		}
+		if setting.QuestionType == 8 {
+			if setting.Required {
+				return errors.New(prefix + "为计算题，不能设置为必填")
+			}
+			e, err := expr.Parse(setting.Expression)
+			if err != nil {
+				return errors.New(prefix + "的计算公式错误: " + err.Error())
+			}
+			for _, v := range e.Vars() {
+				serialNum, _ := strconv.Atoi(v[1:])
+				questionType, ok := questionTypes[serialNum]
+				if !ok {
+					return errors.New(prefix + "的计算公式引用的" + v + "不是前面的题目")
+				}
+				if questionType != 1 && questionType != 2 && questionType != 3 && questionType != 8 {
+					return errors.New(prefix + "的计算公式只能引用选择题、填空题或计算题")
+				}
+			}
+		}
+		questionTypes[question.SerialNum] = setting.QuestionType
+	}
+	return nil
+}
+
+// FillDerivedAnswers 为答卷填充引用答案后的题面，并计算计算题的结果作为额外的答案保存
+func FillDerivedAnswers(questions []model.Question, answerSheet *dao.AnswerSheet) error {
+	questionMap := make(map[int]model.Question)
+	for _, question := range questions {
+		questionMap[question.ID] = question
+	}
+	contents := make(map[int]string)
+	for _, answer := range answerSheet.Answers {
+		if question, ok := questionMap[answer.QuestionID]; ok {
+			contents[question.SerialNum] = answer.Content
+		}
+	}
+	for i, answer := range answerSheet.Answers {
+		question, ok := questionMap[answer.QuestionID]
+		if !ok {
+			continue
+		}
+		answerSheet.Answers[i].SerialNum = question.SerialNum
+		if len(GetQuestionPipes(question)) != 0 {
+			answerSheet.Answers[i].Subject = ResolvePipes(question.Subject, contents)
+		}
+	}
+	sorted := make([]model.Question, len(questions))
+	copy(sorted, questions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SerialNum < sorted[j].SerialNum
+	})
+	values := make(map[string]float64)
+	for _, question := range sorted {
+		value, ok, err := numericAnswer(question, contents[question.SerialNum])
+		if err != nil {
+			return err
+		}
+		if ok {
+			values["Q"+strconv.Itoa(question.SerialNum)] = value
+		}
+		if question.QuestionType != 8 {
+			continue
+		}
+		// 引用的答案缺失或不是数字时，计算结果留空
+		content := ""
+		if e, err := expr.Parse(question.Expression); err == nil {
+			if v, err := e.Eval(values); err == nil {
+				values["Q"+strconv.Itoa(question.SerialNum)] = v
+				content = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		contents[question.SerialNum] = content
+		answerSheet.Answers = append(answerSheet.Answers, dao.Answer{
+			QuestionID: question.ID,
+			SerialNum:  question.SerialNum,
+			Content:    content,
+		})
+	}
+	return nil
+}
+
+// numericAnswer 获取答案在计算公式中的数值，填空题取数值，单选题取所选选项序号，多选题取所选选项数量
+func numericAnswer(question model.Question, content string) (float64, bool, error) {
+	if content == "" {
+		return 0, false, nil
+	}
+	switch question.QuestionType {
+	case 1:
+		options, err := GetQuestionOptions(question)
+		if err != nil {
+			return 0, false, err
+		}
+		for _, option := range options {
+			if option.Content == content {
+				return float64(option.SerialNum), true, nil
+			}
+		}
+	case 2:
+		return float64(len(strings.Split(content, "┋"))), true, nil
+	case 3:
+		v, err := strconv.ParseFloat(strings.TrimSpace(content), 64)
+		return v, err == nil, nil
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+)
+
+const maxCompletionMessageLength = 10000 // 感谢语的最大长度
+
+// redirectPattern 跳转链接中的占位符，如 {{Q3}}、{{answer_id}}、{{channel}}
+var redirectPattern = regexp.MustCompile(`\{\{\s*([A-Za-z][A-Za-z0-9_]*)\s*\}\}`)
+
+// questionVarPattern 引用题目答案的占位符名称
+var questionVarPattern = regexp.MustCompile(`^[Qq](\d+)$`)
+
+// colorPattern 主题色只允许十六进制颜色
+var colorPattern = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+
+// Completion 提交问卷后返回的完成页
+type Completion struct {
+	Message     string `json:"message"`
+	RedirectURL string `json:"redirect_url"`
+	ShowResults bool   `json:"show_results"`
+}
+
+// CheckCompletion 检查完成页设置，跳转链接只能引用存在的题目和隐藏字段，
+// 匿名投票不保存答卷与答题者的对应关系，跳转链接不能使用占位符
+func CheckCompletion(base dao.BaseConfig, questionList []dao.QuestionList) error {
+	completion := base.Completion
+	if utf8.RuneCountInString(completion.Message) > maxCompletionMessageLength {
+		return errors.New("感谢语过长")
+	}
+	if completion.RedirectURL == "" {
+		return nil
+	}
+	if base.Anonymous && redirectPattern.MatchString(completion.RedirectURL) {
+		return errors.New("匿名投票的跳转链接不能引用答案、答卷ID或隐藏字段")
+	}
+	questionTypes := make(map[int]int)
+	for _, question := range questionList {
+		questionTypes[question.SerialNum] = question.QuestionSetting.QuestionType
+	}
+	hiddenFields := make(map[string]bool)
+	for _, field := range base.HiddenFields {
+		hiddenFields[field] = true
+	}
+	for _, match := range redirectPattern.FindAllStringSubmatch(completion.RedirectURL, -1) {
+		name := match[1]
+		if m := questionVarPattern.FindStringSubmatch(name); m != nil {
+			serialNum, _ := strconv.Atoi(m[1])
+			questionType, ok := questionTypes[serialNum]
+			if !ok {
+				return errors.New("跳转链接引用的" + match[0] + "不存在")
+			}
+			if questionType == 5 || questionType == 6 {
+				return errors.New("跳转链接不能引用图片或文件题的答案")
+			}
+			continue
+		}
+		if name != "answer_id" && !hiddenFields[name] {
+			return errors.New("跳转链接中的" + match[0] + "不是题目、答卷ID或隐藏字段")
+		}
+	}
+	u, err := url.Parse(redirectPattern.ReplaceAllString(completion.RedirectURL, "x"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("跳转链接必须是 http 或 https 链接")
+	}
+	return nil
+}
+
+// CheckTheme 检查问卷主题
+func CheckTheme(theme model.SurveyTheme) error {
+	if theme.PrimaryColor != "" && !colorPattern.MatchString(theme.PrimaryColor) {
+		return errors.New("主题色必须为十六进制颜色")
+	}
+	for _, link := range []string{theme.HeaderImg, theme.Logo} {
+		if link == "" {
+			continue
+		}
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("主题图片必须是 http 或 https 链接")
+		}
+	}
+	return nil
+}
+
+// GetCompletion 根据答卷生成完成页，跳转链接中的占位符替换为转义后的答案，
+// resultVisible 为投票结果是否对答题者公开
+func GetCompletion(survey *model.Survey, answerSheet dao.AnswerSheet, translation Translation,
+	resultVisible bool) Completion {
+	completion := Completion{
+		Message:     translation.Text("completion", survey.Completion.Message),
+		ShowResults: survey.Completion.ShowResults && resultVisible,
+	}
+	if survey.Completion.RedirectURL == "" {
+		return completion
+	}
+	contents := make(map[int]string)
+	for _, answer := range answerSheet.Answers {
+		contents[answer.SerialNum] = answer.Content
+	}
+	completion.RedirectURL = redirectPattern.ReplaceAllStringFunc(survey.Completion.RedirectURL,
+		func(token string) string {
+			name := redirectPattern.FindStringSubmatch(token)[1]
+			if m := questionVarPattern.FindStringSubmatch(name); m != nil {
+				serialNum, _ := strconv.Atoi(m[1])
+				return url.QueryEscape(contents[serialNum])
+			}
+			if name == "answer_id" {
+				return answerSheet.AnswerID.Hex()
+			}
+			return url.QueryEscape(answerSheet.HiddenFields[name])
+		})
+	return completion
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// CounterDrift 计数器与答卷不一致的一项
+type CounterDrift struct {
+	Field   string `json:"field"`   // total、问题ID 或 问题ID:答案
+	Counter int    `json:"counter"` // 计数器中的值
+	Actual  int    `json:"actual"`  // 根据答卷重新统计的值
+}
+
+// CounterReport 重建计数器的结果
+type CounterReport struct {
+	SurveyID int64          `json:"survey_id"`
+	Existed  bool           `json:"existed"` // 重建前计数器是否存在
+	Drifts   []CounterDrift `json:"drifts"`
+}
+
+// GetVoteStats 根据 Redis 计数器获取投票统计，计数器不存在时从答卷重建，结果与 GenerateVoteStats 一致
+func GetVoteStats(sid int64, questions []model.Question) ([]VoteStatistics, error) {
+	loaded, err := loadQuestionOptions(questions)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := d.GetStatsCounters(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		stats, err = rebuildStatsCounters(sid, questions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(stats.Groups) == 0 || stats.Groups[0].Total == 0 {
+		return emptyVoteStats(loaded, questions), nil
+	}
+	counts := countAggregatedStats(loaded, stats, 1)
+	return formatVoteStats(loaded, ensureGroup(counts, "")), nil
+}
+
+// RebuildStatsCounters 从答卷重新统计问卷的计数器，并返回重建前计数器与答卷不一致的项
+func RebuildStatsCounters(sid int64) (*CounterReport, error) {
+	questions, err := d.GetQuestionsBySurveyID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	current, err := d.GetStatsCounters(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := rebuildStatsCounters(sid, questions)
+	if err != nil {
+		return nil, err
+	}
+	report := &CounterReport{SurveyID: sid, Existed: current != nil, Drifts: make([]CounterDrift, 0)}
+	if current == nil {
+		return report, nil
+	}
+	before, after := flattenStats(current), flattenStats(actual)
+	for field := range after {
+		if _, ok := before[field]; !ok {
+			before[field] = 0
+		}
+	}
+	fields := make([]string, 0, len(before))
+	for field := range before {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if before[field] != after[field] {
+			report.Drifts = append(report.Drifts, CounterDrift{Field: field, Counter: before[field], Actual: after[field]})
+		}
+	}
+	return report, nil
+}
+
+// RebuildAllStatsCounters 重建所有已有计数器的问卷
+func RebuildAllStatsCounters() ([]CounterReport, error) {
+	sids, err := d.GetCounterSurveys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]CounterReport, 0, len(sids))
+	for _, sid := range sids {
+		report, err := RebuildStatsCounters(sid)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// rebuildStatsCounters 在 MongoDB 中统计答卷并覆盖计数器
+//
+// 统计与覆盖之间提交或删除的答卷可能被重复计入或遗漏，可再次重建修正。
+func rebuildStatsCounters(sid int64, questions []model.Question) (*dao.AnswerStats, error) {
+	stats, err := d.AggregateAnswerStats(ctx, sid, nil, "", questionIDsOfTypes(questions, 1, 2))
+	if err != nil {
+		return nil, err
+	}
+	if err := d.SetStatsCounters(ctx, sid, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// updateStatsCounters 将答卷计入计数器并通知实时结果和新答卷推送，新答卷需先记入哈希链，
+// sign 为 -1 时扣除，失败只记录日志，由重建命令修正
+func updateStatsCounters(answerSheet dao.AnswerSheet, sign int) {
+	questions, err := d.GetQuestionsBySurveyID(ctx, answerSheet.SurveyID)
+	if err != nil {
+		zap.L().Error("更新统计计数器失败", zap.Int64("survey_id", answerSheet.SurveyID), zap.Error(err))
+		return
+	}
+	delta := sheetStats(answerSheet, questions, sign)
+	if err := d.IncreaseStatsCounters(ctx, answerSheet.SurveyID, delta); err != nil {
+		zap.L().Error("更新统计计数器失败", zap.Int64("survey_id", answerSheet.SurveyID), zap.Error(err))
+	}
+	if err := d.PublishStatsUpdate(ctx, answerSheet.SurveyID); err != nil {
+		zap.L().Error("发布答卷变化通知失败", zap.Int64("survey_id", answerSheet.SurveyID), zap.Error(err))
+	}
+}
+
+// sheetStats 统计单份答卷，与 AggregateAnswerStats 的统计方式一致
+func sheetStats(answerSheet dao.AnswerSheet, questions []model.Question, sign int) *dao.AnswerStats {
+	counted := make(map[int]bool)
+	for _, id := range questionIDsOfTypes(questions, 1, 2) {
+		counted[id] = true
+	}
+	stats := &dao.AnswerStats{Groups: []dao.StatsGroup{{Total: sign}}}
+	for _, answer := range answerSheet.Answers {
+		stats.Presence = append(stats.Presence, dao.StatsPresence{QuestionID: answer.QuestionID, Count: sign})
+		if !counted[answer.QuestionID] {
+			continue
+		}
+		for _, content := range strings.Split(answer.Content, "┋") {
+			stats.Counts = append(stats.Counts, dao.StatsCount{
+				QuestionID: answer.QuestionID,
+				Content:    content,
+				Count:      sign,
+			})
+		}
+	}
+	return stats
+}
+
+// flattenStats 将统计结果展开为字段到次数的映射，省略次数为 0 的项
+func flattenStats(stats *dao.AnswerStats) map[string]int {
+	fields := make(map[string]int)
+	for _, group := range stats.Groups {
+		fields["total"] += group.Total
+	}
+	for _, presence := range stats.Presence {
+		fields[strconv.Itoa(presence.QuestionID)] += presence.Count
+	}
+	for _, count := range stats.Counts {
+		fields[strconv.Itoa(count.QuestionID)+":"+count.Content] += count.Count
+	}
+	for field, n := range fields {
+		if n == 0 {
+			delete(fields, field)
+		}
+	}
+	return fields
+}
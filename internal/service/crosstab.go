@@ -0,0 +1,422 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+
+	"github.com/zjutjh/WeJH-SDK/excel"
+)
+
+// 交叉分析的维度类型
+const (
+	DimensionQuestion = "question" // 单选题或多选题的答案
+	DimensionRecord   = "record"   // 统一验证信息
+	DimensionHidden   = "hidden"   // 隐藏字段
+)
+
+const (
+	unknownValue = "未知" // 没有统一验证记录或未携带隐藏字段
+	allValue     = "全部" // 未指定列维度时的唯一一列
+)
+
+// recordAttributes 可用于分析的统一验证信息
+var recordAttributes = map[string]string{
+	"college":   "学院",
+	"gender":    "性别",
+	"user_type": "用户类型",
+}
+
+// CrossTabQuery 交叉分析条件
+type CrossTabQuery struct {
+	Row     model.AnswerDimension   `json:"row"`
+	Column  *model.AnswerDimension  `json:"column"`  // 为空时只按行维度统计
+	Filters []model.AnswerCondition `json:"filters"` // 答案条件 需全部满足
+}
+
+// CrossTab 交叉分析结果，多选题的一份答卷可计入多行或多列，因此合计为答卷数而非各格之和
+type CrossTab struct {
+	RowTitle       string      `json:"row_title"`
+	ColumnTitle    string      `json:"column_title"`
+	Rows           []string    `json:"rows"`
+	Columns        []string    `json:"columns"`
+	Counts         [][]int     `json:"counts"`          // 同时属于该行和该列的答卷数
+	RowTotals      []int       `json:"row_totals"`      // 属于该行的答卷数
+	ColumnTotals   []int       `json:"column_totals"`   // 属于该列的答卷数
+	Total          int         `json:"total"`           // 参与分析的答卷数
+	RowPercents    [][]float64 `json:"row_percents"`    // 占该行答卷数的百分比
+	ColumnPercents [][]float64 `json:"column_percents"` // 占该列答卷数的百分比
+	Percents       [][]float64 `json:"percents"`        // 占全部答卷数的百分比
+}
+
+// crossTabDimension 解析后的维度，values 返回答卷在该维度上的取值，没有取值的答卷不参与分析
+type crossTabDimension struct {
+	title    string
+	labels   []string        // 固定的取值顺序 为空时按出现的取值排序
+	optional map[string]bool // 没有答卷时省略的取值
+	values   func(sheet dao.AnswerSheet) []string
+}
+
+// CheckCrossTab 检查交叉分析条件
+func CheckCrossTab(survey *model.Survey, query CrossTabQuery) error {
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkAnswerConditions(questions, query.Filters); err != nil {
+		return err
+	}
+	dims := []model.AnswerDimension{query.Row}
+	if query.Column != nil {
+		dims = append(dims, *query.Column)
+	}
+	for _, dim := range dims {
+		if err := checkDimension(survey, questions, dim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCrossTab 对问卷的有效答卷做交叉分析，分析条件需先由 CheckCrossTab 检查
+func GetCrossTab(survey *model.Survey, query CrossTabQuery) (*CrossTab, error) {
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	row, err := resolveDimension(survey, questions, query.Row)
+	if err != nil {
+		return nil, err
+	}
+	column := &crossTabDimension{labels: []string{allValue}, values: func(dao.AnswerSheet) []string {
+		return []string{allValue}
+	}}
+	if query.Column != nil {
+		column, err = resolveDimension(survey, questions, *query.Column)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sheets, err := GetSurveyAnswersBySurveyID(survey.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	questionIDs := make(map[int]int)
+	for _, question := range questions {
+		questionIDs[question.SerialNum] = question.ID
+	}
+
+	type cell struct{ row, column string }
+	counts := make(map[cell]int)
+	rowTotals := make(map[string]int)
+	columnTotals := make(map[string]int)
+	total := 0
+	for _, sheet := range sheets {
+		if !matchAnswerConditions(sheet, query.Filters, questionIDs) {
+			continue
+		}
+		rowValues, columnValues := row.values(sheet), column.values(sheet)
+		if len(rowValues) == 0 || len(columnValues) == 0 {
+			continue
+		}
+		total++
+		for _, r := range rowValues {
+			rowTotals[r]++
+			for _, c := range columnValues {
+				counts[cell{r, c}]++
+			}
+		}
+		for _, c := range columnValues {
+			columnTotals[c]++
+		}
+	}
+
+	table := &CrossTab{
+		RowTitle:    row.title,
+		ColumnTitle: column.title,
+		Rows:        dimensionLabels(row, rowTotals),
+		Columns:     dimensionLabels(column, columnTotals),
+		Total:       total,
+	}
+	for _, r := range table.Rows {
+		countRow := make([]int, 0, len(table.Columns))
+		rowPercents := make([]float64, 0, len(table.Columns))
+		columnPercents := make([]float64, 0, len(table.Columns))
+		percents := make([]float64, 0, len(table.Columns))
+		for _, c := range table.Columns {
+			count := counts[cell{r, c}]
+			countRow = append(countRow, count)
+			rowPercents = append(rowPercents, percentOf(count, rowTotals[r]))
+			columnPercents = append(columnPercents, percentOf(count, columnTotals[c]))
+			percents = append(percents, percentOf(count, total))
+		}
+		table.Counts = append(table.Counts, countRow)
+		table.RowPercents = append(table.RowPercents, rowPercents)
+		table.ColumnPercents = append(table.ColumnPercents, columnPercents)
+		table.Percents = append(table.Percents, percents)
+		table.RowTotals = append(table.RowTotals, rowTotals[r])
+	}
+	for _, c := range table.Columns {
+		table.ColumnTotals = append(table.ColumnTotals, columnTotals[c])
+	}
+	return table, nil
+}
+
+// ExportCrossTab 导出交叉分析结果，包括计数和三种百分比
+func ExportCrossTab(survey *model.Survey, table *CrossTab) (string, error) {
+	corner := table.RowTitle
+	if table.ColumnTitle != "" {
+		corner += " \\ " + table.ColumnTitle
+	}
+	headers := append([]string{corner}, table.Columns...)
+	headers = append(headers, "合计")
+	countRows := make([][]any, 0, len(table.Rows)+1)
+	for i, r := range table.Rows {
+		row := []any{r}
+		for _, count := range table.Counts[i] {
+			row = append(row, count)
+		}
+		countRows = append(countRows, append(row, table.RowTotals[i]))
+	}
+	totalRow := []any{"合计"}
+	for _, count := range table.ColumnTotals {
+		totalRow = append(totalRow, count)
+	}
+	countRows = append(countRows, append(totalRow, table.Total))
+	percentRows := func(percents [][]float64) [][]any {
+		rows := make([][]any, 0, len(table.Rows))
+		for i, r := range table.Rows {
+			row := []any{r}
+			for _, percent := range percents[i] {
+				row = append(row, fmt.Sprintf("%.2f%%", percent))
+			}
+			rows = append(rows, row)
+		}
+		return rows
+	}
+	sheets := []excel.Sheet{
+		{Name: "计数", Headers: headers, Rows: countRows},
+		{Name: "行百分比", Headers: headers[:len(headers)-1], Rows: percentRows(table.RowPercents)},
+		{Name: "列百分比", Headers: headers[:len(headers)-1], Rows: percentRows(table.ColumnPercents)},
+		{Name: "总百分比", Headers: headers[:len(headers)-1], Rows: percentRows(table.Percents)},
+	}
+	return createExcelFile(sheets, fmt.Sprintf("%s_交叉分析.xlsx", survey.Title))
+}
+
+// checkDimension 检查分析维度
+func checkDimension(survey *model.Survey, questions []model.Question, dim model.AnswerDimension) error {
+	switch dim.Type {
+	case DimensionQuestion:
+		question := findQuestionBySerial(questions, dim.SerialNum)
+		if question == nil {
+			return errors.New("问题" + strconv.Itoa(dim.SerialNum) + "不存在")
+		}
+		if question.QuestionType != 1 && question.QuestionType != 2 {
+			return errors.New("只能按单选题或多选题分析")
+		}
+	case DimensionRecord:
+		if _, ok := recordAttributes[dim.Attribute]; !ok {
+			return errors.New("统一验证信息" + dim.Attribute + "不存在")
+		}
+		if !survey.Verify {
+			return errors.New("问卷未开启统一验证，不能按统一验证信息分析")
+		}
+		if survey.Anonymous {
+			return errors.New("匿名投票不能按统一验证信息分析")
+		}
+		if !linkStudent() {
+			return errors.New("未配置答卷关联学号的密钥，不能按统一验证信息分析")
+		}
+	case DimensionHidden:
+		if !IsHiddenField(survey, dim.Field) {
+			return errors.New("隐藏字段" + dim.Field + "不存在")
+		}
+	default:
+		return errors.New("分析维度" + dim.Type + "不存在")
+	}
+	return nil
+}
+
+// resolveDimension 生成维度的取值方法
+func resolveDimension(survey *model.Survey, questions []model.Question,
+	dim model.AnswerDimension) (*crossTabDimension, error) {
+	if err := checkDimension(survey, questions, dim); err != nil {
+		return nil, err
+	}
+	switch dim.Type {
+	case DimensionQuestion:
+		return questionDimension(*findQuestionBySerial(questions, dim.SerialNum))
+	case DimensionRecord:
+		records, err := d.GetRecordSheets(ctx, survey.ID)
+		if err != nil {
+			return nil, err
+		}
+		students := make(map[string]dao.RecordSheet)
+		for _, entry := range records {
+			students[studentKey(entry.Record.StudentID)] = entry.Record
+		}
+		title := recordAttributes[dim.Attribute]
+		return &crossTabDimension{title: title, values: func(sheet dao.AnswerSheet) []string {
+			record, ok := students[sheet.StudentKey]
+			if !ok || sheet.StudentKey == "" {
+				return []string{unknownValue}
+			}
+			return []string{recordAttribute(record, dim.Attribute)}
+		}}, nil
+	default:
+		return &crossTabDimension{title: dim.Field, values: func(sheet dao.AnswerSheet) []string {
+			if value := sheet.HiddenFields[dim.Field]; value != "" {
+				return []string{value}
+			}
+			return []string{unknownValue}
+		}}, nil
+	}
+}
+
+// questionDimension 按选择题的答案分析，不是选项的答案归入其他，未作答的答卷不参与分析
+func questionDimension(question model.Question) (*crossTabDimension, error) {
+	options, err := GetQuestionOptions(question)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(options, func(i, j int) bool {
+		return options[i].SerialNum < options[j].SerialNum
+	})
+	labels := make([]string, 0, len(options)+1)
+	contents := make(map[string]bool)
+	for _, option := range options {
+		labels = append(labels, option.Content)
+		contents[option.Content] = true
+	}
+	optional := make(map[string]bool)
+	if !contents["其他"] {
+		labels = append(labels, "其他")
+		optional["其他"] = true
+	}
+	values := func(sheet dao.AnswerSheet) []string {
+		result := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, answer := range sheet.Answers {
+			if answer.QuestionID != question.ID || answer.Content == "" {
+				continue
+			}
+			for _, value := range strings.Split(answer.Content, "┋") {
+				if !contents[value] {
+					value = "其他"
+				}
+				if !seen[value] {
+					seen[value] = true
+					result = append(result, value)
+				}
+			}
+		}
+		return result
+	}
+	return &crossTabDimension{
+		title:    fmt.Sprintf("%d. %s", question.SerialNum, question.Subject),
+		labels:   labels,
+		optional: optional,
+		values:   values,
+	}, nil
+}
+
+// dimensionLabels 获取维度的取值顺序，没有固定顺序时按取值排序，未知排在最后
+func dimensionLabels(dim *crossTabDimension, totals map[string]int) []string {
+	if len(dim.labels) > 0 {
+		labels := make([]string, 0, len(dim.labels))
+		for _, label := range dim.labels {
+			if dim.optional[label] && totals[label] == 0 {
+				continue
+			}
+			labels = append(labels, label)
+		}
+		return labels
+	}
+	labels := make([]string, 0, len(totals))
+	for label := range totals {
+		if label != unknownValue {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	if totals[unknownValue] > 0 {
+		labels = append(labels, unknownValue)
+	}
+	return labels
+}
+
+func findQuestionBySerial(questions []model.Question, serialNum int) *model.Question {
+	for i := range questions {
+		if questions[i].SerialNum == serialNum {
+			return &questions[i]
+		}
+	}
+	return nil
+}
+
+func recordAttribute(record dao.RecordSheet, attribute string) string {
+	var value string
+	switch attribute {
+	case "college":
+		value = record.College
+	case "gender":
+		value = record.Gender
+	case "user_type":
+		value = record.UserTypeDesc
+		if value == "" {
+			value = record.UserType
+		}
+	}
+	if value == "" {
+		return unknownValue
+	}
+	return value
+}
+
+// checkAnswerConditions 检查答案条件中的题目是否存在
+func checkAnswerConditions(questions []model.Question, conditions []model.AnswerCondition) error {
+	serials := make(map[int]bool)
+	for _, question := range questions {
+		serials[question.SerialNum] = true
+	}
+	for _, condition := range conditions {
+		if !serials[condition.SerialNum] {
+			return errors.New("筛选条件中的问题" + strconv.Itoa(condition.SerialNum) + "不存在")
+		}
+	}
+	return nil
+}
+
+// matchAnswerConditions 判断答卷是否满足全部答案条件，questionIDs 为题目序号对应的问题ID
+func matchAnswerConditions(sheet dao.AnswerSheet, conditions []model.AnswerCondition, questionIDs map[int]int) bool {
+	for _, condition := range conditions {
+		matched := false
+		for _, answer := range sheet.Answers {
+			if answer.QuestionID != questionIDs[condition.SerialNum] {
+				continue
+			}
+			for _, value := range strings.Split(answer.Content, "┋") {
+				if value == condition.Answer {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func percentOf(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(count)*10000/float64(total)) / 100
+}
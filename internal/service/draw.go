@@ -0,0 +1,339 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"QA-System/internal/dao"
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+
+	"github.com/zjutjh/WeJH-SDK/excel"
+	"gorm.io/gorm"
+)
+
+// 抽奖范围
+const (
+	DrawPoolAnswer = "answer" // 有效答卷
+	DrawPoolRecord = "record" // 统一验证记录
+)
+
+const maxDrawCount = 1000 // 单次抽奖的最大中奖人数
+
+// drawAlgorithm 开奖算法说明，随导出文件公布
+const drawAlgorithm = "每个候选的得分为 SHA-256(种子|公开随机数|候选标识) 的十六进制串，" +
+	"按得分从小到大依次中奖，每位学生最多中奖一次时跳过已中奖的学号；" +
+	"候选列表摘要为按字典序排列的候选标识以换行连接后的 SHA-256"
+
+// ErrDrawFinished 抽奖已开奖
+var ErrDrawFinished = errors.New("抽奖已开奖")
+
+// drawCandidate 抽奖候选
+type drawCandidate struct {
+	Key       string
+	Student   string // 限制中奖次数时区分学生的标识
+	StudentID string
+	Name      string
+	College   string
+}
+
+// CheckDraw 检查抽奖设置
+func CheckDraw(survey *model.Survey, draw model.Draw) error {
+	if draw.Count < 1 || draw.Count > maxDrawCount {
+		return errors.New("中奖人数必须在1到" + strconv.Itoa(maxDrawCount) + "之间")
+	}
+	switch draw.Pool {
+	case DrawPoolAnswer:
+		if survey.Anonymous {
+			return errors.New("匿名投票只能从统一验证记录中抽奖")
+		}
+	case DrawPoolRecord:
+		if !survey.Verify {
+			return errors.New("问卷未开启统一验证，不能从统一验证记录中抽奖")
+		}
+		if len(draw.Filter.Answers) > 0 {
+			return errors.New("从统一验证记录中抽奖不能按答案筛选")
+		}
+	default:
+		return errors.New("抽奖范围不存在")
+	}
+	if draw.OnePerStudent && !survey.Verify {
+		return errors.New("问卷未开启统一验证，不能按学生限制中奖次数")
+	}
+	if draw.OnePerStudent && draw.Pool == DrawPoolAnswer && !linkStudent() {
+		return errors.New("未配置答卷关联学号的密钥，不能按学生限制答卷的中奖次数")
+	}
+	for _, value := range []string{draw.Filter.SubmittedAfter, draw.Filter.SubmittedBefore} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseInLocation(time.DateTime, value, time.Local); err != nil {
+			return errors.New("提交时间的格式必须为 2006-01-02 15:04:05")
+		}
+	}
+	if len(draw.Filter.Answers) == 0 {
+		return nil
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return err
+	}
+	return checkAnswerConditions(questions, draw.Filter.Answers)
+}
+
+// CreateDraw 创建抽奖并生成种子，只公布种子的摘要
+func CreateDraw(draw model.Draw) (model.Draw, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return draw, err
+	}
+	draw.Seed = hex.EncodeToString(buf)
+	draw.SeedHash = sha256Hex(draw.Seed)
+	draw.Status = 0
+	return d.CreateDraw(ctx, draw)
+}
+
+// GetDrawByID 根据ID获取抽奖
+func GetDrawByID(id int) (*model.Draw, error) {
+	return d.GetDrawByID(ctx, id)
+}
+
+// GetDrawsBySurveyID 获取问卷的全部抽奖
+func GetDrawsBySurveyID(sid int64) ([]model.Draw, error) {
+	return d.GetDrawsBySurveyID(ctx, sid)
+}
+
+// GetDrawWinners 获取抽奖的中奖记录
+func GetDrawWinners(id int) ([]model.DrawWinner, error) {
+	return d.GetDrawWinners(ctx, id)
+}
+
+// DeleteDraw 删除抽奖
+func DeleteDraw(id int) error {
+	return d.DeleteDraw(ctx, id)
+}
+
+// RunDraw 开奖，salt 为开奖时加入的公开随机数，可为空
+func RunDraw(survey *model.Survey, draw *model.Draw, salt string) ([]model.DrawWinner, error) {
+	if draw.Status != 0 {
+		return nil, ErrDrawFinished
+	}
+	candidates, err := getDrawCandidates(survey, draw)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Key < candidates[j].Key
+	})
+	keys := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		keys = append(keys, candidate.Key)
+	}
+	scores := make(map[string]string, len(candidates))
+	for _, candidate := range candidates {
+		scores[candidate.Key] = sha256Hex(draw.Seed + "|" + salt + "|" + candidate.Key)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i].Key] < scores[candidates[j].Key]
+	})
+	winners := make([]model.DrawWinner, 0, draw.Count)
+	won := make(map[string]bool)
+	for _, candidate := range candidates {
+		if len(winners) >= draw.Count {
+			break
+		}
+		if draw.OnePerStudent && candidate.Student != "" {
+			if won[candidate.Student] {
+				continue
+			}
+			won[candidate.Student] = true
+		}
+		winners = append(winners, model.DrawWinner{
+			DrawID:    draw.ID,
+			Rank:      len(winners) + 1,
+			Key:       candidate.Key,
+			StudentID: candidate.StudentID,
+			Name:      candidate.Name,
+			College:   candidate.College,
+		})
+	}
+	now := time.Now()
+	draw.Salt = salt
+	draw.PoolHash = sha256Hex(strings.Join(keys, "\n"))
+	draw.Candidates = len(keys)
+	draw.CandidateKeys = keys
+	draw.Status = 1
+	draw.DrawnAt = &now
+	err = d.FinishDraw(ctx, *draw, winners)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDrawFinished
+	}
+	return winners, err
+}
+
+// GetDrawDetail 获取抽奖详情，开奖后公开种子和中奖记录
+func GetDrawDetail(draw *model.Draw, mask bool) (map[string]any, error) {
+	detail := map[string]any{
+		"draw":    draw,
+		"winners": make([]model.DrawWinner, 0),
+	}
+	if draw.Status == 0 {
+		return detail, nil
+	}
+	winners, err := d.GetDrawWinners(ctx, draw.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mask {
+		for i := range winners {
+			winners[i].StudentID = maskStudentID(winners[i].StudentID)
+			winners[i].Name = maskName(winners[i].Name)
+		}
+	}
+	detail["seed"] = draw.Seed
+	detail["algorithm"] = drawAlgorithm
+	detail["winners"] = winners
+	return detail, nil
+}
+
+// ExportDraw 导出中奖名单，包括复现开奖所需的种子和候选列表
+func ExportDraw(survey *model.Survey, draw *model.Draw) (string, error) {
+	winners, err := d.GetDrawWinners(ctx, draw.ID)
+	if err != nil {
+		return "", err
+	}
+	winnerRows := make([][]any, 0, len(winners))
+	for _, winner := range winners {
+		winnerRows = append(winnerRows,
+			[]any{winner.Rank, winner.StudentID, winner.Name, winner.College, winner.Key})
+	}
+	infoRows := [][]any{
+		{"抽奖名称", draw.Name},
+		{"抽奖范围", draw.Pool},
+		{"中奖人数", draw.Count},
+		{"种子摘要", draw.SeedHash},
+		{"种子", draw.Seed},
+		{"公开随机数", draw.Salt},
+		{"候选数", draw.Candidates},
+		{"候选列表摘要", draw.PoolHash},
+		{"开奖算法", drawAlgorithm},
+	}
+	if draw.DrawnAt != nil {
+		infoRows = append(infoRows, []any{"开奖时间", draw.DrawnAt.Format(time.DateTime)})
+	}
+	candidateRows := make([][]any, 0, len(draw.CandidateKeys))
+	for _, key := range draw.CandidateKeys {
+		candidateRows = append(candidateRows, []any{key})
+	}
+	sheets := []excel.Sheet{
+		{Name: "中奖名单", Headers: []string{"中奖顺序", "学号", "姓名", "学院", "候选标识"}, Rows: winnerRows},
+		{Name: "开奖信息", Headers: []string{"项目", "内容"}, Rows: infoRows},
+		{Name: "候选列表", Headers: []string{"候选标识"}, Rows: candidateRows},
+	}
+	return createExcelFile(sheets, fmt.Sprintf("%s_抽奖%d.xlsx", survey.Title, draw.ID))
+}
+
+// getDrawCandidates 获取满足筛选条件的抽奖候选
+func getDrawCandidates(survey *model.Survey, draw *model.Draw) ([]drawCandidate, error) {
+	records, err := d.GetRecordSheets(ctx, survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	filter := draw.Filter
+	inTime := func(t string) bool {
+		return (filter.SubmittedAfter == "" || t >= filter.SubmittedAfter) &&
+			(filter.SubmittedBefore == "" || t < filter.SubmittedBefore)
+	}
+	candidates := make([]drawCandidate, 0)
+	if draw.Pool == DrawPoolRecord {
+		for _, entry := range records {
+			if !inTime(entry.Record.Time.In(time.Local).Format(time.DateTime)) {
+				continue
+			}
+			candidates = append(candidates, drawCandidate{
+				Key:       entry.ID.Hex(),
+				Student:   entry.Record.StudentID,
+				StudentID: entry.Record.StudentID,
+				Name:      entry.Record.Name,
+				College:   entry.Record.College,
+			})
+		}
+		return candidates, nil
+	}
+	// 答卷只保存学号的 HMAC，学号、姓名和学院从统一验证记录中获取
+	students := make(map[string]dao.RecordSheet)
+	for _, entry := range records {
+		students[studentKey(entry.Record.StudentID)] = entry.Record
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	questionIDs := make(map[int]int)
+	for _, question := range questions {
+		questionIDs[question.SerialNum] = question.ID
+	}
+	sheets, _, err := d.GetAnswerSheetBySurveyID(ctx, survey.ID, 0, 0, "", true, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, sheet := range sheets {
+		if !inTime(sheet.Time) || !matchAnswerConditions(sheet, filter.Answers, questionIDs) {
+			continue
+		}
+		student := students[sheet.StudentKey]
+		candidates = append(candidates, drawCandidate{
+			Key:       sheet.AnswerID.Hex(),
+			Student:   sheet.StudentKey,
+			StudentID: student.StudentID,
+			Name:      student.Name,
+			College:   student.College,
+		})
+	}
+	return candidates, nil
+}
+
+// maskStudentID 隐藏学号中间部分
+func maskStudentID(studentID string) string {
+	if len(studentID) <= 6 {
+		return strings.Repeat("*", len(studentID))
+	}
+	return studentID[:4] + strings.Repeat("*", len(studentID)-6) + studentID[len(studentID)-2:]
+}
+
+// maskName 只保留姓名的第一个字
+func maskName(name string) string {
+	runes := []rune(name)
+	if len(runes) <= 1 {
+		return name
+	}
+	return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+}
+
+// linkStudent 判断答卷是否关联统一验证的学号
+func linkStudent() bool {
+	return global.Config.GetString("answer.studentKey") != ""
+}
+
+// studentKey 计算学号的 HMAC，未配置密钥或学号为空时返回空字符串
+func studentKey(studentID string) string {
+	if studentID == "" || !linkStudent() {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(global.Config.GetString("answer.studentKey")))
+	mac.Write([]byte(studentID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"QA-System/internal/dao"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const feedBatchSize = 100 // 每次查询哈希链条目的最大数量
+
+// answerListeners 本实例等待新答卷的订阅者
+type answerListeners struct {
+	mu        sync.Mutex
+	listeners map[int64]map[chan struct{}]struct{}
+}
+
+var feeds = &answerListeners{
+	listeners: make(map[int64]map[chan struct{}]struct{}),
+}
+
+// StreamAnswerSheets 以 Server-Sent Events 推送问卷的新答卷，事件ID为答卷在哈希链中的序号
+//
+// after 大于 0 时先补发哈希链中该序号之后提交的答卷，用于断线后续传；为 0 时只推送连接之后的答卷。
+// 答卷保存后才记入哈希链，且序号连续递增，因此并发提交的答卷即使保存完成的顺序与答卷ID不一致也不会遗漏。
+// 答卷记入哈希链后才发布答卷变化通知，被唤醒的连接总能查询到新条目。
+// 记入哈希链失败的答卷不会被推送，校验哈希链时会报告。
+func StreamAnswerSheets(c *gin.Context, sid int64, after int64) {
+	// 先订阅再查询，避免查询与订阅之间的答卷被遗漏
+	notify, cancel := feeds.listen(sid)
+	defer cancel()
+	if after <= 0 {
+		last, err := d.GetLastLedgerEntry(ctx, sid)
+		if err != nil {
+			zap.L().Error("获取哈希链最新条目失败", zap.Int64("survey_id", sid), zap.Error(err))
+			return
+		}
+		if last != nil {
+			after = last.Seq
+		}
+	}
+	send := func() bool {
+		for {
+			entries, err := d.GetLedgerEntriesAfter(ctx, sid, after, feedBatchSize)
+			if err != nil {
+				zap.L().Error("获取新答卷失败", zap.Int64("survey_id", sid), zap.Error(err))
+				return false
+			}
+			ids := make([]primitive.ObjectID, 0, len(entries))
+			for _, entry := range entries {
+				if entry.Type == LedgerSubmit {
+					ids = append(ids, entry.AnswerID)
+				}
+			}
+			sheets := make(map[primitive.ObjectID]dao.AnswerSheet)
+			if len(ids) > 0 {
+				list, err := d.GetAnswerSheetsByIDs(ctx, sid, ids)
+				if err != nil {
+					zap.L().Error("获取新答卷失败", zap.Int64("survey_id", sid), zap.Error(err))
+					return false
+				}
+				for _, sheet := range list {
+					sheets[sheet.AnswerID] = sheet
+				}
+			}
+			for _, entry := range entries {
+				after = entry.Seq
+				// 推送前已被删除的答卷不再推送
+				sheet, ok := sheets[entry.AnswerID]
+				if entry.Type != LedgerSubmit || !ok {
+					continue
+				}
+				c.Render(-1, sse.Event{Id: strconv.FormatInt(entry.Seq, 10), Event: "answer", Data: sheet})
+			}
+			if len(entries) < feedBatchSize {
+				return true
+			}
+		}
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	if !send() {
+		return
+	}
+	c.Writer.Flush()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(_ io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-notify:
+			return send()
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+		}
+		return true
+	})
+}
+
+// listen 等待问卷的答卷变化，返回的函数用于取消订阅
+func (l *answerListeners) listen(sid int64) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	l.mu.Lock()
+	if l.listeners[sid] == nil {
+		l.listeners[sid] = make(map[chan struct{}]struct{})
+	}
+	l.listeners[sid][ch] = struct{}{}
+	l.mu.Unlock()
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.listeners[sid], ch)
+		if len(l.listeners[sid]) == 0 {
+			delete(l.listeners, sid)
+		}
+	}
+}
+
+// notify 通知问卷的订阅者，尚未处理的通知会被合并
+func (l *answerListeners) notify(sid int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.listeners[sid] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/pkg/describe"
+
+	"go.uber.org/zap"
+)
+
+// 答题者上报的访问事件
+const (
+	VisitStart = "start" // 开始作答
+	VisitDraft = "draft" // 保存草稿，附带所在的分页序号
+)
+
+// FunnelSection 分页的到达和流失人数
+type FunnelSection struct {
+	SerialNum int    `json:"serial_num"`
+	Title     string `json:"title"`
+	Reached   int    `json:"reached"` // 开始作答后到达该分页的访问数
+	Dropped   int    `json:"dropped"` // 停留在该分页且未提交的访问数
+}
+
+// FunnelQuestion 题目的到达人数
+type FunnelQuestion struct {
+	SerialNum int    `json:"serial_num"`
+	Subject   string `json:"subject"`
+	Section   int    `json:"section"` // 所属分页序号 0为不分页
+	Reached   int    `json:"reached"`
+}
+
+// Funnel 问卷从打开到提交的漏斗
+type Funnel struct {
+	Views          int     `json:"views"`           // 获取问卷的次数
+	Starts         int     `json:"starts"`          // 开始作答的访问数
+	Submits        int     `json:"submits"`         // 提交的访问数
+	StartRate      float64 `json:"start_rate"`      // 开始作答数占获取次数的百分比
+	CompletionRate float64 `json:"completion_rate"` // 提交数占开始作答数的百分比
+	ConversionRate float64 `json:"conversion_rate"` // 提交数占获取次数的百分比
+	// 从开始作答到提交的时长中位数，单位为秒，只统计上报过开始作答的访问
+	MedianCompletionSeconds float64          `json:"median_completion_seconds"`
+	Sections                []FunnelSection  `json:"sections"`  // 问卷未分页时为空
+	Questions               []FunnelQuestion `json:"questions"` // 问卷未分页时到达人数均为开始作答数
+	DropOff                 *FunnelQuestion  `json:"drop_off"`  // 流失最多的分页的第一题，未分页或无人流失时为空
+}
+
+// NewVisit 记录答题者获取问卷，返回随机生成的访问标识，失败时只记录日志并返回空标识
+func NewVisit(sid int64) string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		zap.L().Error("生成访问标识失败", zap.Error(err))
+		return ""
+	}
+	visitID := hex.EncodeToString(buf)
+	err := d.CreateVisit(ctx, dao.Visit{VisitID: visitID, SurveyID: sid, ViewedAt: time.Now()})
+	if err != nil {
+		zap.L().Error("记录问卷访问失败", zap.Int64("survey_id", sid), zap.Error(err))
+		return ""
+	}
+	return visitID
+}
+
+// CheckVisitEvent 检查答题者上报的访问事件，保存草稿时的分页序号为 0 或问卷中的分页
+func CheckVisitEvent(sid int64, event string, section int) error {
+	if event != VisitStart && event != VisitDraft {
+		return errors.New("访问事件不存在")
+	}
+	if event == VisitStart || section == 0 {
+		return nil
+	}
+	sections, err := d.GetSectionsBySurveyID(ctx, sid)
+	if err != nil {
+		return err
+	}
+	for _, s := range sections {
+		if s.SerialNum == section {
+			return nil
+		}
+	}
+	return errors.New("分页" + strconv.Itoa(section) + "不存在")
+}
+
+// RecordVisitEvent 记录答题者上报的访问事件，section 为保存草稿时所在的分页序号
+func RecordVisitEvent(sid int64, visitID string, event string, section int) error {
+	switch event {
+	case VisitStart:
+		return d.StartVisit(ctx, sid, visitID, time.Now())
+	case VisitDraft:
+		return d.ReachVisitSection(ctx, sid, visitID, section, time.Now())
+	default:
+		return errors.New("访问事件不存在")
+	}
+}
+
+// SubmitVisit 记录访问提交问卷，失败只记录日志
+func SubmitVisit(sid int64, visitID string) {
+	if visitID == "" {
+		return
+	}
+	if err := d.SubmitVisit(ctx, sid, visitID, time.Now()); err != nil {
+		zap.L().Error("记录问卷访问失败", zap.Int64("survey_id", sid), zap.Error(err))
+	}
+}
+
+// GetFunnel 统计问卷的访问漏斗
+//
+// 上报过开始作答、保存过草稿或已提交的访问都视为开始作答。开始作答后至少到达第一页，提交后到达所有分页。
+func GetFunnel(sid int64) (*Funnel, error) {
+	visits, err := d.GetVisitsBySurveyID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := d.GetSectionsBySurveyID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(sections, func(i, j int) bool { return sections[i].SerialNum < sections[j].SerialNum })
+
+	funnel := &Funnel{
+		Views:     len(visits),
+		Sections:  make([]FunnelSection, 0, len(sections)),
+		Questions: make([]FunnelQuestion, 0, len(questions)),
+	}
+	for _, section := range sections {
+		funnel.Sections = append(funnel.Sections, FunnelSection{SerialNum: section.SerialNum, Title: section.Title})
+	}
+	durations := make([]float64, 0)
+	reached := make([]int, 0) // 开始作答的访问到达的分页序号，已提交的为 math.MaxInt
+	for _, visit := range visits {
+		if visit.StartedAt == nil && visit.SubmittedAt == nil && visit.Section == 0 {
+			continue
+		}
+		funnel.Starts++
+		if visit.SubmittedAt == nil {
+			reached = append(reached, visit.Section)
+			continue
+		}
+		funnel.Submits++
+		reached = append(reached, math.MaxInt)
+		if visit.StartedAt != nil && visit.SubmittedAt.After(*visit.StartedAt) {
+			durations = append(durations, visit.SubmittedAt.Sub(*visit.StartedAt).Seconds())
+		}
+	}
+	funnel.StartRate = percentOf(funnel.Starts, funnel.Views)
+	funnel.CompletionRate = percentOf(funnel.Submits, funnel.Starts)
+	funnel.ConversionRate = percentOf(funnel.Submits, funnel.Views)
+	funnel.MedianCompletionSeconds = describe.Summarize(durations).Median
+
+	for _, serial := range reached {
+		current := currentSection(funnel.Sections, serial)
+		for i := 0; i <= current; i++ {
+			funnel.Sections[i].Reached++
+		}
+		if serial != math.MaxInt && current >= 0 {
+			funnel.Sections[current].Dropped++
+		}
+	}
+	serialMap := GetSectionSerialMap(sections)
+	for _, question := range questions {
+		item := FunnelQuestion{
+			SerialNum: question.SerialNum,
+			Subject:   question.Subject,
+			Section:   serialMap[question.SectionID],
+			Reached:   funnel.Starts,
+		}
+		for _, section := range funnel.Sections {
+			if section.SerialNum == item.Section {
+				item.Reached = section.Reached
+			}
+		}
+		funnel.Questions = append(funnel.Questions, item)
+	}
+	sort.Slice(funnel.Questions, func(i, j int) bool {
+		return funnel.Questions[i].SerialNum < funnel.Questions[j].SerialNum
+	})
+	funnel.DropOff = dropOffQuestion(funnel)
+	return funnel, nil
+}
+
+// currentSection 返回访问到达的最后一个分页的下标，未上报时视为停留在第一页，问卷未分页时返回 -1
+func currentSection(sections []FunnelSection, serial int) int {
+	if len(sections) == 0 {
+		return -1
+	}
+	current := 0
+	for i, section := range sections {
+		if section.SerialNum <= serial {
+			current = i
+		}
+	}
+	return current
+}
+
+// dropOffQuestion 返回流失最多的分页的第一题
+func dropOffQuestion(funnel *Funnel) *FunnelQuestion {
+	worst := -1
+	for i, section := range funnel.Sections {
+		if section.Dropped > 0 && (worst < 0 || section.Dropped > funnel.Sections[worst].Dropped) {
+			worst = i
+		}
+	}
+	if worst < 0 {
+		return nil
+	}
+	for _, question := range funnel.Questions {
+		if question.Section == funnel.Sections[worst].SerialNum {
+			return &question
+		}
+	}
+	return nil
+}
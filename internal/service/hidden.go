@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"unicode/utf8"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+)
+
+const (
+	maxHiddenFieldNum    = 10  // 每份问卷最多的隐藏字段数量
+	maxHiddenValueLength = 100 // 隐藏字段值的最大长度
+)
+
+// hiddenFieldPattern 隐藏字段名只允许字母开头的字母、数字和下划线，避免与查询参数和 MongoDB 字段路径冲突
+var hiddenFieldPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,31}$`)
+
+// CheckHiddenFields 检查问卷声明的隐藏字段
+func CheckHiddenFields(fields []string) error {
+	if len(fields) > maxHiddenFieldNum {
+		return errors.New("隐藏字段数量过多")
+	}
+	fieldMap := make(map[string]bool)
+	for _, field := range fields {
+		if !hiddenFieldPattern.MatchString(field) {
+			return errors.New("隐藏字段" + field + "只能包含字母、数字和下划线，且以字母开头")
+		}
+		if fieldMap[field] {
+			return errors.New("隐藏字段" + field + "重复")
+		}
+		fieldMap[field] = true
+	}
+	return nil
+}
+
+// IsHiddenField 判断字段是否为问卷声明的隐藏字段
+func IsHiddenField(survey *model.Survey, name string) bool {
+	for _, field := range survey.HiddenFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectHiddenFields 从提交的参数中收集问卷声明的隐藏字段，优先使用 values 中的值，其次使用 fallback 中的值
+func CollectHiddenFields(survey *model.Survey, values map[string]string,
+	fallback func(name string) string) map[string]string {
+	hidden := make(map[string]string)
+	for _, field := range survey.HiddenFields {
+		value, ok := values[field]
+		if !ok && fallback != nil {
+			value = fallback(field)
+		}
+		if value == "" {
+			continue
+		}
+		if utf8.RuneCountInString(value) > maxHiddenValueLength {
+			value = string([]rune(value)[:maxHiddenValueLength])
+		}
+		hidden[field] = value
+	}
+	return hidden
+}
+
+// GroupAnswerSheetsByHiddenField 按隐藏字段的值对答卷分组，未携带该字段的答卷归入空字符串
+func GroupAnswerSheetsByHiddenField(answerSheets []dao.AnswerSheet, field string) (
+	[]string, map[string][]dao.AnswerSheet) {
+	values := make([]string, 0)
+	groups := make(map[string][]dao.AnswerSheet)
+	for _, sheet := range answerSheets {
+		value := sheet.HiddenFields[field]
+		if _, ok := groups[value]; !ok {
+			values = append(values, value)
+		}
+		groups[value] = append(groups[value], sheet)
+	}
+	return values, groups
+}
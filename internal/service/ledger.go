@@ -0,0 +1,327 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"QA-System/internal/dao"
+	global "QA-System/internal/global/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// 哈希链条目类型
+const (
+	LedgerSubmit = "submit" // 提交答卷
+	LedgerDelete = "delete" // 管理员删除答卷
+	LedgerPurge  = "purge"  // 删除问卷及其全部答卷
+)
+
+const (
+	ledgerAppendRetries             = 5                // 并发追加冲突时的重试次数
+	defaultLedgerCheckpointInterval = 60 * time.Minute // 默认签名检查点的间隔
+)
+
+// LedgerDivergence 校验哈希链时发现的问题
+type LedgerDivergence struct {
+	Seq      int64  `json:"seq,omitempty"`
+	AnswerID string `json:"answer_id,omitempty"`
+	Problem  string `json:"problem"`
+}
+
+// LedgerReport 哈希链校验报告
+type LedgerReport struct {
+	SurveyID    int64              `json:"survey_id"`
+	Entries     int                `json:"entries"`     // 哈希链条目数
+	Head        string             `json:"head"`        // 最新哈希
+	Answers     int                `json:"answers"`     // 哈希链记录的现存答卷数
+	Checkpoints int                `json:"checkpoints"` // 签名检查点数
+	Valid       bool               `json:"valid"`
+	Divergences []LedgerDivergence `json:"divergences"`
+}
+
+// answerDigest 参与摘要的答案内容
+type answerDigest struct {
+	QuestionID int    `json:"question_id"`
+	Content    string `json:"content"`
+}
+
+// sheetDigest 参与摘要的答卷内容，不包括提交后会被系统更新的字段
+type sheetDigest struct {
+	SurveyID     int64             `json:"survey_id"`
+	AnswerID     string            `json:"answer_id"`
+	Time         string            `json:"time"`
+	Answers      []answerDigest    `json:"answers"`
+	HiddenFields map[string]string `json:"hidden_fields,omitempty"`
+	Lang         string            `json:"lang,omitempty"`
+	Receipt      string            `json:"receipt,omitempty"`
+	StudentKey   string            `json:"student_key,omitempty"`
+}
+
+// recordSubmission 将提交的答卷记入哈希链
+func recordSubmission(answerSheet dao.AnswerSheet) error {
+	digest, err := digestAnswerSheet(answerSheet)
+	if err != nil {
+		return err
+	}
+	return appendLedger(dao.LedgerEntry{
+		SurveyID: answerSheet.SurveyID,
+		Type:     LedgerSubmit,
+		AnswerID: answerSheet.AnswerID,
+		Digest:   digest,
+	})
+}
+
+// appendLedger 在问卷哈希链末尾追加条目，序号冲突时重新读取链尾后重试
+func appendLedger(entry dao.LedgerEntry) error {
+	var err error
+	for i := 0; i < ledgerAppendRetries; i++ {
+		var last *dao.LedgerEntry
+		last, err = d.GetLastLedgerEntry(ctx, entry.SurveyID)
+		if err != nil {
+			return err
+		}
+		entry.Seq, entry.PrevHash = 1, ""
+		if last != nil {
+			entry.Seq, entry.PrevHash = last.Seq+1, last.Hash
+		}
+		// MongoDB 只保存到毫秒
+		entry.Time = time.Now().Truncate(time.Millisecond)
+		entry.Hash = hashLedgerEntry(entry)
+		err = d.InsertLedgerEntry(ctx, entry)
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// VerifyLedger 重新计算问卷的哈希链并与答卷和签名检查点比对
+func VerifyLedger(sid int64) (*LedgerReport, error) {
+	entries, err := d.GetLedgerEntries(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	report := &LedgerReport{SurveyID: sid, Entries: len(entries), Divergences: make([]LedgerDivergence, 0)}
+	diverge := func(seq int64, answerID, problem string) {
+		report.Divergences = append(report.Divergences,
+			LedgerDivergence{Seq: seq, AnswerID: answerID, Problem: problem})
+	}
+	// 按哈希链重放答卷的提交和删除
+	hashes := make(map[int64]string)
+	live := make(map[string]string)
+	prev := ""
+	for i, entry := range entries {
+		if entry.Seq != int64(i+1) {
+			diverge(entry.Seq, "", fmt.Sprintf("序号不连续，应为%d", i+1))
+		}
+		if entry.PrevHash != prev {
+			diverge(entry.Seq, "", "与上一条目的哈希不衔接")
+		}
+		if hashLedgerEntry(entry) != entry.Hash {
+			diverge(entry.Seq, "", "条目内容与哈希不一致")
+		}
+		prev = entry.Hash
+		hashes[entry.Seq] = entry.Hash
+		answerID := entry.AnswerID.Hex()
+		switch entry.Type {
+		case LedgerSubmit:
+			live[answerID] = entry.Digest
+		case LedgerDelete:
+			if _, ok := live[answerID]; !ok {
+				diverge(entry.Seq, answerID, "删除了未记录的答卷")
+			}
+			delete(live, answerID)
+		case LedgerPurge:
+			live = make(map[string]string)
+		}
+	}
+	report.Head = prev
+	report.Answers = len(live)
+	// 比对现存答卷
+	sheets, _, err := d.GetAnswerSheetBySurveyID(ctx, sid, 0, 0, "", false, nil)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, sheet := range sheets {
+		answerID := sheet.AnswerID.Hex()
+		seen[answerID] = true
+		digest, ok := live[answerID]
+		if !ok {
+			diverge(0, answerID, "答卷未记入哈希链")
+			continue
+		}
+		current, err := digestAnswerSheet(sheet)
+		if err != nil {
+			return nil, err
+		}
+		if current != digest {
+			diverge(0, answerID, "答卷内容被修改")
+		}
+	}
+	for answerID := range live {
+		if !seen[answerID] {
+			diverge(0, answerID, "答卷被删除但未记入哈希链")
+		}
+	}
+	// 校验签名检查点
+	checkpoints, err := d.GetLedgerCheckpoints(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	report.Checkpoints = len(checkpoints)
+	for _, checkpoint := range checkpoints {
+		if !verifyCheckpoint(checkpoint) {
+			diverge(checkpoint.Seq, "", "检查点签名无效")
+			continue
+		}
+		if hashes[checkpoint.Seq] != checkpoint.Hash {
+			diverge(checkpoint.Seq, "", "哈希链与已签名的检查点不一致")
+		}
+	}
+	report.Valid = len(report.Divergences) == 0
+	return report, nil
+}
+
+// CreateCheckpoints 为自上次检查点后有新条目的哈希链签名
+func CreateCheckpoints() error {
+	key, err := getLedgerSignKey()
+	if err != nil || key == nil {
+		return err
+	}
+	sids, err := d.GetLedgerSurveys(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sid := range sids {
+		last, err := d.GetLastLedgerEntry(ctx, sid)
+		if err != nil {
+			return err
+		}
+		checkpoint, err := d.GetLastLedgerCheckpoint(ctx, sid)
+		if err != nil {
+			return err
+		}
+		if last == nil || (checkpoint != nil && checkpoint.Seq >= last.Seq) {
+			continue
+		}
+		next := dao.LedgerCheckpoint{
+			SurveyID:  sid,
+			Seq:       last.Seq,
+			Hash:      last.Hash,
+			Time:      time.Now().Truncate(time.Millisecond),
+			PublicKey: base64.StdEncoding.EncodeToString(publicKeyOf(key)),
+		}
+		next.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, checkpointMessage(next)))
+		if err := d.SaveLedgerCheckpoint(ctx, next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartLedgerWorker 定时为哈希链生成签名检查点，未配置签名密钥时不启动
+func StartLedgerWorker() {
+	key, err := getLedgerSignKey()
+	if err != nil {
+		zap.L().Error("哈希链签名密钥无效", zap.Error(err))
+		return
+	}
+	if key == nil {
+		zap.L().Warn("未配置哈希链签名密钥，不生成签名检查点")
+		return
+	}
+	interval := defaultLedgerCheckpointInterval
+	if minutes := global.Config.GetInt("ledger.checkpointInterval"); minutes > 0 {
+		interval = time.Duration(minutes) * time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := CreateCheckpoints(); err != nil {
+				zap.L().Error("生成哈希链检查点失败", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// digestAnswerSheet 计算答卷内容的摘要
+func digestAnswerSheet(answerSheet dao.AnswerSheet) (string, error) {
+	content := sheetDigest{
+		SurveyID:     answerSheet.SurveyID,
+		AnswerID:     answerSheet.AnswerID.Hex(),
+		Time:         answerSheet.Time,
+		Answers:      make([]answerDigest, 0, len(answerSheet.Answers)),
+		HiddenFields: answerSheet.HiddenFields,
+		Lang:         answerSheet.Lang,
+		Receipt:      answerSheet.Receipt,
+		StudentKey:   answerSheet.StudentKey,
+	}
+	for _, answer := range answerSheet.Answers {
+		content.Answers = append(content.Answers, answerDigest{QuestionID: answer.QuestionID, Content: answer.Content})
+	}
+	// 结构体和字符串映射的序列化结果是确定的
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hashLedgerEntry 计算哈希链条目的哈希
+func hashLedgerEntry(entry dao.LedgerEntry) string {
+	data := fmt.Sprintf("%s|%d|%d|%s|%s|%s|%d|%d", entry.PrevHash, entry.SurveyID, entry.Seq, entry.Type,
+		entry.AnswerID.Hex(), entry.Digest, entry.Operator, entry.Time.UnixMilli())
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func checkpointMessage(checkpoint dao.LedgerCheckpoint) []byte {
+	return []byte(fmt.Sprintf("%d|%d|%s|%d", checkpoint.SurveyID, checkpoint.Seq, checkpoint.Hash,
+		checkpoint.Time.UnixMilli()))
+}
+
+// verifyCheckpoint 校验检查点签名，公钥需与当前配置的签名密钥一致
+func verifyCheckpoint(checkpoint dao.LedgerCheckpoint) bool {
+	publicKey, err := base64.StdEncoding.DecodeString(checkpoint.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	if key, err := getLedgerSignKey(); err == nil && key != nil && !publicKeyOf(key).Equal(ed25519.PublicKey(publicKey)) {
+		return false
+	}
+	signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(publicKey, checkpointMessage(checkpoint), signature)
+}
+
+// getLedgerSignKey 获取配置的 ed25519 签名密钥，未配置时返回 nil
+func getLedgerSignKey() (ed25519.PrivateKey, error) {
+	seed := global.Config.GetString("ledger.signKey")
+	if seed == "" {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil || len(data) != ed25519.SeedSize {
+		return nil, errors.New("ledger.signKey 必须是 base64 编码的 32 字节种子")
+	}
+	return ed25519.NewKeyFromSeed(data), nil
+}
+
+func publicKeyOf(key ed25519.PrivateKey) ed25519.PublicKey {
+	publicKey := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	copy(publicKey, key[ed25519.SeedSize:])
+	return publicKey
+}
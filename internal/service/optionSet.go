@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+)
+
+// CreateOptionSet 创建选项集
+func CreateOptionSet(uid int, name, desc string, nodes []dao.OptionSetNode) (int, error) {
+	set, err := d.CreateOptionSet(ctx, model.OptionSet{UserID: uid, Name: name, Description: desc, Version: 1})
+	if err != nil {
+		return 0, err
+	}
+	err = createOptionSetItems(set.ID, set.Version, 0, nodes)
+	return set.ID, err
+}
+
+// UpdateOptionSet 修改选项集，修改后的条目保存为新版本，历史版本保留
+func UpdateOptionSet(set *model.OptionSet, name, desc string, nodes []dao.OptionSetNode) error {
+	version := set.Version + 1
+	// 先写入新版本的条目再切换版本号，避免读取到不完整的版本
+	err := createOptionSetItems(set.ID, version, 0, nodes)
+	if err != nil {
+		return err
+	}
+	return d.UpdateOptionSet(ctx, model.OptionSet{ID: set.ID, Name: name, Description: desc, Version: version})
+}
+
+// GetOptionSetByID 根据ID获取选项集
+func GetOptionSetByID(id int) (*model.OptionSet, error) {
+	return d.GetOptionSetByID(ctx, id)
+}
+
+// GetOptionSets 分页查询选项集
+func GetOptionSets(keyword string, pageNum, pageSize int) ([]model.OptionSet, int64, error) {
+	return d.GetOptionSets(ctx, keyword, pageNum, pageSize)
+}
+
+// DeleteOptionSet 删除选项集
+func DeleteOptionSet(id int) error {
+	return d.DeleteOptionSet(ctx, id)
+}
+
+// IsOptionSetInUse 判断选项集是否被问题绑定
+func IsOptionSetInUse(id int) (bool, error) {
+	count, err := d.CountQuestionsByOptionSetID(ctx, id)
+	return count > 0, err
+}
+
+// GetOptionSetTree 获取选项集指定版本的条目树，version 为 0 时获取最新版本
+func GetOptionSetTree(set *model.OptionSet, version int) ([]dao.OptionSetNode, error) {
+	if version == 0 {
+		version = set.Version
+	}
+	items, err := d.GetOptionSetItems(ctx, set.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int][]model.OptionSetItem)
+	for _, item := range items {
+		children[item.ParentID] = append(children[item.ParentID], item)
+	}
+	return buildOptionSetTree(children, 0), nil
+}
+
+// CheckOptionSetNodes 检查选项集条目是否为空或同级重复
+func CheckOptionSetNodes(nodes []dao.OptionSetNode) error {
+	contentMap := make(map[string]bool)
+	for _, node := range nodes {
+		if strings.TrimSpace(node.Content) == "" {
+			return errors.New("选项集条目内容为空")
+		}
+		if strings.Contains(node.Content, "┋") {
+			return errors.New("选项集条目" + node.Content + "包含非法字符┋")
+		}
+		if contentMap[node.Content] {
+			return errors.New("选项集条目" + node.Content + "重复")
+		}
+		contentMap[node.Content] = true
+		if err := CheckOptionSetNodes(node.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetQuestionOptions 获取问题的选项，绑定选项集的选择题使用选项集的第一级条目作为选项
+func GetQuestionOptions(question model.Question) ([]model.Option, error) {
+	if question.OptionSetID == 0 || (question.QuestionType != 1 && question.QuestionType != 2) {
+		return d.GetOptionsByQuestionID(ctx, question.ID)
+	}
+	nodes, err := getQuestionOptionSetTree(question)
+	if err != nil {
+		return nil, err
+	}
+	options := make([]model.Option, 0, len(nodes))
+	for i, node := range nodes {
+		options = append(options, model.Option{
+			QuestionID: question.ID,
+			SerialNum:  i + 1,
+			Content:    node.Content,
+		})
+	}
+	return options, nil
+}
+
+// GetQuestionCascade 获取级联选择题的条目树
+func GetQuestionCascade(question model.Question) ([]dao.OptionSetNode, error) {
+	if question.QuestionType != 7 || question.OptionSetID == 0 {
+		return make([]dao.OptionSetNode, 0), nil
+	}
+	return getQuestionOptionSetTree(question)
+}
+
+// CheckOptionSetAnswer 检查绑定选项集的问题答案是否在选项集内
+func CheckOptionSetAnswer(question *model.Question, answer string) error {
+	if question.OptionSetID == 0 || answer == "" {
+		return nil
+	}
+	nodes, err := getQuestionOptionSetTree(*question)
+	if err != nil {
+		return err
+	}
+	values := strings.Split(answer, "┋")
+	switch question.QuestionType {
+	case 1, 2:
+		if question.OtherOption {
+			return nil
+		}
+		for _, value := range values {
+			if findOptionSetNode(nodes, value) == nil {
+				return errors.New("问题" + strconv.Itoa(question.SerialNum) + "的选项" + value + "不在选项集内")
+			}
+		}
+	case 7:
+		// 级联选择需要从第一级开始逐级匹配，直到末级条目
+		for _, value := range values {
+			node := findOptionSetNode(nodes, value)
+			if node == nil {
+				return errors.New("问题" + strconv.Itoa(question.SerialNum) + "的选项" + value + "不在选项集内")
+			}
+			nodes = node.Children
+		}
+		if len(nodes) != 0 {
+			return errors.New("问题" + strconv.Itoa(question.SerialNum) + "未选择到最后一级")
+		}
+	}
+	return nil
+}
+
+func getQuestionOptionSetTree(question model.Question) ([]dao.OptionSetNode, error) {
+	set, err := d.GetOptionSetByID(ctx, question.OptionSetID)
+	if err != nil {
+		return nil, err
+	}
+	return GetOptionSetTree(set, question.OptionSetVersion)
+}
+
+func createOptionSetItems(id, version, parentID int, nodes []dao.OptionSetNode) error {
+	for i, node := range nodes {
+		item, err := d.CreateOptionSetItem(ctx, model.OptionSetItem{
+			OptionSetID: id,
+			Version:     version,
+			ParentID:    parentID,
+			SerialNum:   i + 1,
+			Content:     strings.TrimSpace(node.Content),
+		})
+		if err != nil {
+			return err
+		}
+		err = createOptionSetItems(id, version, item.ID, node.Children)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildOptionSetTree(children map[int][]model.OptionSetItem, parentID int) []dao.OptionSetNode {
+	nodes := make([]dao.OptionSetNode, 0, len(children[parentID]))
+	for _, item := range children[parentID] {
+		nodes = append(nodes, dao.OptionSetNode{
+			Content:  item.Content,
+			Children: buildOptionSetTree(children, item.ID),
+		})
+	}
+	return nodes
+}
+
+func findOptionSetNode(nodes []dao.OptionSetNode, content string) *dao.OptionSetNode {
+	for i := range nodes {
+		if nodes[i].Content == content {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
+
+// ResolveOptionSets 检查问题列表绑定的选项集，并为绑定选项集的选择题填充选项
+func ResolveOptionSets(questionList []dao.QuestionList) error {
+	for i, question := range questionList {
+		setting := question.QuestionSetting
+		if setting.OptionSetID == 0 {
+			if setting.QuestionType == 7 {
+				return errors.New("问题" + strconv.Itoa(question.SerialNum) + "为级联选择题但未绑定选项集")
+			}
+			continue
+		}
+		set, err := d.GetOptionSetByID(ctx, setting.OptionSetID)
+		if err != nil {
+			return err
+		}
+		if setting.OptionSetVersion < 0 || setting.OptionSetVersion > set.Version {
+			return errors.New("问题" + strconv.Itoa(question.SerialNum) + "绑定的选项集版本不存在")
+		}
+		if setting.QuestionType != 1 && setting.QuestionType != 2 {
+			continue
+		}
+		nodes, err := GetOptionSetTree(set, setting.OptionSetVersion)
+		if err != nil {
+			return err
+		}
+		options := make([]dao.Option, 0, len(nodes))
+		for j, node := range nodes {
+			options = append(options, dao.Option{SerialNum: j + 1, Content: node.Content})
+		}
+		questionList[i].Options = options
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"QA-System/internal/model"
+)
+
+// RespondentOrder 答题者看到的题目和选项顺序
+type RespondentOrder struct {
+	Positions    map[int]int   // 问题ID对应的展示位置 从1开始
+	OptionOrders map[int][]int // 打乱选项的问题ID对应的选项序号展示顺序
+}
+
+// GetRespondentKey 获取生成随机顺序的答题者标识，优先使用学号，其次使用会话标识
+func GetRespondentKey(studentID, session string) string {
+	if studentID != "" {
+		return "stu:" + studentID
+	}
+	return "session:" + session
+}
+
+// CheckRespondentKey 检查提交时带回的答题者标识，按学号生成的顺序只能由该学生提交
+func CheckRespondentKey(key, studentID string) error {
+	if strings.HasPrefix(key, "stu:") && key != GetRespondentKey(studentID, "") {
+		return errors.New("题目顺序不属于当前答题者")
+	}
+	return nil
+}
+
+// IsOrderShuffled 判断问卷、分页或问题是否打乱顺序，不打乱时所有答题者看到的顺序相同
+func IsOrderShuffled(survey *model.Survey, questions []model.Question) (bool, error) {
+	if survey.ShuffleQuestions {
+		return true, nil
+	}
+	for _, question := range questions {
+		if question.ShuffleOptions {
+			return true, nil
+		}
+	}
+	sections, err := d.GetSectionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, section := range sections {
+		if section.Shuffle {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ArrangeQuestions 按分页顺序排列展示给答题者的问题，并根据答题者标识确定性地打乱题目顺序，固定位置的题目保持不动
+func ArrangeQuestions(survey *model.Survey, questions []model.Question, sections []model.Section,
+	key string) []model.Question {
+	// 计算题不展示给答题者
+	sorted := make([]model.Question, 0, len(questions))
+	for _, question := range questions {
+		if question.QuestionType != 8 {
+			sorted = append(sorted, question)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SerialNum < sorted[j].SerialNum
+	})
+	if len(sections) == 0 {
+		if !survey.ShuffleQuestions {
+			return sorted
+		}
+		return shuffleQuestions(sorted, newRespondentRand(key, fmt.Sprintf("survey:%d", survey.ID)))
+	}
+	grouped := make(map[int][]model.Question)
+	for _, question := range sorted {
+		grouped[question.SectionID] = append(grouped[question.SectionID], question)
+	}
+	arranged := make([]model.Question, 0, len(sorted))
+	for _, section := range sections {
+		sectionQuestions := grouped[section.ID]
+		if section.Shuffle || survey.ShuffleQuestions {
+			sectionQuestions = shuffleQuestions(sectionQuestions,
+				newRespondentRand(key, fmt.Sprintf("section:%d", section.ID)))
+		}
+		arranged = append(arranged, sectionQuestions...)
+		delete(grouped, section.ID)
+	}
+	// 不属于任何分页的题目放在最后
+	for _, question := range sorted {
+		if _, ok := grouped[question.SectionID]; ok {
+			arranged = append(arranged, question)
+		}
+	}
+	return arranged
+}
+
+// ArrangeOptions 根据答题者标识确定性地打乱问题的选项顺序，固定位置的选项保持不动
+func ArrangeOptions(question model.Question, options []model.Option, key string) []model.Option {
+	if !question.ShuffleOptions {
+		return options
+	}
+	r := newRespondentRand(key, fmt.Sprintf("question:%d", question.ID))
+	perm := pinnedPermutation(len(options), func(i int) bool { return options[i].Pinned }, r)
+	arranged := make([]model.Option, len(options))
+	for i, j := range perm {
+		arranged[i] = options[j]
+	}
+	return arranged
+}
+
+// GetRespondentOrder 计算答题者看到的题目和选项顺序，提交时由服务端重新计算后记录到答卷中
+func GetRespondentOrder(survey *model.Survey, key string) (*RespondentOrder, error) {
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := d.GetSectionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	order := &RespondentOrder{
+		Positions:    make(map[int]int),
+		OptionOrders: make(map[int][]int),
+	}
+	for i, question := range ArrangeQuestions(survey, questions, sections, key) {
+		order.Positions[question.ID] = i + 1
+		if !question.ShuffleOptions {
+			continue
+		}
+		options, err := GetQuestionOptions(question)
+		if err != nil {
+			return nil, err
+		}
+		serials := make([]int, 0, len(options))
+		for _, option := range ArrangeOptions(question, options, key) {
+			serials = append(serials, option.SerialNum)
+		}
+		order.OptionOrders[question.ID] = serials
+	}
+	return order, nil
+}
+
+func shuffleQuestions(questions []model.Question, r *rand.Rand) []model.Question {
+	perm := pinnedPermutation(len(questions), func(i int) bool { return questions[i].Pinned }, r)
+	shuffled := make([]model.Question, len(questions))
+	for i, j := range perm {
+		shuffled[i] = questions[j]
+	}
+	return shuffled
+}
+
+// pinnedPermutation 生成只移动未固定元素的随机排列，返回每个位置对应的原下标
+func pinnedPermutation(n int, pinned func(int) bool, r *rand.Rand) []int {
+	perm := make([]int, n)
+	free := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		perm[i] = i
+		if !pinned(i) {
+			free = append(free, i)
+		}
+	}
+	shuffled := make([]int, len(free))
+	copy(shuffled, free)
+	r.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	for i, pos := range free {
+		perm[pos] = shuffled[i]
+	}
+	return perm
+}
+
+func newRespondentRand(key, scope string) *rand.Rand {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key + "|" + scope))
+	return rand.New(rand.NewSource(int64(h.Sum64()))) //nolint:gosec
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+)
+
+// 投票结果公开范围
+const (
+	ResultAlwaysPublic  = 0 // 始终公开
+	ResultNeverPublic   = 1 // 不公开
+	ResultAfterDeadline = 2 // 截止后公开
+	ResultAfterVoted    = 3 // 投票后公开
+)
+
+// 投票结果展示方式
+const (
+	ResultShowCount   = 0 // 展示票数
+	ResultShowRank    = 1 // 仅展示排名
+	ResultShowPercent = 2 // 仅展示百分比
+)
+
+// CheckResultPolicy 检查投票结果公开方式，投票后公开需要通过统一验证确认投票者
+func CheckResultPolicy(base dao.BaseConfig) error {
+	if base.ResultPolicy.Visibility == ResultAfterVoted && !base.Verify {
+		return errors.New("投票后公开结果需要开启统一验证")
+	}
+	return nil
+}
+
+// IsStreamVisible 判断实时结果推送是否对该学生公开
+func IsStreamVisible(survey *model.Survey, studentID string) (bool, error) {
+	if survey.Type != 1 || !survey.ResultPolicy.StreamPublic {
+		return false, nil
+	}
+	return IsResultVisible(survey, studentID)
+}
+
+// IsResultVisible 判断投票结果对该学生是否公开，studentID 为空表示未验证身份
+func IsResultVisible(survey *model.Survey, studentID string) (bool, error) {
+	switch survey.ResultPolicy.Visibility {
+	case ResultNeverPublic:
+		return false, nil
+	case ResultAfterDeadline:
+		return !survey.Deadline.IsZero() && survey.Deadline.Before(time.Now()), nil
+	case ResultAfterVoted:
+		if studentID == "" {
+			return false, nil
+		}
+		return d.HasRecordSheet(ctx, survey.ID, studentID)
+	default:
+		return true, nil
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+)
+
+// GetSectionsBySurveyID 根据问卷ID获取分页列表
+func GetSectionsBySurveyID(sid int64) ([]model.Section, error) {
+	return d.GetSectionsBySurveyID(ctx, sid)
+}
+
+// GetSectionSerialMap 获取分页ID到分页序号的映射
+func GetSectionSerialMap(sections []model.Section) map[int]int {
+	serialMap := make(map[int]int, len(sections))
+	for _, section := range sections {
+		serialMap[section.ID] = section.SerialNum
+	}
+	return serialMap
+}
+
+func createSections(sections []dao.Section, sid int64) (map[int]int, error) {
+	sectionIDs := make(map[int]int, len(sections))
+	for _, section := range sections {
+		s, err := d.CreateSection(ctx, model.Section{
+			SurveyID:    sid,
+			SerialNum:   section.SerialNum,
+			Title:       section.Title,
+			Description: section.Description,
+			Shuffle:     section.Shuffle,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sectionIDs[section.SerialNum] = s.ID
+	}
+	return sectionIDs, nil
+}
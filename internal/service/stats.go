@@ -0,0 +1,409 @@
+package service
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+)
+
+// StatsGroupResponse 按隐藏字段分组的统计结果
+type StatsGroupResponse struct {
+	Value      string                        `json:"value"`      // 隐藏字段的值
+	Total      int                           `json:"total"`      // 答卷数
+	Statistics []GetChooseStatisticsResponse `json:"statistics"` // 各题统计
+}
+
+// VoteOptionCount 投票选项的票数
+type VoteOptionCount struct {
+	SerialNum int    `json:"serial_num"` // 选项序号
+	Content   string `json:"content"`    // 选项内容
+	Count     int    `json:"count"`      // 选项数量
+	Rank      int    `json:"rank"`       // 选项排名
+}
+
+// VoteStatistics 投票问题的统计结果
+type VoteStatistics struct {
+	SerialNum    int               `json:"serial_num"`    // 问题序号
+	Question     string            `json:"question"`      // 问题内容
+	QuestionType int               `json:"question_type"` // 问题类型  1:单选 2:多选
+	Options      []VoteOptionCount `json:"options"`       // 选项内容
+}
+
+// StatsCheck 一项统计的比对结果
+type StatsCheck struct {
+	Name  string `json:"name"`
+	Match bool   `json:"match"`
+}
+
+// StatsReport 统计结果的比对报告
+type StatsReport struct {
+	SurveyID int64        `json:"survey_id"`
+	Total    int          `json:"total"` // 有效答卷数
+	Valid    bool         `json:"valid"` // 全部统计一致
+	Checks   []StatsCheck `json:"checks"`
+}
+
+// AggregateQuestionStats 在 MongoDB 中统计问卷的单选题和多选题，结果与 GenerateQuestionStats 一致
+//
+// dimension 不为空时按该隐藏字段的值分组，分组按首次提交的顺序排列；为空时没有答卷则不返回分组。
+func AggregateQuestionStats(sid int64, questions []model.Question, hidden map[string]string,
+	dimension string) ([]StatsGroupResponse, error) {
+	// 获取失败的问题只统计为其他选项，与 GenerateQuestionStats 一致
+	loaded, _ := loadQuestionOptions(questions) //nolint:errcheck
+	stats, err := d.AggregateAnswerStats(ctx, sid, hidden, dimension, questionIDsOfTypes(questions, 1, 2))
+	if err != nil {
+		return nil, err
+	}
+	return aggregatedQuestionStats(loaded, stats), nil
+}
+
+// aggregatedQuestionStats 根据聚合结果生成各分组的单选题和多选题统计
+func aggregatedQuestionStats(loaded questionOptions, stats *dao.AnswerStats) []StatsGroupResponse {
+	counts := countAggregatedStats(loaded, stats, 1, 2)
+	groups := make([]StatsGroupResponse, 0, len(stats.Groups))
+	for _, group := range stats.Groups {
+		groups = append(groups, StatsGroupResponse{
+			Value:      group.Value,
+			Total:      group.Total,
+			Statistics: formatQuestionStats(loaded, ensureGroup(counts, group.Value)),
+		})
+	}
+	return groups
+}
+
+// GenerateVoteStats 生成投票问卷的统计结果，作为 AggregateVoteStats 的参照实现
+func GenerateVoteStats(questions []model.Question, answerSheets []dao.AnswerSheet) ([]VoteStatistics, error) {
+	loaded, err := loadQuestionOptions(questions)
+	if err != nil {
+		return nil, err
+	}
+	return generateVoteStats(loaded, questions, answerSheets), nil
+}
+
+// generateVoteStats 在内存中逐份统计投票问卷的答卷
+func generateVoteStats(loaded questionOptions, questions []model.Question,
+	answerSheets []dao.AnswerSheet) []VoteStatistics {
+	if len(answerSheets) == 0 {
+		return emptyVoteStats(loaded, questions)
+	}
+	// 问题编号对应的选项编号对应的选项数量
+	optionCounts := make(map[int]map[int]int)
+	for _, sheet := range answerSheets {
+		for _, answer := range sheet.Answers {
+			question := loaded.questionMap[answer.QuestionID]
+			// 初始化选项统计（确保每个选项的计数存在且为 0）
+			if _, initialized := optionCounts[question.ID]; !initialized {
+				counts := ensureMap(optionCounts, question.ID)
+				for _, option := range loaded.optionsMap[answer.QuestionID] {
+					counts[option.SerialNum] = 0
+				}
+			}
+			if question.QuestionType != 1 {
+				continue
+			}
+			for _, answerOption := range strings.Split(answer.Content, "┋") {
+				countVoteOption(loaded, optionCounts, answer.QuestionID, answerOption, 1)
+			}
+		}
+	}
+	return formatVoteStats(loaded, optionCounts)
+}
+
+// AggregateVoteStats 在 MongoDB 中统计投票问卷，结果与 GenerateVoteStats 一致
+func AggregateVoteStats(sid int64, questions []model.Question) ([]VoteStatistics, error) {
+	loaded, err := loadQuestionOptions(questions)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := d.AggregateAnswerStats(ctx, sid, nil, "", questionIDsOfTypes(questions, 1))
+	if err != nil {
+		return nil, err
+	}
+	return aggregatedVoteStats(loaded, questions, stats), nil
+}
+
+// aggregatedVoteStats 根据聚合结果生成投票统计
+func aggregatedVoteStats(loaded questionOptions, questions []model.Question, stats *dao.AnswerStats) []VoteStatistics {
+	if len(stats.Groups) == 0 {
+		return emptyVoteStats(loaded, questions)
+	}
+	counts := countAggregatedStats(loaded, stats, 1)
+	return formatVoteStats(loaded, ensureGroup(counts, ""))
+}
+
+// VerifyStats 比对内存统计和 MongoDB 聚合统计的结果，包括总体、按各隐藏字段分组、投票统计和计数器
+func VerifyStats(sid int64) (*StatsReport, error) {
+	survey, err := d.GetSurveyByID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	sheets, err := GetSurveyAnswersBySurveyID(sid, nil)
+	if err != nil {
+		return nil, err
+	}
+	report := &StatsReport{SurveyID: sid, Total: len(sheets), Valid: true}
+	addCheck := func(name string, expected, actual any) error {
+		match, err := sameJSON(expected, actual)
+		if err != nil {
+			return err
+		}
+		report.Checks = append(report.Checks, StatsCheck{Name: name, Match: match})
+		report.Valid = report.Valid && match
+		return nil
+	}
+
+	overall, err := AggregateQuestionStats(sid, questions, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	expectedOverall := make([]StatsGroupResponse, 0, 1)
+	if len(sheets) > 0 {
+		expectedOverall = append(expectedOverall, StatsGroupResponse{
+			Total:      len(sheets),
+			Statistics: GenerateQuestionStats(questions, sheets),
+		})
+	}
+	if err := addCheck("overall", expectedOverall, overall); err != nil {
+		return nil, err
+	}
+
+	for _, field := range survey.HiddenFields {
+		groups, err := AggregateQuestionStats(sid, questions, nil, field)
+		if err != nil {
+			return nil, err
+		}
+		values, sheetGroups := GroupAnswerSheetsByHiddenField(sheets, field)
+		expected := make([]StatsGroupResponse, 0, len(values))
+		for _, value := range values {
+			expected = append(expected, StatsGroupResponse{
+				Value:      value,
+				Total:      len(sheetGroups[value]),
+				Statistics: GenerateQuestionStats(questions, sheetGroups[value]),
+			})
+		}
+		// 分组的顺序取决于答卷的读取顺序，按值比对
+		sortStatsGroups(expected)
+		sortStatsGroups(groups)
+		if err := addCheck("dimension:"+field, expected, groups); err != nil {
+			return nil, err
+		}
+	}
+
+	if survey.Type == 1 {
+		expected, err := GenerateVoteStats(questions, sheets)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := AggregateVoteStats(sid, questions)
+		if err != nil {
+			return nil, err
+		}
+		if err := addCheck("vote", expected, actual); err != nil {
+			return nil, err
+		}
+		// 只比对已有的计数器，不为未投票统计过的问卷建立计数器
+		counters, err := d.GetStatsCounters(ctx, sid)
+		if err != nil {
+			return nil, err
+		}
+		if counters != nil {
+			actual, err := GetVoteStats(sid, questions)
+			if err != nil {
+				return nil, err
+			}
+			if err := addCheck("counters", expected, actual); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// countAggregatedStats 将聚合结果换算为各分组中各题选项的次数，只统计 types 中题型的答案
+func countAggregatedStats(loaded questionOptions, stats *dao.AnswerStats, types ...int) map[string]map[int]map[int]int {
+	counted := make(map[int]bool)
+	for _, t := range types {
+		counted[t] = true
+	}
+	groups := make(map[string]map[int]map[int]int)
+	for _, presence := range stats.Presence {
+		optionCounts := ensureGroup(groups, presence.Group)
+		question := loaded.questionMap[presence.QuestionID]
+		if _, ok := optionCounts[question.ID]; ok {
+			continue
+		}
+		counts := ensureMap(optionCounts, question.ID)
+		for _, option := range loaded.optionsMap[presence.QuestionID] {
+			counts[option.SerialNum] = 0
+		}
+	}
+	for _, count := range stats.Counts {
+		if !counted[loaded.questionMap[count.QuestionID].QuestionType] {
+			continue
+		}
+		countVoteOption(loaded, ensureGroup(groups, count.Group), count.QuestionID, count.Content, count.Count)
+	}
+	return groups
+}
+
+// countVoteOption 为问题的答案计数，不是选项的答案计入其他选项
+func countVoteOption(loaded questionOptions, optionCounts map[int]map[int]int, qid int, content string, n int) {
+	if option, ok := loaded.optionAnswerMap[qid][content]; ok {
+		ensureMap(optionCounts, qid)[option.SerialNum] += n
+		return
+	}
+	ensureMap(optionCounts, qid)[0] += n
+}
+
+// emptyVoteStats 没有答卷时返回所有问题和选项，票数为 0
+func emptyVoteStats(loaded questionOptions, questions []model.Question) []VoteStatistics {
+	response := make([]VoteStatistics, 0, len(questions))
+	for _, q := range questions {
+		options := loaded.optionsMap[q.ID]
+		qOptions := make([]VoteOptionCount, 0, len(options)+1)
+		for _, option := range options {
+			qOptions = append(qOptions, VoteOptionCount{
+				SerialNum: option.SerialNum,
+				Content:   option.Content,
+				Count:     0,
+				Rank:      1,
+			})
+		}
+		// 如果支持 "其他" 选项，添加一项
+		if q.OtherOption {
+			qOptions = append(qOptions, VoteOptionCount{
+				SerialNum: 0,
+				Content:   "其他",
+				Count:     0,
+				Rank:      1,
+			})
+		}
+		response = append(response, VoteStatistics{
+			SerialNum:    q.SerialNum,
+			Question:     q.Subject,
+			QuestionType: q.QuestionType,
+			Options:      qOptions,
+		})
+	}
+	return response
+}
+
+// formatVoteStats 根据各题选项的票数生成投票统计结果，并按票数计算排名
+func formatVoteStats(loaded questionOptions, optionCounts map[int]map[int]int) []VoteStatistics {
+	response := make([]VoteStatistics, 0, len(optionCounts))
+	for qid, options := range optionCounts {
+		q := loaded.questionMap[qid]
+		var qOptions []VoteOptionCount
+		if q.OtherOption {
+			qOptions = make([]VoteOptionCount, 0, len(options)+1)
+			// 添加其他选项
+			qOptions = append(qOptions, VoteOptionCount{
+				SerialNum: 0,
+				Content:   "其他",
+				Count:     options[0],
+			})
+		} else {
+			qOptions = make([]VoteOptionCount, 0, len(options))
+		}
+		// 按序号排序
+		sortedSerialNums := make([]int, 0, len(options))
+		for oSerialNum := range options {
+			sortedSerialNums = append(sortedSerialNums, oSerialNum)
+		}
+		sort.Ints(sortedSerialNums)
+		for _, oSerialNum := range sortedSerialNums {
+			op := loaded.optionSerialNumMap[qid][oSerialNum]
+			qOptions = append(qOptions, VoteOptionCount{
+				SerialNum: op.SerialNum,
+				Content:   op.Content,
+				Count:     options[oSerialNum],
+			})
+		}
+
+		// 按数量降序排列，数量相同时按序号升序排列
+		sortedQOptions := make([]VoteOptionCount, len(qOptions))
+		copy(sortedQOptions, qOptions)
+		sort.Slice(sortedQOptions, func(i, j int) bool {
+			if sortedQOptions[i].Count == sortedQOptions[j].Count {
+				return sortedQOptions[i].SerialNum < sortedQOptions[j].SerialNum
+			}
+			return sortedQOptions[i].Count > sortedQOptions[j].Count
+		})
+		// 并列的选项排名相同，下一名次跳过并列的数量
+		rankMap := make(map[int]int)
+		currentRank := 1
+		for i := 0; i < len(sortedQOptions); i++ {
+			if i > 0 && sortedQOptions[i].Count < sortedQOptions[i-1].Count {
+				currentRank = i + 1
+			}
+			rankMap[sortedQOptions[i].SerialNum] = currentRank
+		}
+		for i := range qOptions {
+			qOptions[i].Rank = rankMap[qOptions[i].SerialNum]
+		}
+
+		response = append(response, VoteStatistics{
+			SerialNum:    q.SerialNum,
+			Question:     q.Subject,
+			QuestionType: q.QuestionType,
+			Options:      qOptions,
+		})
+	}
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].SerialNum < response[j].SerialNum
+	})
+	return response
+}
+
+// questionIDsOfTypes 获取指定题型的问题ID
+func questionIDsOfTypes(questions []model.Question, types ...int) []int {
+	ids := make([]int, 0, len(questions))
+	for _, question := range questions {
+		for _, t := range types {
+			if question.QuestionType == t {
+				ids = append(ids, question.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+func sortStatsGroups(groups []StatsGroupResponse) {
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Value < groups[j].Value
+	})
+}
+
+func sameJSON(a, b any) (bool, error) {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return string(x) == string(y), nil
+}
+
+func ensureGroup(m map[string]map[int]map[int]int, key string) map[int]map[int]int {
+	if m[key] == nil {
+		m[key] = make(map[int]map[int]int)
+	}
+	return m[key]
+}
+
+func ensureMap(m map[int]map[int]int, key int) map[int]int {
+	if m[key] == nil {
+		m[key] = make(map[int]int)
+	}
+	return m[key]
+}
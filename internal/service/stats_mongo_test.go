@@ -0,0 +1,115 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"QA-System/internal/dao"
+	database "QA-System/internal/pkg/database/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupMongoStats 连接 QA_TEST_MONGO_URI 指定的 MongoDB，在临时数据库中保存答卷，未设置时跳过测试
+//
+// 聚合统计只访问 MongoDB，MySQL 使用不会连接成功的占位地址。
+func setupMongoStats(t *testing.T) {
+	uri := os.Getenv("QA_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("未设置 QA_TEST_MONGO_URI，跳过 MongoDB 聚合统计测试")
+	}
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mdb := client.Database("qa_test_" + primitive.NewObjectID().Hex())
+	orm, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "test:test@tcp(127.0.0.1:1)/test",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent), DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection, previous := database.QA, d
+	database.QA = "qa"
+	d = dao.New(orm, mdb)
+	t.Cleanup(func() {
+		database.QA, d = collection, previous
+		mdb.Drop(ctx)          //nolint:errcheck
+		client.Disconnect(ctx) //nolint:errcheck
+	})
+}
+
+// saveStatsSheets 将答卷保存为问卷 sid 的有效答卷，返回补全了问卷ID和答卷ID的答卷
+func saveStatsSheets(t *testing.T, sid int64, sheets []dao.AnswerSheet) []dao.AnswerSheet {
+	t.Helper()
+	saved := make([]dao.AnswerSheet, 0, len(sheets))
+	for _, s := range sheets {
+		s.SurveyID = sid
+		s.AnswerID = primitive.NewObjectID()
+		s.Unique = true
+		if _, err := d.SaveAnswerSheet(ctx, s, nil); err != nil {
+			t.Fatal(err)
+		}
+		saved = append(saved, s)
+	}
+	return saved
+}
+
+func TestAggregateAnswerStatsMongo(t *testing.T) {
+	setupMongoStats(t)
+	loaded := statsFixtureOptions()
+	questionIDs := questionIDsOfTypes(statsQuestions, 1, 2)
+	for i, tc := range statsCases {
+		sheets := saveStatsSheets(t, int64(i+1), tc.sheets)
+		t.Run(tc.name, func(t *testing.T) {
+			stats, err := d.AggregateAnswerStats(ctx, int64(i+1), nil, tc.dimension, questionIDs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGroupStats(t, loaded, tc.dimension, sheets, aggregatedQuestionStats(loaded, stats))
+		})
+	}
+	// 按隐藏字段筛选后不分组
+	sheets := saveStatsSheets(t, int64(len(statsCases)+1), statsCases[len(statsCases)-1].sheets)
+	t.Run("按隐藏字段筛选", func(t *testing.T) {
+		hidden := map[string]string{"class": "1班"}
+		stats, err := d.AggregateAnswerStats(ctx, int64(len(statsCases)+1), hidden, "", questionIDs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filtered := make([]dao.AnswerSheet, 0)
+		for _, s := range sheets {
+			if s.HiddenFields["class"] == "1班" {
+				filtered = append(filtered, s)
+			}
+		}
+		assertGroupStats(t, loaded, "", filtered, aggregatedQuestionStats(loaded, stats))
+	})
+}
+
+func TestAggregateVoteStatsMongo(t *testing.T) {
+	setupMongoStats(t)
+	loaded := statsFixtureOptions()
+	questionIDs := questionIDsOfTypes(statsQuestions, 1)
+	cases := append([]statsCase{{name: "没有答卷"}}, statsCases...)
+	for i, tc := range cases {
+		if tc.dimension != "" {
+			continue
+		}
+		sheets := saveStatsSheets(t, int64(i+1), tc.sheets)
+		t.Run(tc.name, func(t *testing.T) {
+			stats, err := d.AggregateAnswerStats(ctx, int64(i+1), nil, "", questionIDs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameJSON(t, aggregatedVoteStats(loaded, statsQuestions, stats),
+				generateVoteStats(loaded, statsQuestions, sheets))
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+)
+
+// statsQuestions 单选题(含其他选项)、多选题、填空题和不含其他选项的单选题
+var statsQuestions = []model.Question{
+	{ID: 1, SerialNum: 1, Subject: "单选", QuestionType: 1, OtherOption: true},
+	{ID: 2, SerialNum: 2, Subject: "多选", QuestionType: 2},
+	{ID: 3, SerialNum: 3, Subject: "填空", QuestionType: 3},
+	{ID: 4, SerialNum: 4, Subject: "是否", QuestionType: 1},
+}
+
+var statsOptions = map[int][]model.Option{
+	1: {{QuestionID: 1, SerialNum: 1, Content: "A"}, {QuestionID: 1, SerialNum: 2, Content: "B"},
+		{QuestionID: 1, SerialNum: 3, Content: "C"}},
+	2: {{QuestionID: 2, SerialNum: 1, Content: "X"}, {QuestionID: 2, SerialNum: 2, Content: "Y"},
+		{QuestionID: 2, SerialNum: 3, Content: "Z"}},
+	3: {},
+	4: {{QuestionID: 4, SerialNum: 1, Content: "是"}, {QuestionID: 4, SerialNum: 2, Content: "否"}},
+}
+
+func statsFixtureOptions() questionOptions {
+	loaded := newQuestionOptions()
+	for _, question := range statsQuestions {
+		loaded.add(question, statsOptions[question.ID])
+	}
+	return loaded
+}
+
+// statsSheet 生成答卷，answers 为问题ID对应的答案
+func statsSheet(hidden map[string]string, answers map[int]string) dao.AnswerSheet {
+	s := dao.AnswerSheet{HiddenFields: hidden}
+	for _, question := range statsQuestions {
+		if content, ok := answers[question.ID]; ok {
+			s.Answers = append(s.Answers, dao.Answer{QuestionID: question.ID, Content: content})
+		}
+	}
+	return s
+}
+
+// statsCase 答卷及 MongoDB 对同一批答卷的聚合结果
+type statsCase struct {
+	name      string
+	dimension string // 分组的隐藏字段 为空时不分组
+	sheets    []dao.AnswerSheet
+	stats     dao.AnswerStats
+}
+
+var statsCases = []statsCase{
+	{
+		name: "单选和多选拆分",
+		sheets: []dao.AnswerSheet{
+			statsSheet(nil, map[int]string{1: "A", 2: "X┋Z", 3: "hello"}),
+			statsSheet(nil, map[int]string{1: "B", 2: "X", 3: ""}),
+			statsSheet(nil, map[int]string{1: "A", 2: "Y┋Z"}),
+		},
+		stats: dao.AnswerStats{
+			Groups: []dao.StatsGroup{{Value: "", Total: 3}},
+			Presence: []dao.StatsPresence{
+				{QuestionID: 1, Count: 3}, {QuestionID: 2, Count: 3}, {QuestionID: 3, Count: 2},
+			},
+			Counts: []dao.StatsCount{
+				{QuestionID: 1, Content: "A", Count: 2}, {QuestionID: 1, Content: "B", Count: 1},
+				{QuestionID: 2, Content: "X", Count: 2}, {QuestionID: 2, Content: "Y", Count: 1},
+				{QuestionID: 2, Content: "Z", Count: 2},
+			},
+		},
+	},
+	{
+		name: "其他答案",
+		sheets: []dao.AnswerSheet{
+			statsSheet(nil, map[int]string{1: "自填内容", 2: "X┋其他想法"}),
+			statsSheet(nil, map[int]string{1: "A", 4: "是"}),
+			statsSheet(nil, map[int]string{1: "自填内容", 4: "不确定"}),
+		},
+		stats: dao.AnswerStats{
+			Groups: []dao.StatsGroup{{Value: "", Total: 3}},
+			Presence: []dao.StatsPresence{
+				{QuestionID: 1, Count: 3}, {QuestionID: 2, Count: 1}, {QuestionID: 4, Count: 2},
+			},
+			Counts: []dao.StatsCount{
+				{QuestionID: 1, Content: "自填内容", Count: 2}, {QuestionID: 1, Content: "A", Count: 1},
+				{QuestionID: 2, Content: "X", Count: 1}, {QuestionID: 2, Content: "其他想法", Count: 1},
+				{QuestionID: 4, Content: "是", Count: 1}, {QuestionID: 4, Content: "不确定", Count: 1},
+			},
+		},
+	},
+	{
+		name: "空答案",
+		sheets: []dao.AnswerSheet{
+			statsSheet(nil, map[int]string{1: "", 2: ""}),
+			statsSheet(nil, map[int]string{1: "B"}),
+			statsSheet(nil, map[int]string{4: ""}),
+		},
+		stats: dao.AnswerStats{
+			Groups: []dao.StatsGroup{{Value: "", Total: 3}},
+			Presence: []dao.StatsPresence{
+				{QuestionID: 1, Count: 2}, {QuestionID: 2, Count: 1}, {QuestionID: 4, Count: 1},
+			},
+			Counts: []dao.StatsCount{
+				{QuestionID: 1, Content: "", Count: 1}, {QuestionID: 1, Content: "B", Count: 1},
+				{QuestionID: 2, Content: "", Count: 1}, {QuestionID: 4, Content: "", Count: 1},
+			},
+		},
+	},
+	{
+		name:      "按隐藏字段分组",
+		dimension: "class",
+		sheets: []dao.AnswerSheet{
+			statsSheet(map[string]string{"class": "1班"}, map[int]string{1: "A", 2: "X┋Y"}),
+			statsSheet(map[string]string{"class": "2班"}, map[int]string{1: "B"}),
+			statsSheet(map[string]string{"class": "1班"}, map[int]string{1: "C", 2: "Y"}),
+			statsSheet(nil, map[int]string{1: "A"}),
+		},
+		stats: dao.AnswerStats{
+			Groups: []dao.StatsGroup{{Value: "1班", Total: 2}, {Value: "2班", Total: 1}, {Value: "", Total: 1}},
+			Presence: []dao.StatsPresence{
+				{Group: "1班", QuestionID: 1, Count: 2}, {Group: "1班", QuestionID: 2, Count: 2},
+				{Group: "2班", QuestionID: 1, Count: 1}, {Group: "", QuestionID: 1, Count: 1},
+			},
+			Counts: []dao.StatsCount{
+				{Group: "1班", QuestionID: 1, Content: "A", Count: 1},
+				{Group: "1班", QuestionID: 1, Content: "C", Count: 1},
+				{Group: "1班", QuestionID: 2, Content: "X", Count: 1},
+				{Group: "1班", QuestionID: 2, Content: "Y", Count: 2},
+				{Group: "2班", QuestionID: 1, Content: "B", Count: 1},
+				{Group: "", QuestionID: 1, Content: "A", Count: 1},
+			},
+		},
+	},
+}
+
+func TestAggregatedQuestionStats(t *testing.T) {
+	loaded := statsFixtureOptions()
+	for _, tc := range statsCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assertGroupStats(t, loaded, tc.dimension, tc.sheets, aggregatedQuestionStats(loaded, &tc.stats))
+		})
+	}
+}
+
+func TestAggregatedVoteStats(t *testing.T) {
+	loaded := statsFixtureOptions()
+	cases := append([]statsCase{{name: "没有答卷"}}, statsCases...)
+	for _, tc := range cases {
+		if tc.dimension != "" {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			assertSameJSON(t, aggregatedVoteStats(loaded, statsQuestions, &tc.stats),
+				generateVoteStats(loaded, statsQuestions, tc.sheets))
+		})
+	}
+}
+
+// assertGroupStats 比对各分组的聚合统计与该分组答卷的内存统计
+func assertGroupStats(t *testing.T, loaded questionOptions, dimension string, sheets []dao.AnswerSheet,
+	groups []StatsGroupResponse) {
+	t.Helper()
+	values := make(map[string]bool)
+	for _, s := range sheets {
+		values[s.HiddenFields[dimension]] = true
+	}
+	if len(groups) != len(values) {
+		t.Fatalf("got %d groups, want %d", len(groups), len(values))
+	}
+	for _, group := range groups {
+		groupSheets := make([]dao.AnswerSheet, 0)
+		for _, s := range sheets {
+			if dimension == "" || s.HiddenFields[dimension] == group.Value {
+				groupSheets = append(groupSheets, s)
+			}
+		}
+		if group.Total != len(groupSheets) {
+			t.Errorf("group %q: got total %d, want %d", group.Value, group.Total, len(groupSheets))
+		}
+		assertSameJSON(t, group.Statistics, generateQuestionStats(loaded, groupSheets))
+	}
+}
+
+func assertSameJSON(t *testing.T, got, want any) {
+	t.Helper()
+	same, err := sameJSON(got, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !same {
+		x, _ := json.Marshal(got)  //nolint:errcheck
+		y, _ := json.Marshal(want) //nolint:errcheck
+		t.Errorf("aggregated stats differ\n got: %s\nwant: %s", x, y)
+	}
+}
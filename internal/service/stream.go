@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultStreamThrottle = time.Second      // 默认同一问卷两次推送的最小间隔
+	streamHeartbeat       = 15 * time.Second // 心跳间隔，避免代理断开空闲连接
+)
+
+// voteUpdate 推送给订阅者的最新问卷设置和投票统计，问卷已删除时 survey 为 nil
+type voteUpdate struct {
+	survey *model.Survey
+	stats  []VoteStatistics
+}
+
+// resultHub 本实例的实时结果订阅者，同一问卷的多个订阅者共用一次统计
+type resultHub struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan voteUpdate]struct{}
+	scheduled   map[int64]bool      // 已安排推送的问卷
+	lastSent    map[int64]time.Time // 问卷上次推送的时间
+}
+
+var hub = &resultHub{
+	subscribers: make(map[int64]map[chan voteUpdate]struct{}),
+	scheduled:   make(map[int64]bool),
+	lastSent:    make(map[int64]time.Time),
+}
+
+// StartLiveUpdates 订阅各实例发布的答卷变化通知，推送给本实例的实时结果和新答卷订阅者
+func StartLiveUpdates() {
+	go func() {
+		for sid := range d.SubscribeStatsUpdates(ctx) {
+			hub.notify(sid)
+			feeds.notify(sid)
+		}
+	}()
+}
+
+// StreamVoteStats 以 Server-Sent Events 推送投票统计，连接时推送一次，之后在答卷变化时推送
+//
+// 每次推送和心跳时使用最新的问卷设置，visible 判断结果是否仍对该连接公开，不再公开或问卷已删除时关闭连接；
+// format 将统计结果转换为推送的数据，如按问卷的展示方式隐藏票数。
+func StreamVoteStats(c *gin.Context, sid int64, visible func(*model.Survey) bool,
+	format func(*model.Survey, []VoteStatistics) any) {
+	updates, cancel := hub.subscribe(sid)
+	defer cancel()
+	update, err := currentVoteUpdate(sid)
+	if err != nil {
+		zap.L().Error("获取投票统计失败", zap.Int64("survey_id", sid), zap.Error(err))
+		return
+	}
+	if update.survey == nil || !visible(update.survey) {
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("statistics", format(update.survey, update.stats))
+	c.Writer.Flush()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(_ io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case update := <-updates:
+			if update.survey == nil || !visible(update.survey) {
+				return false
+			}
+			c.SSEvent("statistics", format(update.survey, update.stats))
+		case <-heartbeat.C:
+			// 没有新答卷时也要及时发现公开设置的变化
+			survey, err := d.GetSurveyByID(ctx, sid)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false
+			} else if err != nil {
+				zap.L().Error("获取问卷失败", zap.Int64("survey_id", sid), zap.Error(err))
+			} else if !visible(survey) {
+				return false
+			}
+			c.SSEvent("ping", time.Now().Unix())
+		}
+		return true
+	})
+}
+
+// subscribe 订阅问卷的实时结果，返回的函数用于取消订阅
+func (h *resultHub) subscribe(sid int64) (<-chan voteUpdate, func()) {
+	ch := make(chan voteUpdate, 1)
+	h.mu.Lock()
+	if h.subscribers[sid] == nil {
+		h.subscribers[sid] = make(map[chan voteUpdate]struct{})
+	}
+	h.subscribers[sid][ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[sid], ch)
+		if len(h.subscribers[sid]) == 0 {
+			delete(h.subscribers, sid)
+			delete(h.lastSent, sid)
+		}
+	}
+}
+
+// notify 安排推送问卷的最新结果，距上次推送不足间隔时延后，期间的变化合并为一次推送
+func (h *resultHub) notify(sid int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subscribers[sid]) == 0 || h.scheduled[sid] {
+		return
+	}
+	h.scheduled[sid] = true
+	wait := time.Until(h.lastSent[sid].Add(getStreamThrottle()))
+	if wait < 0 {
+		wait = 0
+	}
+	time.AfterFunc(wait, func() {
+		h.broadcast(sid)
+	})
+}
+
+// broadcast 重新读取问卷并统计，推送给全部订阅者，订阅者来不及接收时只保留最新结果
+func (h *resultHub) broadcast(sid int64) {
+	h.mu.Lock()
+	delete(h.scheduled, sid)
+	if len(h.subscribers[sid]) == 0 {
+		h.mu.Unlock()
+		return
+	}
+	h.lastSent[sid] = time.Now()
+	h.mu.Unlock()
+
+	update, err := currentVoteUpdate(sid)
+	if err != nil {
+		zap.L().Error("获取投票统计失败", zap.Int64("survey_id", sid), zap.Error(err))
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[sid] {
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// currentVoteUpdate 读取问卷的最新设置和投票统计
+func currentVoteUpdate(sid int64) (voteUpdate, error) {
+	survey, err := d.GetSurveyByID(ctx, sid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return voteUpdate{}, nil
+	} else if err != nil {
+		return voteUpdate{}, err
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, sid)
+	if err != nil {
+		return voteUpdate{}, err
+	}
+	stats, err := GetVoteStats(sid, questions)
+	return voteUpdate{survey: survey, stats: stats}, err
+}
+
+func getStreamThrottle() time.Duration {
+	if ms := global.Config.GetInt("stream.throttle"); ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return defaultStreamThrottle
+}
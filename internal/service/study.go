@@ -0,0 +1,433 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+
+	"github.com/zjutjh/WeJH-SDK/excel"
+)
+
+const studySignificance = 0.05 // 判定变化显著的 p 值阈值
+
+// StudyTrend 研究各轮次的结果及相邻轮次之间的变化
+type StudyTrend struct {
+	Waves []StudyWaveStats `json:"waves"`
+	Items []StudyItemTrend `json:"items"`
+}
+
+// StudyWaveStats 轮次概况
+type StudyWaveStats struct {
+	SurveyID int64  `json:"survey_id"`
+	Label    string `json:"label"`
+	Title    string `json:"title"`  // 问卷标题
+	Sheets   int    `json:"sheets"` // 有效答卷数
+}
+
+// StudyItemTrend 研究题目在各轮次的结果
+type StudyItemTrend struct {
+	Title   string          `json:"title"`
+	Options []string        `json:"options"`
+	Waves   []StudyItemWave `json:"waves"`   // 与 StudyTrend.Waves 一一对应
+	Changes []StudyChange   `json:"changes"` // 相邻两个有人作答的轮次之间的变化
+}
+
+// StudyItemWave 研究题目在一个轮次的结果
+type StudyItemWave struct {
+	SerialNum   int        `json:"serial_num"`  // 对应的问题序号 0为该轮次没有对应的问题
+	Question    string     `json:"question"`    // 对应的问题题目
+	Respondents int        `json:"respondents"` // 作答该题的答卷数
+	Unmapped    int        `json:"unmapped"`    // 选择了不能对应到统一选项的内容的答卷数
+	Counts      []int      `json:"counts"`      // 与 Options 一一对应
+	Percents    []float64  `json:"percents"`    // 占作答答卷数的百分比
+	CIs         []Interval `json:"cis"`         // 百分比的 95% 置信区间
+}
+
+// StudyChange 两个轮次之间各选项占比的变化
+type StudyChange struct {
+	From    string         `json:"from"` // 轮次名称
+	To      string         `json:"to"`
+	Options []OptionChange `json:"options"` // 与 Options 一一对应
+}
+
+// OptionChange 选项占比的变化，显著性使用双比例 z 检验
+type OptionChange struct {
+	Option      string  `json:"option"`
+	Diff        float64 `json:"diff"`        // 变化的百分点
+	PValue      float64 `json:"p_value"`     // 双侧 p 值
+	Significant bool    `json:"significant"` // p 值小于 0.05
+}
+
+// studyWave 加载了问卷、问题和有效答卷的轮次
+type studyWave struct {
+	model.StudyWave
+	survey    *model.Survey
+	questions []model.Question
+	sheets    []dao.AnswerSheet
+}
+
+// CheckStudy 检查研究的轮次和对应题目，轮次问卷的管理权限需另行检查
+func CheckStudy(waves []model.StudyWave, items []model.StudyItem) error {
+	surveys := make(map[int64]bool)
+	labels := make(map[string]bool)
+	for _, wave := range waves {
+		if surveys[wave.SurveyID] {
+			return errors.New("问卷" + strconv.FormatInt(wave.SurveyID, 10) + "重复")
+		}
+		surveys[wave.SurveyID] = true
+		if strings.TrimSpace(wave.Label) == "" {
+			return errors.New("轮次名称为空")
+		}
+		if labels[wave.Label] {
+			return errors.New("轮次名称" + wave.Label + "重复")
+		}
+		labels[wave.Label] = true
+	}
+	questions := make(map[int64][]model.Question)
+	for _, item := range items {
+		options := make(map[string]bool)
+		for _, option := range item.Options {
+			if strings.TrimSpace(option) == "" {
+				return errors.New("研究题目" + item.Title + "的选项为空")
+			}
+			if options[option] {
+				return errors.New("研究题目" + item.Title + "的选项" + option + "重复")
+			}
+			options[option] = true
+		}
+		linked := make(map[int64]bool)
+		for _, link := range item.Questions {
+			sid := strconv.FormatInt(link.SurveyID, 10)
+			if !surveys[link.SurveyID] {
+				return errors.New("研究题目" + item.Title + "对应的问卷" + sid + "不是研究的轮次")
+			}
+			if linked[link.SurveyID] {
+				return errors.New("研究题目" + item.Title + "在问卷" + sid + "中对应了多个问题")
+			}
+			linked[link.SurveyID] = true
+			if _, ok := questions[link.SurveyID]; !ok {
+				list, err := d.GetQuestionsBySurveyID(ctx, link.SurveyID)
+				if err != nil {
+					return err
+				}
+				questions[link.SurveyID] = list
+			}
+			question := findQuestionBySerial(questions[link.SurveyID], link.SerialNum)
+			if question == nil {
+				return errors.New("问卷" + sid + "的问题" + strconv.Itoa(link.SerialNum) + "不存在")
+			}
+			if question.QuestionType != 1 && question.QuestionType != 2 {
+				return errors.New("研究题目只能对应单选题或多选题")
+			}
+			list, err := GetQuestionOptions(*question)
+			if err != nil {
+				return err
+			}
+			contents := make(map[string]bool)
+			for _, option := range list {
+				contents[option.Content] = true
+			}
+			for content, target := range link.OptionMap {
+				if !contents[content] {
+					return errors.New("问卷" + sid + "的问题" + strconv.Itoa(link.SerialNum) + "没有选项" + content)
+				}
+				if !options[target] {
+					return errors.New("选项" + content + "对应的统一选项" + target + "不存在")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CreateStudy 创建研究，轮次按列表顺序排列
+func CreateStudy(uid int, name, desc string, waves []model.StudyWave, items []model.StudyItem) (int, error) {
+	study, err := d.CreateStudy(ctx, model.Study{UserID: uid, Name: name, Description: desc, Items: items}, waves)
+	return study.ID, err
+}
+
+// UpdateStudy 修改研究，轮次按列表顺序排列
+func UpdateStudy(id int, name, desc string, waves []model.StudyWave, items []model.StudyItem) error {
+	return d.UpdateStudy(ctx, model.Study{ID: id, Name: name, Description: desc, Items: items}, waves)
+}
+
+// GetStudyByID 根据ID获取研究
+func GetStudyByID(id int) (*model.Study, error) {
+	return d.GetStudyByID(ctx, id)
+}
+
+// GetStudyWaves 获取研究的全部轮次
+func GetStudyWaves(id int) ([]model.StudyWave, error) {
+	return d.GetStudyWaves(ctx, id)
+}
+
+// GetStudies 分页查询研究，uid 为 0 时查询全部
+func GetStudies(uid int, pageNum, pageSize int) ([]model.Study, int64, error) {
+	return d.GetStudies(ctx, uid, pageNum, pageSize)
+}
+
+// DeleteStudy 删除研究，不影响轮次的问卷
+func DeleteStudy(id int) error {
+	return d.DeleteStudy(ctx, id)
+}
+
+// GetStudyTrend 统计研究题目在各轮次的结果及变化
+func GetStudyTrend(study *model.Study) (*StudyTrend, error) {
+	waves, err := loadStudyWaves(study.ID)
+	if err != nil {
+		return nil, err
+	}
+	return studyTrend(study, waves), nil
+}
+
+// ExportStudy 导出研究的趋势、变化和合并了各轮次答卷的研究题目答案
+func ExportStudy(study *model.Study) (string, error) {
+	waves, err := loadStudyWaves(study.ID)
+	if err != nil {
+		return "", err
+	}
+	trend := studyTrend(study, waves)
+
+	trendHeaders := []string{"题目", "选项"}
+	for _, wave := range trend.Waves {
+		trendHeaders = append(trendHeaders, wave.Label)
+	}
+	trendRows := make([][]any, 0)
+	changeRows := make([][]any, 0)
+	for _, item := range trend.Items {
+		row := []any{item.Title, "作答答卷数"}
+		for _, wave := range item.Waves {
+			row = append(row, wave.Respondents)
+		}
+		trendRows = append(trendRows, row)
+		for i, option := range item.Options {
+			row := []any{item.Title, option}
+			for _, wave := range item.Waves {
+				if wave.SerialNum == 0 {
+					row = append(row, "-")
+				} else {
+					row = append(row, fmt.Sprintf("%.2f%%", wave.Percents[i]))
+				}
+			}
+			trendRows = append(trendRows, row)
+		}
+		for _, change := range item.Changes {
+			for _, option := range change.Options {
+				significant := "否"
+				if option.Significant {
+					significant = "是"
+				}
+				changeRows = append(changeRows, []any{item.Title, option.Option, change.From, change.To,
+					fmt.Sprintf("%+.2f", option.Diff), fmt.Sprintf("%.4f", option.PValue), significant})
+			}
+		}
+	}
+
+	answerHeaders := []string{"轮次", "问卷", "提交时间"}
+	for _, item := range study.Items {
+		answerHeaders = append(answerHeaders, item.Title)
+	}
+	answerRows := make([][]any, 0)
+	for _, wave := range waves {
+		questions := make([]*model.Question, 0, len(study.Items))
+		links := make([]model.StudyQuestion, 0, len(study.Items))
+		options := make([]map[string]bool, 0, len(study.Items))
+		for _, item := range study.Items {
+			question, link := studyQuestion(item, wave)
+			questions = append(questions, question)
+			links = append(links, link)
+			options = append(options, studyOptions(item))
+		}
+		for _, sheet := range wave.sheets {
+			row := []any{wave.Label, wave.survey.Title, sheet.Time}
+			for i := range study.Items {
+				if questions[i] == nil {
+					row = append(row, "")
+					continue
+				}
+				values, unmapped := studyAnswer(sheet, questions[i], links[i], options[i])
+				row = append(row, strings.Join(append(values, unmapped...), "┋"))
+			}
+			answerRows = append(answerRows, row)
+		}
+	}
+
+	sheets := []excel.Sheet{
+		{Name: "趋势", Headers: trendHeaders, Rows: trendRows},
+		{Name: "变化", Headers: []string{"题目", "选项", "起始轮次", "结束轮次", "变化(百分点)", "p值", "是否显著"},
+			Rows: changeRows},
+		{Name: "答卷", Headers: answerHeaders, Rows: answerRows},
+	}
+	return createExcelFile(sheets, fmt.Sprintf("%s_纵向研究.xlsx", study.Name))
+}
+
+// loadStudyWaves 按轮次顺序加载研究各轮次的问卷、问题和有效答卷
+func loadStudyWaves(id int) ([]studyWave, error) {
+	list, err := d.GetStudyWaves(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	waves := make([]studyWave, 0, len(list))
+	for _, wave := range list {
+		survey, err := d.GetSurveyByID(ctx, wave.SurveyID)
+		if err != nil {
+			return nil, err
+		}
+		questions, err := d.GetQuestionsBySurveyID(ctx, wave.SurveyID)
+		if err != nil {
+			return nil, err
+		}
+		sheets, err := GetSurveyAnswersBySurveyID(wave.SurveyID, nil)
+		if err != nil {
+			return nil, err
+		}
+		waves = append(waves, studyWave{StudyWave: wave, survey: survey, questions: questions, sheets: sheets})
+	}
+	return waves, nil
+}
+
+func studyTrend(study *model.Study, waves []studyWave) *StudyTrend {
+	trend := &StudyTrend{
+		Waves: make([]StudyWaveStats, 0, len(waves)),
+		Items: make([]StudyItemTrend, 0, len(study.Items)),
+	}
+	for _, wave := range waves {
+		trend.Waves = append(trend.Waves, StudyWaveStats{
+			SurveyID: wave.SurveyID,
+			Label:    wave.Label,
+			Title:    wave.survey.Title,
+			Sheets:   len(wave.sheets),
+		})
+	}
+	for _, item := range study.Items {
+		trend.Items = append(trend.Items, studyItemTrend(item, waves))
+	}
+	return trend
+}
+
+func studyItemTrend(item model.StudyItem, waves []studyWave) StudyItemTrend {
+	index := make(map[string]int)
+	for i, option := range item.Options {
+		index[option] = i
+	}
+	options := studyOptions(item)
+	result := StudyItemTrend{
+		Title:   item.Title,
+		Options: item.Options,
+		Waves:   make([]StudyItemWave, 0, len(waves)),
+		Changes: make([]StudyChange, 0),
+	}
+	prev := -1
+	for i, wave := range waves {
+		stats := StudyItemWave{Counts: make([]int, len(item.Options))}
+		if question, link := studyQuestion(item, wave); question != nil {
+			stats.SerialNum = question.SerialNum
+			stats.Question = question.Subject
+			for _, sheet := range wave.sheets {
+				values, unmapped := studyAnswer(sheet, question, link, options)
+				if len(values) == 0 && len(unmapped) == 0 {
+					continue
+				}
+				stats.Respondents++
+				if len(unmapped) > 0 {
+					stats.Unmapped++
+				}
+				for _, value := range values {
+					stats.Counts[index[value]]++
+				}
+			}
+		}
+		for _, count := range stats.Counts {
+			stats.Percents = append(stats.Percents, percentOf(count, stats.Respondents))
+			stats.CIs = append(stats.CIs, shareInterval(count, stats.Respondents))
+		}
+		result.Waves = append(result.Waves, stats)
+		if stats.Respondents == 0 {
+			continue
+		}
+		if prev >= 0 {
+			result.Changes = append(result.Changes, studyChange(item, waves[prev], result.Waves[prev], wave, stats))
+		}
+		prev = i
+	}
+	return result
+}
+
+func studyChange(item model.StudyItem, fromWave studyWave, from StudyItemWave, toWave studyWave,
+	to StudyItemWave) StudyChange {
+	change := StudyChange{From: fromWave.Label, To: toWave.Label, Options: make([]OptionChange, 0, len(item.Options))}
+	for i, option := range item.Options {
+		diff, p := twoProportionTest(from.Counts[i], from.Respondents, to.Counts[i], to.Respondents)
+		change.Options = append(change.Options, OptionChange{
+			Option:      option,
+			Diff:        math.Round(diff*10000) / 100,
+			PValue:      math.Round(p*10000) / 10000,
+			Significant: p < studySignificance,
+		})
+	}
+	return change
+}
+
+// studyQuestion 获取研究题目在轮次中对应的问题，没有对应或问题已不存在时返回空
+func studyQuestion(item model.StudyItem, wave studyWave) (*model.Question, model.StudyQuestion) {
+	for _, link := range item.Questions {
+		if link.SurveyID == wave.SurveyID {
+			return findQuestionBySerial(wave.questions, link.SerialNum), link
+		}
+	}
+	return nil, model.StudyQuestion{}
+}
+
+// studyOptions 研究题目的统一选项集合
+func studyOptions(item model.StudyItem) map[string]bool {
+	options := make(map[string]bool)
+	for _, option := range item.Options {
+		options[option] = true
+	}
+	return options
+}
+
+// studyAnswer 把答卷对问题的答案对应到统一选项，返回去重后的统一选项和不能对应的原始内容
+func studyAnswer(sheet dao.AnswerSheet, question *model.Question, link model.StudyQuestion,
+	options map[string]bool) ([]string, []string) {
+	values := make([]string, 0)
+	unmapped := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, answer := range sheet.Answers {
+		if answer.QuestionID != question.ID || answer.Content == "" {
+			continue
+		}
+		for _, content := range strings.Split(answer.Content, "┋") {
+			value, ok := link.OptionMap[content]
+			if !ok {
+				value = content
+			}
+			if !options[value] {
+				unmapped = append(unmapped, content)
+				continue
+			}
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	return values, unmapped
+}
+
+// twoProportionTest 比较两组的占比，返回占比之差和双侧 p 值，合并占比为 0 或 1 时 p 值为 1
+func twoProportionTest(c1, n1, c2, n2 int) (float64, float64) {
+	p1, p2 := float64(c1)/float64(n1), float64(c2)/float64(n2)
+	pooled := float64(c1+c2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return p2 - p1, 1
+	}
+	z := (p2 - p1) / se
+	return p2 - p1, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/tally"
+
+	"github.com/zjutjh/WeJH-SDK/excel"
+)
+
+const maxScoreLimit = 100 // 评分题最高分的上限
+
+// QuestionTally 问题的计票结果
+type QuestionTally struct {
+	SerialNum    int            `json:"serial_num"`
+	Subject      string         `json:"subject"`
+	QuestionType int            `json:"question_type"`
+	Options      map[int]string `json:"options"` // 选项序号对应的选项内容
+	Seed         int64          `json:"seed"`    // 随机平票规则使用的种子，公开后可复核
+	Result       tally.Result   `json:"result"`
+}
+
+// CheckBallotSettings 检查排序题和评分题的计票设置
+func CheckBallotSettings(questionList []dao.QuestionList) error {
+	for _, question := range questionList {
+		setting := question.QuestionSetting
+		prefix := "问题" + strconv.Itoa(question.SerialNum)
+		if setting.Numeric && setting.QuestionType != 3 {
+			return errors.New(prefix + "不是填空题，不能设置为数值")
+		}
+		switch setting.TieBreak {
+		case "", tally.TieBreakSerial, tally.TieBreakCountBack, tally.TieBreakRandom:
+		default:
+			return errors.New(prefix + "的平票规则不存在")
+		}
+		switch setting.QuestionType {
+		case 9:
+			if setting.TallyMethod != "" && setting.TallyMethod != tally.MethodInstantRunoff &&
+				setting.TallyMethod != tally.MethodBorda {
+				return errors.New(prefix + "为排序题，计票方法只能为即时决选或波达计数")
+			}
+			if setting.OtherOption {
+				return errors.New(prefix + "为排序题，不能设置其他选项")
+			}
+		case 10:
+			if setting.TallyMethod != "" && setting.TallyMethod != tally.MethodScore {
+				return errors.New(prefix + "为评分题，计票方法只能为平均分")
+			}
+			if setting.MaxScore < 1 || setting.MaxScore > maxScoreLimit {
+				return errors.New(prefix + "的最高分必须在1到" + strconv.Itoa(maxScoreLimit) + "之间")
+			}
+			if setting.OtherOption {
+				return errors.New(prefix + "为评分题，不能设置其他选项")
+			}
+		default:
+			if setting.TallyMethod != "" {
+				return errors.New(prefix + "不是排序题或评分题，不能设置计票方法")
+			}
+		}
+	}
+	return nil
+}
+
+// CheckBallotAnswer 检查排序题和评分题的答案
+//
+// 排序题的答案为按偏好从高到低排列的选项内容，评分题的答案为按选项序号排列的分数，未评分的选项留空。
+func CheckBallotAnswer(question *model.Question, answer string) error {
+	if answer == "" || (question.QuestionType != 9 && question.QuestionType != 10) {
+		return nil
+	}
+	options, err := GetQuestionOptions(*question)
+	if err != nil {
+		return err
+	}
+	prefix := "问题" + strconv.Itoa(question.SerialNum)
+	values := strings.Split(answer, "┋")
+	if question.QuestionType == 10 {
+		if len(values) != len(options) {
+			return errors.New(prefix + "的评分数量与选项数量不一致")
+		}
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			score, err := strconv.Atoi(value)
+			if err != nil || score < 0 || score > question.MaxScore {
+				return errors.New(prefix + "的评分必须为0到" + strconv.Itoa(question.MaxScore) + "之间的整数")
+			}
+		}
+		return nil
+	}
+	contents := make(map[string]bool)
+	for _, option := range options {
+		contents[option.Content] = true
+	}
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if !contents[value] {
+			return errors.New(prefix + "的选项" + value + "不存在")
+		}
+		if seen[value] {
+			return errors.New(prefix + "的选项" + value + "重复排序")
+		}
+		seen[value] = true
+	}
+	length := uint(len(values))
+	if (question.MinimumOption != 0 && length < question.MinimumOption) ||
+		(question.MaximumOption != 0 && length > question.MaximumOption) {
+		return errors.New(prefix + "排序的选项数量不符合要求")
+	}
+	return nil
+}
+
+// GetSurveyTallies 对问卷中的选择题、排序题和评分题计票
+func GetSurveyTallies(survey *model.Survey, answerSheets []dao.AnswerSheet) ([]QuestionTally, error) {
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(questions, func(i, j int) bool {
+		return questions[i].SerialNum < questions[j].SerialNum
+	})
+	tallies := make([]QuestionTally, 0)
+	for _, question := range questions {
+		if question.QuestionType != 1 && question.QuestionType != 2 &&
+			question.QuestionType != 9 && question.QuestionType != 10 {
+			continue
+		}
+		options, err := GetQuestionOptions(question)
+		if err != nil {
+			return nil, err
+		}
+		candidates := make([]int, 0, len(options))
+		contents := make(map[int]string)
+		serials := make(map[string]int)
+		for _, option := range options {
+			candidates = append(candidates, option.SerialNum)
+			contents[option.SerialNum] = option.Content
+			serials[option.Content] = option.SerialNum
+		}
+		// 种子由问卷ID和题目序号决定，修改问卷后保持不变
+		seed := survey.ID<<16 | int64(question.SerialNum)
+		config := tally.Config{TieBreak: question.TieBreak, Seed: seed}
+		item := QuestionTally{
+			SerialNum:    question.SerialNum,
+			Subject:      question.Subject,
+			QuestionType: question.QuestionType,
+			Options:      contents,
+		}
+		if question.TieBreak == tally.TieBreakRandom {
+			item.Seed = seed
+		}
+		if question.QuestionType == 10 {
+			ballots := make([]map[int]float64, 0)
+			for _, content := range getQuestionContents(answerSheets, question.ID) {
+				ballot := make(map[int]float64)
+				for i, value := range strings.Split(content, "┋") {
+					if score, err := strconv.Atoi(value); err == nil && i < len(candidates) {
+						ballot[candidates[i]] = float64(score)
+					}
+				}
+				ballots = append(ballots, ballot)
+			}
+			item.Result = tally.ScoreVote(candidates, ballots, config)
+			tallies = append(tallies, item)
+			continue
+		}
+		ballots := make([][]int, 0)
+		for _, content := range getQuestionContents(answerSheets, question.ID) {
+			ballot := make([]int, 0)
+			for _, value := range strings.Split(content, "┋") {
+				if serialNum, ok := serials[value]; ok {
+					ballot = append(ballot, serialNum)
+				}
+			}
+			ballots = append(ballots, ballot)
+		}
+		switch {
+		case question.QuestionType == 1:
+			item.Result = tally.Plurality(candidates, ballots, config)
+		case question.QuestionType == 2:
+			item.Result = tally.Approval(candidates, ballots, config)
+		case question.TallyMethod == tally.MethodBorda:
+			item.Result = tally.Borda(candidates, ballots, config)
+		default:
+			item.Result = tally.InstantRunoff(candidates, ballots, config)
+		}
+		tallies = append(tallies, item)
+	}
+	return tallies, nil
+}
+
+// getQuestionContents 获取答卷中某一问题的非空答案
+func getQuestionContents(answerSheets []dao.AnswerSheet, questionID int) []string {
+	contents := make([]string, 0, len(answerSheets))
+	for _, sheet := range answerSheets {
+		for _, answer := range sheet.Answers {
+			if answer.QuestionID == questionID && answer.Content != "" {
+				contents = append(contents, answer.Content)
+			}
+		}
+	}
+	return contents
+}
+
+// getTallySheets 生成计票结果和即时决选逐轮报告的工作表
+func getTallySheets(tallies []QuestionTally) []excel.Sheet {
+	sheets := make([]excel.Sheet, 0)
+	for _, item := range tallies {
+		result := item.Result
+		rows := [][]any{
+			{"计票方法", result.Method, ""},
+			{"平票规则", result.TieBreak, ""},
+			{"有效选票", result.Ballots, ""},
+			{"当选", item.Options[result.Winner], ""},
+		}
+		if item.Seed != 0 {
+			rows = append(rows, []any{"随机种子", item.Seed, ""})
+		}
+		for _, score := range result.Scores {
+			rows = append(rows, []any{score.Rank, item.Options[score.Candidate], score.Value})
+		}
+		sheets = append(sheets, excel.Sheet{
+			Name:    fmt.Sprintf("第%d题计票", item.SerialNum),
+			Headers: []string{"排名", "选项内容", "得分"},
+			Rows:    rows,
+		})
+		if len(result.Rounds) == 0 {
+			continue
+		}
+		roundRows := make([][]any, 0)
+		for _, round := range result.Rounds {
+			for _, score := range round.Scores {
+				status := ""
+				switch score.Candidate {
+				case round.Elected:
+					status = "当选"
+				case round.Eliminated:
+					status = "淘汰"
+					if round.TieBroken {
+						status = "淘汰(平票规则)"
+					}
+				}
+				roundRows = append(roundRows, []any{round.Number, item.Options[score.Candidate], score.Value, status})
+			}
+			roundRows = append(roundRows, []any{round.Number, "已耗尽的选票", round.Exhausted, ""})
+		}
+		sheets = append(sheets, excel.Sheet{
+			Name:    fmt.Sprintf("第%d题逐轮", item.SerialNum),
+			Headers: []string{"轮次", "选项内容", "票数", "结果"},
+			Rows:    roundRows,
+		})
+	}
+	return sheets
+}
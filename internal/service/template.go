@@ -0,0 +1,223 @@
+package service
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+
+	"gorm.io/gorm"
+)
+
+//go:embed templates/*.json
+var builtinTemplateFS embed.FS
+
+// TemplateInfo 模板信息
+type TemplateInfo struct {
+	Key         string `json:"key"` // 模板标识 内置模板为英文名称 组织模板为ID
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	SurveyType  uint   `json:"survey_type"`
+	Builtin     bool   `json:"builtin"` // 是否为内置模板
+}
+
+// TemplateDetail 模板详情
+type TemplateDetail struct {
+	TemplateInfo
+	Content dao.TemplateContent `json:"content"`
+}
+
+type builtinTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	SurveyType  uint   `json:"survey_type"`
+	dao.TemplateContent
+}
+
+// GetTemplates 获取内置模板和组织模板列表，category 为空时获取全部
+func GetTemplates(category string) ([]TemplateInfo, error) {
+	templates := make([]TemplateInfo, 0)
+	entries, err := builtinTemplateFS.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		key := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		detail, err := getBuiltinTemplate(key)
+		if err != nil {
+			return nil, err
+		}
+		if category == "" || detail.Category == category {
+			templates = append(templates, detail.TemplateInfo)
+		}
+	}
+	orgTemplates, err := d.GetTemplates(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+	for _, template := range orgTemplates {
+		templates = append(templates, templateInfo(template))
+	}
+	return templates, nil
+}
+
+// GetTemplateDetail 根据模板标识获取模板详情，模板不存在时返回 gorm.ErrRecordNotFound
+func GetTemplateDetail(key string) (*TemplateDetail, error) {
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return getBuiltinTemplate(key)
+	}
+	template, err := d.GetTemplateByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	detail := &TemplateDetail{TemplateInfo: templateInfo(*template)}
+	err = json.Unmarshal([]byte(template.Content), &detail.Content)
+	return detail, err
+}
+
+// PromoteTemplate 将已有问卷提升为组织模板
+func PromoteTemplate(uid int, survey *model.Survey, name, desc, category string) (int, error) {
+	content := dao.TemplateContent{
+		BaseConfig: dao.BaseConfig{
+			DailyLimit:       survey.DailyLimit,
+			SumLimit:         survey.SumLimit,
+			Verify:           survey.Verify,
+			UndergradOnly:    survey.UndergradOnly,
+			NeedNotify:       survey.NeedNotify,
+			ShuffleQuestions: survey.ShuffleQuestions,
+			HiddenFields:     survey.HiddenFields,
+			DefaultLang:      survey.DefaultLang,
+			Completion:       survey.Completion,
+			Theme:            survey.Theme,
+			ResultPolicy:     survey.ResultPolicy,
+			Anonymous:        survey.Anonymous,
+		},
+		QuestionConfig: dao.QuestionConfig{
+			Title:        survey.Title,
+			Desc:         survey.Desc,
+			QuestionList: make([]dao.QuestionList, 0),
+		},
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return 0, err
+	}
+	sections, err := d.GetSectionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return 0, err
+	}
+	content.QuestionConfig.Sections = make([]dao.Section, 0, len(sections))
+	for _, section := range sections {
+		content.QuestionConfig.Sections = append(content.QuestionConfig.Sections, dao.Section{
+			SerialNum:   section.SerialNum,
+			Title:       section.Title,
+			Description: section.Description,
+			Shuffle:     section.Shuffle,
+		})
+	}
+	sectionSerialMap := GetSectionSerialMap(sections)
+	for _, question := range questions {
+		// 模板中的题目均为副本，不保留题库引用
+		item := dao.QuestionList{
+			SerialNum:   question.SerialNum,
+			Subject:     question.Subject,
+			Description: question.Description,
+			Img:         question.Img,
+			QuestionSetting: dao.QuestionSetting{
+				Required:         question.Required,
+				Unique:           question.Unique,
+				OtherOption:      question.OtherOption,
+				QuestionType:     question.QuestionType,
+				Reg:              question.Reg,
+				MaximumOption:    question.MaximumOption,
+				MinimumOption:    question.MinimumOption,
+				OptionSetID:      question.OptionSetID,
+				OptionSetVersion: question.OptionSetVersion,
+				ShuffleOptions:   question.ShuffleOptions,
+				Pinned:           question.Pinned,
+				Expression:       question.Expression,
+				TallyMethod:      question.TallyMethod,
+				TieBreak:         question.TieBreak,
+				MaxScore:         question.MaxScore,
+				Numeric:          question.Numeric,
+			},
+			Options: make([]dao.Option, 0),
+			Section: sectionSerialMap[question.SectionID],
+		}
+		// 绑定选项集的问题在使用模板时重新填充选项
+		if question.OptionSetID == 0 {
+			options, err := d.GetOptionsByQuestionID(ctx, question.ID)
+			if err != nil {
+				return 0, err
+			}
+			for _, option := range options {
+				item.Options = append(item.Options, dao.Option{
+					SerialNum:   option.SerialNum,
+					Content:     option.Content,
+					Description: option.Description,
+					Img:         option.Img,
+					Pinned:      option.Pinned,
+				})
+			}
+		}
+		content.QuestionConfig.QuestionList = append(content.QuestionConfig.QuestionList, item)
+	}
+	jsonData, err := json.Marshal(content)
+	if err != nil {
+		return 0, err
+	}
+	template, err := d.CreateTemplate(ctx, model.Template{
+		UserID:      uid,
+		SurveyID:    survey.ID,
+		Name:        name,
+		Description: desc,
+		Category:    category,
+		SurveyType:  survey.Type,
+		Content:     string(jsonData),
+	})
+	return template.ID, err
+}
+
+// DeleteTemplate 删除组织模板
+func DeleteTemplate(id int) error {
+	return d.DeleteTemplate(ctx, id)
+}
+
+func getBuiltinTemplate(key string) (*TemplateDetail, error) {
+	data, err := builtinTemplateFS.ReadFile("templates/" + path.Base(key) + ".json")
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var template builtinTemplate
+	if err := json.Unmarshal(data, &template); err != nil {
+		return nil, err
+	}
+	return &TemplateDetail{
+		TemplateInfo: TemplateInfo{
+			Key:         key,
+			Name:        template.Name,
+			Description: template.Description,
+			Category:    template.Category,
+			SurveyType:  template.SurveyType,
+			Builtin:     true,
+		},
+		Content: template.TemplateContent,
+	}, nil
+}
+
+func templateInfo(template model.Template) TemplateInfo {
+	return TemplateInfo{
+		Key:         strconv.Itoa(template.ID),
+		Name:        template.Name,
+		Description: template.Description,
+		Category:    template.Category,
+		SurveyType:  template.SurveyType,
+	}
+}
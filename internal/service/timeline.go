@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"time"
+)
+
+// TimeRange 按提交时间筛选答卷，为空时不限制
+type TimeRange struct {
+	Start string `form:"start" json:"start"` // 包含
+	End   string `form:"end" json:"end"`     // 不包含
+}
+
+// TimelineBucket 时间段内提交的有效答卷数
+type TimelineBucket struct {
+	Start string `json:"start"`
+	Count int    `json:"count"`
+}
+
+// SubmissionTimeline 问卷的答卷时间分布
+type SubmissionTimeline struct {
+	Unit    string           `json:"unit"`
+	Buckets []TimelineBucket `json:"buckets"` // 从第一个到最后一个时间段，没有答卷的时间段记为 0
+	Total   int              `json:"total"`
+	Untimed int64            `json:"untimed"` // 没有可查询答卷时间的旧答卷数，迁移后为 0
+}
+
+// SubmissionHeatmap 问卷按星期几和小时的答卷分布
+type SubmissionHeatmap struct {
+	Counts  [7][24]int `json:"counts"` // 第一维从周一到周日，第二维为小时
+	Total   int        `json:"total"`
+	Untimed int64      `json:"untimed"`
+}
+
+// CheckTimeRange 检查时间范围的格式
+func CheckTimeRange(r TimeRange) error {
+	start, end, err := r.parse()
+	if err != nil {
+		return err
+	}
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return errors.New("开始时间必须早于结束时间")
+	}
+	return nil
+}
+
+// GetSubmissionTimeline 按小时(hour)、天(day)或周(week)统计问卷的有效答卷数，周从周一开始
+func GetSubmissionTimeline(sid int64, unit string, r TimeRange) (*SubmissionTimeline, error) {
+	start, end, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := d.AggregateSubmissionTimeline(ctx, sid, unit, localOffset(), start, end)
+	if err != nil {
+		return nil, err
+	}
+	untimed, err := d.CountUntimedAnswerSheets(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	timeline := &SubmissionTimeline{Unit: unit, Buckets: make([]TimelineBucket, 0), Untimed: untimed}
+	if len(buckets) == 0 {
+		return timeline, nil
+	}
+	counts := make(map[string]int, len(buckets))
+	for _, bucket := range buckets {
+		counts[bucket.Start] = bucket.Count
+		timeline.Total += bucket.Count
+	}
+	first, err := time.ParseInLocation(time.DateTime, buckets[0].Start, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	last, err := time.ParseInLocation(time.DateTime, buckets[len(buckets)-1].Start, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	for t := first; !t.After(last); t = nextBucket(t, unit) {
+		label := t.Format(time.DateTime)
+		timeline.Buckets = append(timeline.Buckets, TimelineBucket{Start: label, Count: counts[label]})
+	}
+	return timeline, nil
+}
+
+// GetSubmissionHeatmap 按星期几和小时统计问卷的有效答卷数
+func GetSubmissionHeatmap(sid int64, r TimeRange) (*SubmissionHeatmap, error) {
+	start, end, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	cells, err := d.AggregateSubmissionHeatmap(ctx, sid, localOffset(), start, end)
+	if err != nil {
+		return nil, err
+	}
+	untimed, err := d.CountUntimedAnswerSheets(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	heatmap := &SubmissionHeatmap{Untimed: untimed}
+	for _, cell := range cells {
+		heatmap.Counts[cell.Weekday-1][cell.Hour] = cell.Count
+		heatmap.Total += cell.Count
+	}
+	return heatmap, nil
+}
+
+// MigrateAnswerTimes 为旧答卷补充可按范围查询的答卷时间，返回更新的答卷数
+func MigrateAnswerTimes() (int64, error) {
+	return d.MigrateSubmittedAt(ctx, localOffset())
+}
+
+func (r TimeRange) parse() (start, end time.Time, err error) {
+	if r.Start != "" {
+		if start, err = time.ParseInLocation(time.DateTime, r.Start, time.Local); err != nil {
+			return start, end, errors.New("开始时间的格式必须为 2006-01-02 15:04:05")
+		}
+	}
+	if r.End != "" {
+		if end, err = time.ParseInLocation(time.DateTime, r.End, time.Local); err != nil {
+			return start, end, errors.New("结束时间的格式必须为 2006-01-02 15:04:05")
+		}
+	}
+	return start, end, nil
+}
+
+// localOffset 本地时区的 UTC 偏移，如 +08:00，本地时区没有夏令时
+func localOffset() string {
+	return time.Now().Format("-07:00")
+}
+
+func nextBucket(t time.Time, unit string) time.Time {
+	switch unit {
+	case "hour":
+		return t.Add(time.Hour)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/i18n"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Translation 问卷某一语言的译文，键为翻译条目
+//
+// 翻译条目按序号而不是ID编排，修改问卷后译文仍然有效：
+// title、desc、completion 为问卷标题、描述和感谢语，s1.title、s1.description 为分页，
+// q3.subject、q3.description 为问题，q3.o2 为问题3的第2个选项。
+type Translation map[string]string
+
+// Text 获取条目的译文，没有译文时返回原文
+func (t Translation) Text(key, source string) string {
+	if text, ok := t[key]; ok && text != "" {
+		return text
+	}
+	return source
+}
+
+// Question 获取翻译后的问题
+func (t Translation) Question(question model.Question) model.Question {
+	prefix := "q" + strconv.Itoa(question.SerialNum) + "."
+	question.Subject = t.Text(prefix+"subject", question.Subject)
+	question.Description = t.Text(prefix+"description", question.Description)
+	return question
+}
+
+// Option 获取翻译后的选项
+func (t Translation) Option(question model.Question, option model.Option) model.Option {
+	option.Content = t.Text(optionTranslationKey(question.SerialNum, option.SerialNum), option.Content)
+	return option
+}
+
+// Section 获取翻译后的分页
+func (t Translation) Section(section model.Section) model.Section {
+	prefix := "s" + strconv.Itoa(section.SerialNum) + "."
+	section.Title = t.Text(prefix+"title", section.Title)
+	section.Description = t.Text(prefix+"description", section.Description)
+	return section
+}
+
+// GetSurveyDefaultLang 获取问卷原文的语言
+func GetSurveyDefaultLang(survey *model.Survey) string {
+	if survey.DefaultLang == "" {
+		return i18n.DefaultLang
+	}
+	return survey.DefaultLang
+}
+
+// GetSurveyLanguages 获取问卷支持的语言，第一个为原文语言
+func GetSurveyLanguages(survey *model.Survey) ([]string, error) {
+	defaultLang := GetSurveyDefaultLang(survey)
+	langs, err := d.GetTranslationLangs(ctx, survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	languages := []string{defaultLang}
+	for _, lang := range langs {
+		if !strings.EqualFold(lang, defaultLang) {
+			languages = append(languages, lang)
+		}
+	}
+	return languages, nil
+}
+
+// MatchSurveyLanguage 根据指定的语言和 Accept-Language 请求头选择问卷语言，均不支持时使用原文语言
+func MatchSurveyLanguage(survey *model.Survey, lang, acceptLanguage string) (string, error) {
+	languages, err := GetSurveyLanguages(survey)
+	if err != nil {
+		return "", err
+	}
+	requested := make([]string, 0)
+	if lang != "" {
+		requested = append(requested, lang)
+	}
+	requested = append(requested, i18n.ParseAcceptLanguage(acceptLanguage)...)
+	return i18n.Match(languages, requested, languages[0]), nil
+}
+
+// GetTranslation 获取问卷某一语言的译文，原文语言返回空译文
+func GetTranslation(survey *model.Survey, lang string) (Translation, error) {
+	translation := make(Translation)
+	if strings.EqualFold(lang, GetSurveyDefaultLang(survey)) {
+		return translation, nil
+	}
+	translations, err := d.GetTranslations(ctx, survey.ID, lang)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range translations {
+		translation[item.Key] = item.Content
+	}
+	return translation, nil
+}
+
+// NormalizeAnswer 将选择题和排序题中译文的选项还原为原文，使不同语言的答卷可以合并统计
+func NormalizeAnswer(question *model.Question, answer string, translation Translation) (string, error) {
+	if len(translation) == 0 || answer == "" || !isOptionAnswer(question.QuestionType) {
+		return answer, nil
+	}
+	options, err := GetQuestionOptions(*question)
+	if err != nil {
+		return "", err
+	}
+	sources := make(map[string]string)
+	for _, option := range options {
+		sources[translation.Option(*question, option).Content] = option.Content
+	}
+	values := strings.Split(answer, "┋")
+	for i, value := range values {
+		if source, ok := sources[value]; ok {
+			values[i] = source
+		}
+	}
+	return strings.Join(values, "┋"), nil
+}
+
+// getAnswerOptionSerials 获取选择题和排序题答案对应的选项序号，其他选项不计入
+func getAnswerOptionSerials(question model.Question, answer string) ([]int, error) {
+	if answer == "" || !isOptionAnswer(question.QuestionType) {
+		return nil, nil
+	}
+	options, err := GetQuestionOptions(question)
+	if err != nil {
+		return nil, err
+	}
+	serials := make(map[string]int)
+	for _, option := range options {
+		serials[option.Content] = option.SerialNum
+	}
+	result := make([]int, 0)
+	for _, value := range strings.Split(answer, "┋") {
+		if serialNum, ok := serials[value]; ok {
+			result = append(result, serialNum)
+		}
+	}
+	return result, nil
+}
+
+// isOptionAnswer 判断答案是否由选项内容组成，包括单选、多选和排序题
+func isOptionAnswer(questionType int) bool {
+	return questionType == 1 || questionType == 2 || questionType == 9
+}
+
+type translationEntry struct {
+	Key    string
+	Source string
+}
+
+// getTranslationEntries 获取问卷全部可翻译的条目及原文
+func getTranslationEntries(survey *model.Survey) ([]translationEntry, error) {
+	entries := []translationEntry{
+		{Key: "title", Source: survey.Title},
+		{Key: "desc", Source: survey.Desc},
+		{Key: "completion", Source: survey.Completion.Message},
+	}
+	sections, err := d.GetSectionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, section := range sections {
+		prefix := "s" + strconv.Itoa(section.SerialNum) + "."
+		entries = append(entries,
+			translationEntry{Key: prefix + "title", Source: section.Title},
+			translationEntry{Key: prefix + "description", Source: section.Description})
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(questions, func(i, j int) bool {
+		return questions[i].SerialNum < questions[j].SerialNum
+	})
+	for _, question := range questions {
+		prefix := "q" + strconv.Itoa(question.SerialNum) + "."
+		entries = append(entries,
+			translationEntry{Key: prefix + "subject", Source: question.Subject},
+			translationEntry{Key: prefix + "description", Source: question.Description})
+		options, err := GetQuestionOptions(question)
+		if err != nil {
+			return nil, err
+		}
+		for _, option := range options {
+			entries = append(entries, translationEntry{
+				Key:    optionTranslationKey(question.SerialNum, option.SerialNum),
+				Source: option.Content,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// ExportTranslation 导出问卷某一语言的翻译文件，返回文件地址
+func ExportTranslation(survey *model.Survey, lang string) (string, error) {
+	entries, err := getTranslationEntries(survey)
+	if err != nil {
+		return "", err
+	}
+	translation, err := GetTranslation(survey, lang)
+	if err != nil {
+		return "", err
+	}
+	f := excelize.NewFile()
+	rows := [][]any{{"条目", "原文(" + GetSurveyDefaultLang(survey) + ")", "译文(" + lang + ")"}}
+	for _, entry := range entries {
+		rows = append(rows, []any{entry.Key, entry.Source, translation[entry.Key]})
+	}
+	for i, row := range rows {
+		if err := f.SetSheetRow("Sheet1", "A"+strconv.Itoa(i+1), &row); err != nil {
+			return "", errors.New("写入数据失败原因: " + err.Error())
+		}
+	}
+	if err := f.SetColWidth("Sheet1", "B", "C", 60); err != nil {
+		return "", errors.New("设置列宽失败原因: " + err.Error())
+	}
+	fileName := survey.Title + "_" + lang + ".xlsx"
+	if _, err := os.Stat("./public/xlsx/"); os.IsNotExist(err) {
+		if err := os.Mkdir("./public/xlsx/", 0750); err != nil {
+			return "", errors.New("创建文件夹失败原因: " + err.Error())
+		}
+	}
+	if err := f.SaveAs("./public/xlsx/" + fileName); err != nil {
+		return "", errors.New("保存文件失败原因: " + err.Error())
+	}
+	return GetConfigUrl() + "/public/xlsx/" + fileName, nil
+}
+
+// ImportTranslation 导入问卷某一语言的翻译文件，替换该语言原有的译文，返回导入的条目数
+func ImportTranslation(survey *model.Survey, lang string, reader io.Reader) (int, error) {
+	if strings.EqualFold(lang, GetSurveyDefaultLang(survey)) {
+		return 0, errors.New("不能导入问卷原文语言的译文")
+	}
+	entries, err := getTranslationEntries(survey)
+	if err != nil {
+		return 0, err
+	}
+	keys := make(map[string]bool)
+	for _, entry := range entries {
+		keys[entry.Key] = true
+	}
+	f, err := excelize.OpenReader(reader)
+	if err != nil {
+		return 0, errors.New("读取翻译文件失败原因: " + err.Error())
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		return 0, errors.New("读取翻译文件失败原因: " + err.Error())
+	}
+	translations := make([]model.Translation, 0)
+	for i, row := range rows {
+		// 跳过标题行和未翻译的条目
+		if i == 0 || len(row) < 3 || strings.TrimSpace(row[2]) == "" {
+			continue
+		}
+		key := strings.TrimSpace(row[0])
+		if !keys[key] {
+			return 0, fmt.Errorf("第%d行的条目%s不存在", i+1, key)
+		}
+		translations = append(translations, model.Translation{
+			SurveyID: survey.ID,
+			Lang:     lang,
+			Key:      key,
+			Content:  row[2],
+		})
+	}
+	return len(translations), d.SetTranslations(ctx, survey.ID, lang, translations)
+}
+
+// DeleteTranslation 删除问卷某一语言的译文
+func DeleteTranslation(sid int64, lang string) error {
+	return d.SetTranslations(ctx, sid, lang, nil)
+}
+
+func optionTranslationKey(questionSerial, optionSerial int) string {
+	return "q" + strconv.Itoa(questionSerial) + ".o" + strconv.Itoa(optionSerial)
+}
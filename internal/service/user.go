@@ -50,37 +50,76 @@ func GetQuestionByID(id int) (*model.Question, error) {
 	return question, err
 }
 
-// SubmitSurvey 提交问卷
-func SubmitSurvey(sid int64, data []dao.QuestionsList, t string) error {
+// SubmitSurvey 提交问卷，返回保存的答卷，studentID 为统一验证的学号，答卷只保存学号的 HMAC
+func SubmitSurvey(sid int64, data []dao.QuestionsList, t string, order *RespondentOrder,
+	hidden map[string]string, lang string, studentID string) (dao.AnswerSheet, error) {
+	answerSheet, qids, err := newAnswerSheet(sid, data, order, lang)
+	if err != nil {
+		return answerSheet, err
+	}
+	answerSheet.Time = t
+	answerSheet.SubmittedAt, err = time.ParseInLocation(time.DateTime, t, time.Local)
+	if err != nil {
+		return answerSheet, err
+	}
+	answerSheet.StudentKey = studentKey(studentID)
+	answerSheet.AnswerID = primitive.NewObjectID()
+	answerSheet.HiddenFields = hidden
+	replaced, err := d.SaveAnswerSheet(ctx, answerSheet, qids)
+	if err != nil {
+		return answerSheet, err
+	}
+	// 答卷已保存，返回错误会导致重复提交，校验哈希链时会报告未记录的答卷
+	if err := recordSubmission(answerSheet); err != nil {
+		zap.L().Error("答卷记入哈希链失败", zap.Int64("survey_id", sid), zap.Error(err))
+	}
+	// 更新计数器时通知新答卷推送，需在记入哈希链之后
+	updateStatsCounters(answerSheet, 1)
+	if replaced != nil {
+		updateStatsCounters(*replaced, -1)
+	}
+	err = d.IncreaseSurveyNum(ctx, sid)
+	if err != nil {
+		return answerSheet, err
+	}
+	err = FromSurveyIDToMsg(sid)
+	return answerSheet, err
+}
+
+// newAnswerSheet 根据答案生成答卷，并返回需要判断唯一性的问题
+func newAnswerSheet(sid int64, data []dao.QuestionsList, order *RespondentOrder,
+	lang string) (dao.AnswerSheet, []int, error) {
 	var answerSheet dao.AnswerSheet
 	answerSheet.SurveyID = sid
-	answerSheet.Time = t
 	answerSheet.Unique = true
-	answerSheet.AnswerID = primitive.NewObjectID()
+	answerSheet.Lang = lang
 	qids := make([]int, 0)
 	for _, q := range data {
 		var answer dao.Answer
 		question, err := d.GetQuestionByID(ctx, q.QuestionID)
 		if err != nil {
-			return err
+			return answerSheet, nil, err
 		}
 		if question.QuestionType == 3 && question.Unique {
 			qids = append(qids, q.QuestionID)
 		}
 		answer.QuestionID = q.QuestionID
 		answer.Content = q.Answer
+		answer.Position = order.Positions[q.QuestionID]
+		answer.OptionOrder = order.OptionOrders[q.QuestionID]
+		answer.OptionSerials, err = getAnswerOptionSerials(*question, q.Answer)
+		if err != nil {
+			return answerSheet, nil, err
+		}
 		answerSheet.Answers = append(answerSheet.Answers, answer)
 	}
-	err := d.SaveAnswerSheet(ctx, answerSheet, qids)
-	if err != nil {
-		return err
-	}
-	err = d.IncreaseSurveyNum(ctx, sid)
+	// 填充引用答案后的题面并计算计算题
+	questions, err := d.GetQuestionsBySurveyID(ctx, sid)
 	if err != nil {
-		return err
+		return answerSheet, nil, err
 	}
-	err = FromSurveyIDToMsg(sid)
-	return err
+	err = FillDerivedAnswers(questions, &answerSheet)
+	return answerSheet, qids, err
 }
 
 // CreateOauthRecord 创建一条统一验证记录
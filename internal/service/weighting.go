@@ -0,0 +1,401 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+
+	"github.com/zjutjh/WeJH-SDK/excel"
+	"gorm.io/gorm"
+)
+
+// 加权方法
+const (
+	WeightPoststratify = "poststratify" // 事后分层 只能使用一个加权变量
+	WeightRaking       = "raking"       // 迭代比例拟合 使各加权变量的边际分布分别符合总体
+)
+
+const (
+	rakingMaxIterations = 100  // 迭代比例拟合的最大轮数
+	rakingTolerance     = 1e-6 // 各取值的加权占比与目标占比的最大允许误差
+	confidenceZ         = 1.96 // 95% 置信水平的正态分位数
+)
+
+// Interval 百分比的置信区间
+type Interval struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// WeightingSummary 加权结果概况
+type WeightingSummary struct {
+	Method        string   `json:"method"`
+	Eligible      int      `json:"eligible"`   // 参与加权的答卷数
+	Excluded      int      `json:"excluded"`   // 加权变量没有取值或取值不在总体分布中的答卷数，不计入加权结果
+	MinWeight     float64  `json:"min_weight"` // 权重已归一化为平均 1
+	MaxWeight     float64  `json:"max_weight"`
+	DesignEffect  float64  `json:"design_effect"`  // Kish 设计效应
+	EffectiveSize float64  `json:"effective_size"` // 有效样本量
+	Iterations    int      `json:"iterations"`     // 迭代比例拟合的轮数
+	Converged     bool     `json:"converged"`      // 迭代比例拟合是否收敛，事后分层总为 true
+	Warnings      []string `json:"warnings"`
+}
+
+// weightingVariable 解析后的加权变量
+type weightingVariable struct {
+	dim    *crossTabDimension
+	shares map[string]float64 // 按有答卷的取值归一化后的目标占比
+}
+
+// CheckWeighting 检查加权设置，题目只能为单选题
+func CheckWeighting(survey *model.Survey, weighting model.Weighting) error {
+	if weighting.Method != WeightPoststratify && weighting.Method != WeightRaking {
+		return errors.New("加权方法只能为事后分层或迭代比例拟合")
+	}
+	if len(weighting.Targets) == 0 {
+		return errors.New("至少需要一个加权变量")
+	}
+	if weighting.Method == WeightPoststratify && len(weighting.Targets) > 1 {
+		return errors.New("事后分层只能使用一个加权变量，多个变量请使用迭代比例拟合")
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return err
+	}
+	seen := make(map[model.AnswerDimension]bool)
+	for _, target := range weighting.Targets {
+		if err := checkDimension(survey, questions, target.Dimension); err != nil {
+			return err
+		}
+		if target.Dimension.Type == DimensionQuestion &&
+			findQuestionBySerial(questions, target.Dimension.SerialNum).QuestionType != 1 {
+			return errors.New("只能按单选题加权")
+		}
+		if seen[target.Dimension] {
+			return errors.New("加权变量重复")
+		}
+		seen[target.Dimension] = true
+		sum := 0.0
+		for value, share := range target.Shares {
+			if share < 0 || math.IsNaN(share) || math.IsInf(share, 0) {
+				return errors.New("取值" + value + "的占比不合法")
+			}
+			sum += share
+		}
+		if sum <= 0 {
+			return errors.New("加权变量的总体占比之和必须大于0")
+		}
+	}
+	return nil
+}
+
+// SetWeighting 保存问卷的加权设置，设置需先由 CheckWeighting 检查
+func SetWeighting(sid int64, weighting model.Weighting) error {
+	weighting.SurveyID = sid
+	return d.SaveWeighting(ctx, weighting)
+}
+
+// GetWeighting 获取问卷的加权设置，未设置时返回 nil
+func GetWeighting(sid int64) (*model.Weighting, error) {
+	weighting, err := d.GetWeighting(ctx, sid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return weighting, err
+}
+
+// DeleteWeighting 删除问卷的加权设置
+func DeleteWeighting(sid int64) error {
+	return d.DeleteWeighting(ctx, sid)
+}
+
+// GetWeightingSummary 按加权设置计算问卷全部有效答卷的权重，返回加权结果概况
+func GetWeightingSummary(survey *model.Survey, weighting *model.Weighting) (*WeightingSummary, error) {
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	sheets, err := GetSurveyAnswersBySurveyID(survey.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	_, summary, err := computeWeights(survey, questions, weighting, sheets)
+	return summary, err
+}
+
+// ApplyWeighting 为选择题统计结果加上加权占比和置信区间，问卷未设置加权时不做修改并返回 nil
+//
+// 权重按筛选后的答卷计算，加权变量没有取值的答卷不计入加权结果。
+func ApplyWeighting(survey *model.Survey, questions []model.Question, hidden map[string]string,
+	stats []GetChooseStatisticsResponse) (*WeightingSummary, error) {
+	weighting, err := GetWeighting(survey.ID)
+	if err != nil || weighting == nil {
+		return nil, err
+	}
+	sheets, err := GetSurveyAnswersBySurveyID(survey.ID, hidden)
+	if err != nil {
+		return nil, err
+	}
+	weights, summary, err := computeWeights(survey, questions, weighting, sheets)
+	if err != nil {
+		return nil, err
+	}
+	// 获取失败的问题只统计为其他选项，与未加权的统计一致
+	loaded, _ := loadQuestionOptions(questions) //nolint:errcheck
+	weighted := make(map[int]map[int]float64)
+	sumW := make(map[int]float64)  // 作答该题的答卷权重之和
+	sumW2 := make(map[int]float64) // 作答该题的答卷权重平方和
+	answered := make(map[int]int)  // 作答该题的答卷数
+	for i, sheet := range sheets {
+		w := weights[i]
+		if w == 0 {
+			continue
+		}
+		for _, answer := range sheet.Answers {
+			question := loaded.questionMap[answer.QuestionID]
+			if question.QuestionType != 1 && question.QuestionType != 2 {
+				continue
+			}
+			counts := weighted[question.ID]
+			if counts == nil {
+				counts = make(map[int]float64)
+				weighted[question.ID] = counts
+			}
+			for _, content := range strings.Split(answer.Content, "┋") {
+				serial := 0
+				if option, ok := loaded.optionAnswerMap[question.ID][content]; ok {
+					serial = option.SerialNum
+				}
+				counts[serial] += w
+			}
+			sumW[question.ID] += w
+			sumW2[question.ID] += w * w
+			answered[question.ID]++
+		}
+	}
+	serialToID := make(map[int]int)
+	for _, question := range questions {
+		serialToID[question.SerialNum] = question.ID
+	}
+	for i := range stats {
+		qid := serialToID[stats[i].SerialNum]
+		total := 0.0
+		for _, w := range weighted[qid] {
+			total += w
+		}
+		// 按作答该题的答卷计算设计效应，多选题的每个选项按一次计入样本量
+		n := total
+		if sumW[qid] > 0 {
+			n = total * sumW[qid] * sumW[qid] / (float64(answered[qid]) * sumW2[qid])
+		}
+		for j := range stats[i].Options {
+			option := &stats[i].Options[j]
+			p := 0.0
+			if total > 0 {
+				p = weighted[qid][option.SerialNum] / total
+			}
+			option.WeightedPercent = fmt.Sprintf("%.2f%%", p*100)
+			ci := wilsonInterval(p, n)
+			option.WeightedCI = &ci
+		}
+	}
+	return summary, nil
+}
+
+// computeWeights 计算每份答卷的权重，不参与加权的答卷权重为 0，其余答卷的权重平均为 1
+func computeWeights(survey *model.Survey, questions []model.Question, weighting *model.Weighting,
+	sheets []dao.AnswerSheet) ([]float64, *WeightingSummary, error) {
+	summary := &WeightingSummary{Method: weighting.Method, Converged: true, Warnings: make([]string, 0)}
+	variables := make([]weightingVariable, 0, len(weighting.Targets))
+	for _, target := range weighting.Targets {
+		dim, err := resolveDimension(survey, questions, target.Dimension)
+		if err != nil {
+			return nil, nil, err
+		}
+		variables = append(variables, weightingVariable{dim: dim, shares: target.Shares})
+	}
+
+	// 每份答卷在各加权变量上的取值，取值不唯一或没有总体占比的答卷不参与加权
+	values := make([][]string, len(sheets))
+	weights := make([]float64, len(sheets))
+	for i, sheet := range sheets {
+		row := make([]string, 0, len(variables))
+		for _, variable := range variables {
+			vs := variable.dim.values(sheet)
+			if len(vs) != 1 || variable.shares[vs[0]] <= 0 {
+				row = nil
+				break
+			}
+			row = append(row, vs[0])
+		}
+		if row == nil {
+			summary.Excluded++
+			continue
+		}
+		values[i] = row
+		weights[i] = 1
+		summary.Eligible++
+	}
+	if summary.Eligible == 0 {
+		summary.Warnings = append(summary.Warnings, "没有可以加权的答卷")
+		return weights, summary, nil
+	}
+
+	// 总体中有但没有答卷的取值无法加权，其余取值的目标占比重新归一化
+	for k := range variables {
+		present := make(map[string]bool)
+		for i := range sheets {
+			if values[i] != nil {
+				present[values[i][k]] = true
+			}
+		}
+		shares := make(map[string]float64)
+		sum := 0.0
+		for value, share := range variables[k].shares {
+			if share <= 0 {
+				continue
+			}
+			if !present[value] {
+				summary.Warnings = append(summary.Warnings,
+					fmt.Sprintf("%s为%s的答卷数为0，加权结果中该群体缺失", variables[k].dim.title, value))
+				continue
+			}
+			shares[value] = share
+			sum += share
+		}
+		for value := range shares {
+			shares[value] /= sum
+		}
+		variables[k].shares = shares
+	}
+	sort.Strings(summary.Warnings)
+
+	if weighting.Method == WeightPoststratify {
+		adjustWeights(weights, values, 0, variables[0].shares)
+	} else {
+		summary.Converged = false
+		for summary.Iterations < rakingMaxIterations {
+			summary.Iterations++
+			for k := range variables {
+				adjustWeights(weights, values, k, variables[k].shares)
+			}
+			if maxShareError(weights, values, variables) < rakingTolerance {
+				summary.Converged = true
+				break
+			}
+		}
+		if !summary.Converged {
+			summary.Warnings = append(summary.Warnings, "迭代比例拟合未收敛，各加权变量的总体分布可能相互矛盾")
+		}
+	}
+
+	sum, sum2 := 0.0, 0.0
+	for _, w := range weights {
+		sum += w
+	}
+	scale := float64(summary.Eligible) / sum
+	summary.MinWeight = math.Inf(1)
+	for i := range weights {
+		if values[i] == nil {
+			continue
+		}
+		weights[i] *= scale
+		sum2 += weights[i] * weights[i]
+		summary.MinWeight = math.Min(summary.MinWeight, weights[i])
+		summary.MaxWeight = math.Max(summary.MaxWeight, weights[i])
+	}
+	n := float64(summary.Eligible)
+	summary.DesignEffect = n * sum2 / (n * n)
+	summary.EffectiveSize = n / summary.DesignEffect
+	return weights, summary, nil
+}
+
+// adjustWeights 调整权重使第 k 个加权变量的加权占比等于目标占比
+func adjustWeights(weights []float64, values [][]string, k int, shares map[string]float64) {
+	totals := make(map[string]float64)
+	sum := 0.0
+	for i, w := range weights {
+		if values[i] != nil {
+			totals[values[i][k]] += w
+			sum += w
+		}
+	}
+	for i := range weights {
+		if values[i] != nil {
+			weights[i] *= shares[values[i][k]] * sum / totals[values[i][k]]
+		}
+	}
+}
+
+// maxShareError 各加权变量的加权占比与目标占比的最大误差
+func maxShareError(weights []float64, values [][]string, variables []weightingVariable) float64 {
+	maxError := 0.0
+	for k, variable := range variables {
+		totals := make(map[string]float64)
+		sum := 0.0
+		for i, w := range weights {
+			if values[i] != nil {
+				totals[values[i][k]] += w
+				sum += w
+			}
+		}
+		for value, share := range variable.shares {
+			maxError = math.Max(maxError, math.Abs(totals[value]/sum-share))
+		}
+	}
+	return maxError
+}
+
+// String 以 12.34%~56.78% 的形式表示置信区间
+func (i Interval) String() string {
+	return fmt.Sprintf("%.2f%%~%.2f%%", i.Low, i.High)
+}
+
+// getWeightingSheet 生成导出文件中的加权说明
+func getWeightingSheet(summary *WeightingSummary) excel.Sheet {
+	method := "事后分层"
+	if summary.Method == WeightRaking {
+		method = "迭代比例拟合"
+	}
+	rows := [][]any{
+		{"加权方法", method},
+		{"参与加权的答卷数", summary.Eligible},
+		{"未参与加权的答卷数", summary.Excluded},
+		{"最小权重", fmt.Sprintf("%.4f", summary.MinWeight)},
+		{"最大权重", fmt.Sprintf("%.4f", summary.MaxWeight)},
+		{"设计效应", fmt.Sprintf("%.4f", summary.DesignEffect)},
+		{"有效样本量", fmt.Sprintf("%.2f", summary.EffectiveSize)},
+	}
+	for _, warning := range summary.Warnings {
+		rows = append(rows, []any{"提示", warning})
+	}
+	return excel.Sheet{Name: "加权说明", Headers: []string{"项目", "值"}, Rows: rows}
+}
+
+// shareInterval 选项次数占总次数的 95% 置信区间
+func shareInterval(count, total int) Interval {
+	if total == 0 {
+		return Interval{}
+	}
+	return wilsonInterval(float64(count)/float64(total), float64(total))
+}
+
+// wilsonInterval 占比 p 在样本量 n 下的 95% Wilson 置信区间，以百分比表示
+func wilsonInterval(p, n float64) Interval {
+	if n <= 0 {
+		return Interval{}
+	}
+	z2 := confidenceZ * confidenceZ
+	denom := 1 + z2/n
+	center := (p + z2/(2*n)) / denom
+	half := confidenceZ * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / denom
+	round := func(v float64) float64 {
+		return math.Round(math.Min(math.Max(v, 0), 1)*10000) / 100
+	}
+	return Interval{Low: round(center - half), High: round(center + half)}
+}
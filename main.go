@@ -2,15 +2,19 @@ package main
 
 import (
 	"QA-System/internal/pkg/idgen"
+	"os"
 	"time"
 
+	"QA-System/internal/command"
 	global "QA-System/internal/global/config"
 	"QA-System/internal/middleware"
+	"QA-System/internal/pkg/code"
 	"QA-System/internal/pkg/database/mongodb"
 	"QA-System/internal/pkg/database/mysql"
 	"QA-System/internal/pkg/log"
 	"QA-System/internal/pkg/session"
 	"QA-System/internal/pkg/utils"
+	"QA-System/internal/pkg/wordfreq"
 	"QA-System/internal/router"
 	"QA-System/internal/service"
 	"QA-System/pkg/extension"
@@ -44,6 +48,19 @@ func main() {
 	if err := utils.Init(); err != nil {
 		zap.L().Fatal(err.Error())
 	}
+	// 执行命令行子命令
+	if command.Run(os.Args[1:]) {
+		return
+	}
+	// 加载词频统计的词典，停用词表无法读取时启动失败
+	if err := wordfreq.Load(global.Config.GetString("analysis.stopWords")); err != nil {
+		zap.L().Fatal("Failed to load word frequency dictionary", zap.Error(err))
+	}
+	// 定时写入暂存的匿名选票，并为哈希链生成签名检查点
+	service.StartBallotWorker()
+	service.StartLedgerWorker()
+	// 订阅答卷变化，推送实时投票结果和新答卷
+	service.StartLiveUpdates()
 
 	// 初始化插件管理器并加载插件
 	pm := extension.GetDefaultManager()
@@ -76,6 +93,7 @@ func main() {
 
 	// 初始化gin
 	r := gin.Default()
+	code.InitValidator()
 	r.Use(middleware.ErrHandler())
 	r.NoMethod(middleware.HandleNotFound)
 	r.NoRoute(middleware.HandleNotFound)